@@ -0,0 +1,50 @@
+package xormigrate
+
+import (
+	"fmt"
+)
+
+// ApprovalVerifier validates the approval token of a migration, e.g. against
+// a change management system. It returns an error if the token is invalid.
+type ApprovalVerifier func(m *Migration, token string) error
+
+// ApprovalError is returned when a migration requiring approval can't run
+type ApprovalError struct {
+	ID  string
+	Err error
+}
+
+func (e *ApprovalError) Error() string {
+	return fmt.Sprintf(`xormigrate: Migration "%s" is not approved: %v`, e.ID, e.Err)
+}
+
+func (e *ApprovalError) Unwrap() error {
+	return e.Err
+}
+
+// Approve provides the approval token of the migration matching
+// migrationID. The token is validated by Options.ApprovalVerifier before the
+// migration runs, and stored in the "approval" column of its record.
+func (x *Xormigrate) Approve(migrationID, token string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.approvals == nil {
+		x.approvals = make(map[string]string)
+	}
+	x.approvals[migrationID] = token
+}
+
+func (x *Xormigrate) checkApproval(m *Migration) error {
+	if !m.RequiresApproval {
+		return nil
+	}
+	token, ok := x.approvals[m.ID]
+	if !ok || x.options.ApprovalVerifier == nil {
+		return &ApprovalError{ID: m.ID, Err: ErrApprovalRequired}
+	}
+	if err := x.options.ApprovalVerifier(m, token); err != nil {
+		return &ApprovalError{ID: m.ID, Err: err}
+	}
+	return nil
+}
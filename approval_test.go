@@ -0,0 +1,81 @@
+package xormigrate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestApproval(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		errInvalidToken := errors.New("invalid token")
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			ApprovalVerifier: func(m *Migration, token string) error {
+				if token != "CHG-42" {
+					return errInvalidToken
+				}
+				return nil
+			},
+		}, []*Migration{
+			migrations[0],
+			{
+				ID:               "201608301430",
+				RequiresApproval: true,
+				Migrate: func(tx *xorm.Session) error {
+					return tx.Sync2(&Pet{})
+				},
+			},
+		})
+
+		err := m.Migrate()
+		assert.True(t, errors.Is(err, ErrApprovalRequired))
+		assert.Equal(t, int64(1), tableCount(t, db))
+
+		m.Approve("201608301430", "CHG-1")
+		err = m.Migrate()
+		var approvalError *ApprovalError
+		if assert.True(t, errors.As(err, &approvalError)) {
+			assert.Equal(t, "201608301430", approvalError.ID)
+			assert.Equal(t, errInvalidToken, approvalError.Err)
+		}
+
+		m.Approve("201608301430", "CHG-42")
+		assert.NoError(t, m.Migrate())
+		var record migrationRecord
+		has, err := db.Table("migration").Cols("id", "approval").ID("201608301430").Get(&record)
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.Equal(t, "CHG-42", record.Approval)
+	})
+}
+
+func TestApprovalLongToken(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		// E.g. a signed JWT.
+		token := strings.Repeat("a", 1024)
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			ApprovalVerifier: func(m *Migration, token string) error {
+				return nil
+			},
+		}, []*Migration{{
+			ID:               "201608301400",
+			RequiresApproval: true,
+			Migrate: func(tx *xorm.Session) error {
+				return tx.Sync2(&Person{})
+			},
+		}})
+		m.Approve("201608301400", token)
+		assert.NoError(t, m.Migrate())
+
+		var record migrationRecord
+		has, err := db.Table("migration").Cols("id", "approval").ID("201608301400").Get(&record)
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.Equal(t, token, record.Approval)
+	})
+}
@@ -0,0 +1,113 @@
+package xormigrate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// AtlasSource loads the migrations of an Atlas migration directory, so that
+// Atlas can be used to plan schema changes while xormigrate executes them.
+//
+// Each "<version>_<name>.sql" file becomes a migration identified by its
+// version, without rollback. The integrity of the directory is verified
+// against its atlas.sum file, as the atlas CLI does.
+type AtlasSource struct {
+	// FS holds the migration directory.
+	FS fs.FS
+	// Dir is the path of the migration directory in FS.
+	Dir string
+}
+
+// Load reads and verifies the migration directory.
+func (s *AtlasSource) Load(ctx context.Context) ([]*Migration, error) {
+	entries, err := fs.ReadDir(s.FS, s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []sqlFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		content, err := fs.ReadFile(s.FS, path.Join(s.Dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sqlFile{name: entry.Name(), content: content})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].name < files[j].name
+	})
+
+	sum, err := fs.ReadFile(s.FS, path.Join(s.Dir, "atlas.sum"))
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyAtlasSum(sum, files); err != nil {
+		return nil, err
+	}
+
+	migrations := make([]*Migration, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(file.name, ".sql")
+		id, description := name, ""
+		if i := strings.IndexByte(name, '_'); i >= 0 {
+			id, description = name[:i], strings.ReplaceAll(name[i+1:], "_", " ")
+		}
+		migrations = append(migrations, &Migration{
+			ID:          id,
+			Description: description,
+			UpSQL:       string(file.content),
+		})
+	}
+	return migrations, nil
+}
+
+func (s *AtlasSource) String() string {
+	return s.Dir
+}
+
+// verifyAtlasSum checks the files against an atlas.sum file. Its first line
+// holds the hash of the whole directory, the next ones the hash of each
+// file. Hashes are cumulative: the hash of a file is the SHA-256 of the names
+// and contents of all files up to and including it. The hash of the
+// directory is the SHA-256 of the names and hashes of all files.
+func verifyAtlasSum(sum []byte, files []sqlFile) error {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(sum))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(lines) != len(files)+1 {
+		return fmt.Errorf("xormigrate: atlas.sum lists %d files, the directory has %d", len(lines)-1, len(files))
+	}
+
+	h, dir := sha256.New(), sha256.New()
+	for i, file := range files {
+		h.Write([]byte(file.name))
+		h.Write(file.content)
+		hash := base64.StdEncoding.EncodeToString(h.Sum(nil))
+		if lines[i+1] != file.name+" h1:"+hash {
+			return &ChecksumMismatchError{File: file.name}
+		}
+		dir.Write([]byte(file.name))
+		dir.Write([]byte(hash))
+	}
+	if lines[0] != "h1:"+base64.StdEncoding.EncodeToString(dir.Sum(nil)) {
+		return &ChecksumMismatchError{File: "atlas.sum"}
+	}
+	return nil
+}
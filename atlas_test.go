@@ -0,0 +1,80 @@
+package xormigrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+// newAtlasDir returns an Atlas migration directory holding files, with the
+// atlas.sum the atlas CLI would generate.
+func newAtlasDir(files [][2]string) fstest.MapFS {
+	dir := fstest.MapFS{}
+	h, dirHash := sha256.New(), sha256.New()
+	var lines string
+	for _, file := range files {
+		dir["atlas/"+file[0]] = &fstest.MapFile{Data: []byte(file[1])}
+		h.Write([]byte(file[0]))
+		h.Write([]byte(file[1]))
+		hash := base64.StdEncoding.EncodeToString(h.Sum(nil))
+		lines += fmt.Sprintf("%s h1:%s\n", file[0], hash)
+		dirHash.Write([]byte(file[0]))
+		dirHash.Write([]byte(hash))
+	}
+	sum := fmt.Sprintf("h1:%s\n%s", base64.StdEncoding.EncodeToString(dirHash.Sum(nil)), lines)
+	dir["atlas/atlas.sum"] = &fstest.MapFile{Data: []byte(sum)}
+	return dir
+}
+
+var atlasFiles = [][2]string{
+	{"20160830140000_create_person.sql", "CREATE TABLE person (id INTEGER, name VARCHAR(255));"},
+	{"20160830143000_create_pet.sql", "CREATE TABLE pet (name VARCHAR(255), person_id INTEGER);"},
+}
+
+func TestAtlasSource(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m, err := NewFromSources(context.Background(), db.NewSession(), &Options{
+			TableName: "migration",
+		}, &AtlasSource{FS: newAtlasDir(atlasFiles), Dir: "atlas"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, m.Migrate())
+		has, _ := db.IsTableExist(&Pet{})
+		assert.True(t, has)
+		assert.Equal(t, int64(2), tableCount(t, db))
+	})
+}
+
+func TestAtlasSourceAtlasSum(t *testing.T) {
+	// The atlas.sum written by "atlas migrate hash" for these files.
+	dir := fstest.MapFS{
+		"atlas/20160830140000_create_person.sql": {Data: []byte("CREATE TABLE person (id INTEGER, name VARCHAR(255));\n")},
+		"atlas/20160830143000_create_pet.sql":    {Data: []byte("CREATE TABLE pet (name VARCHAR(255), person_id INTEGER);\n")},
+		"atlas/atlas.sum": {Data: []byte(`h1:TjKe84Ql0M+2ZNs6bYDKhU7a/2DBRmsUNrIOLuUcvfE=
+20160830140000_create_person.sql h1:etMQTy9ZS18B0fhmkum+F1Qji8TG/m0cxJpyGtJwcsI=
+20160830143000_create_pet.sql h1:7Ad90noWJlDyrrsc0ti86LxEAml2yR2yO6aBsUKBMps=
+`)},
+	}
+	loaded, err := (&AtlasSource{FS: dir, Dir: "atlas"}).Load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"20160830140000", "20160830143000"}, planIDs(loaded))
+}
+
+func TestAtlasSourceTampered(t *testing.T) {
+	dir := newAtlasDir(atlasFiles)
+	dir["atlas/20160830143000_create_pet.sql"].Data = []byte("DROP TABLE person;")
+
+	_, err := (&AtlasSource{FS: dir, Dir: "atlas"}).Load(context.Background())
+	var mismatchError *ChecksumMismatchError
+	if assert.True(t, errors.As(err, &mismatchError)) {
+		assert.Equal(t, "20160830143000_create_pet.sql", mismatchError.File)
+	}
+}
@@ -0,0 +1,239 @@
+package xormigrate
+
+import (
+	"context"
+	"fmt"
+
+	"xorm.io/xorm"
+)
+
+// backend persists the migration history. sessionBackend, the default one,
+// stores it in the migration table, FakeBackend keeps it in memory.
+type backend interface {
+	// tableExists reports whether the migration table exists.
+	tableExists() (bool, error)
+	// probeTable checks that the migration table can be queried, without
+	// relying on the database catalog.
+	probeTable() error
+	createTable() error
+	// upgradeTable adds the columns required by the enabled options.
+	upgradeTable() error
+	// recordIDs returns the IDs of all the records, in a single query.
+	recordIDs() ([]string, error)
+	// countRecords counts the records whose ID is not in exclude.
+	countRecords(exclude []string) (int64, error)
+	insertRecord(record *migrationRecord) error
+	// insertRecords inserts the records in batches.
+	insertRecords(records []migrationRecord) error
+	deleteRecord(id string) error
+	setChecksum(id, checksum string) error
+	// listRecords returns the records sorted by ID.
+	listRecords() ([]migrationRecord, error)
+	// replaceRecords replaces all the records.
+	replaceRecords(records []migrationRecord) error
+	// createStepTable creates the step table if it does not exist.
+	createStepTable() error
+	// completedSteps returns the names of the completed steps of a
+	// migration, or none if the step table does not exist.
+	completedSteps(id string) ([]string, error)
+	insertStep(id, step string) error
+	deleteSteps(id string) error
+	// dialect returns the name of the database type, e.g. "postgres".
+	dialect() string
+	// dataSourceName returns the DSN of the database, empty if unknown.
+	dataSourceName() string
+	// engine returns the engine of the database, nil if there is none.
+	engine() *xorm.Engine
+	// exec executes a statement of a migration, or a session setting, in
+	// the current session.
+	exec(statement string) error
+	// maintain refreshes the statistics of the tables, and reclaims their
+	// space if vacuum is set, outside of any transaction, calling failed
+	// for each statement that failed.
+	maintain(tables []string, vacuum bool, failed func(statement string, err error))
+	// canaryRecords returns the records of the canary table, none if it
+	// does not exist, see CanaryRollout.
+	canaryRecords() ([]canaryRecord, error)
+	// writeCanaryRecords inserts the records in the canary table, or
+	// replaces the ones with the same IDs, creating the table if needed.
+	writeCanaryRecords(records []canaryRecord) error
+	// lock acquires the migration lock called name, waiting until ctx is
+	// done, and returns the function releasing it.
+	lock(ctx context.Context, name string) (func() error, error)
+	begin()
+	commit() error
+	rollback()
+}
+
+// sessionBackend stores the migration history in the migration table, using
+// the current session of x.
+type sessionBackend struct {
+	x *Xormigrate
+}
+
+func (b *sessionBackend) dataSourceName() string {
+	return b.x.session.Engine().DataSourceName()
+}
+
+func (b *sessionBackend) engine() *xorm.Engine {
+	return b.x.session.Engine()
+}
+
+func (b *sessionBackend) exec(statement string) error {
+	_, err := b.x.session.Exec(statement)
+	return err
+}
+
+func (b *sessionBackend) tableExists() (bool, error) {
+	// Not using the session: once it has committed a transaction, xorm
+	// keeps running catalog queries against that transaction.
+	return b.x.session.Engine().IsTableExist(b.x.options.TableName)
+}
+
+func (b *sessionBackend) probeTable() error {
+	if _, err := b.x.session.Table(b.x.options.TableName).Exist(); err != nil {
+		return &MissingTableError{TableName: b.x.options.TableName, Err: err}
+	}
+	return nil
+}
+
+func (b *sessionBackend) recordIDs() ([]string, error) {
+	var ids []string
+	err := b.x.session.Table(b.x.options.TableName).Cols("id").Find(&ids)
+	return ids, err
+}
+
+func (b *sessionBackend) countRecords(exclude []string) (int64, error) {
+	session := b.x.session.Table(b.x.options.TableName)
+	if len(exclude) > 0 {
+		session = session.NotIn("id", exclude)
+	}
+	return session.Count(&Migration{})
+}
+
+func (b *sessionBackend) insertRecord(record *migrationRecord) error {
+	_, err := b.x.session.Table(b.x.options.TableName).Cols(b.x.recordColumns()...).Insert(record)
+	return err
+}
+
+// maxInsertParams bounds the parameters of the statements inserting records
+// in batches, below the limits of SQLite (999) and SQL Server (2100).
+const maxInsertParams = 900
+
+func (b *sessionBackend) insertRecords(records []migrationRecord) error {
+	cols := b.x.recordColumns()
+	batch := maxInsertParams / len(cols)
+	for len(records) > 0 {
+		n := batch
+		if n > len(records) {
+			n = len(records)
+		}
+		if _, err := b.x.session.Table(b.x.options.TableName).Cols(cols...).Insert(records[:n]); err != nil {
+			return err
+		}
+		records = records[n:]
+	}
+	return nil
+}
+
+func (b *sessionBackend) deleteRecord(id string) error {
+	_, err := b.x.session.Table(b.x.options.TableName).ID(id).Delete(&Migration{})
+	return err
+}
+
+func (b *sessionBackend) setChecksum(id, checksum string) error {
+	_, err := b.x.session.Table(b.x.options.TableName).ID(id).Cols("checksum").Update(&migrationRecord{Checksum: checksum})
+	return err
+}
+
+func (b *sessionBackend) listRecords() ([]migrationRecord, error) {
+	cols, err := b.existingRecordColumns()
+	if err != nil {
+		return nil, err
+	}
+	records := []migrationRecord{}
+	err = b.x.session.Table(b.x.options.TableName).Cols(cols...).Asc("id").Find(&records)
+	return records, err
+}
+
+func (b *sessionBackend) replaceRecords(records []migrationRecord) error {
+	cols, err := b.existingRecordColumns()
+	if err != nil {
+		return err
+	}
+	session := b.x.session
+	if _, err := session.Exec(fmt.Sprintf("DELETE FROM %s", session.Engine().Quote(b.x.options.TableName))); err != nil {
+		return err
+	}
+	for i := range records {
+		if _, err := session.Table(b.x.options.TableName).Cols(cols...).Insert(&records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// existingRecordColumns returns the columns of migrationRecord present in the
+// migration table, which may predate some of them.
+func (b *sessionBackend) existingRecordColumns() ([]string, error) {
+	engine := b.x.session.Engine()
+	table, err := engine.TableInfo(&migrationRecord{})
+	if err != nil {
+		return nil, err
+	}
+	cols := []string{"id"}
+	for _, name := range table.ColumnsSeq()[1:] {
+		exist, err := engine.Dialect().IsColumnExist(engine.DB(), context.Background(), b.x.options.TableName, name)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			cols = append(cols, name)
+		}
+	}
+	return cols, nil
+}
+
+func (b *sessionBackend) stepTableName() string {
+	return b.x.options.TableName + "_steps"
+}
+
+func (b *sessionBackend) createStepTable() error {
+	return b.x.session.Table(b.stepTableName()).Sync2(&stepRecord{})
+}
+
+func (b *sessionBackend) completedSteps(id string) ([]string, error) {
+	exists, err := b.x.session.Engine().IsTableExist(b.stepTableName())
+	if err != nil || !exists {
+		return nil, err
+	}
+	var steps []string
+	err = b.x.session.Table(b.stepTableName()).Where("migration_id = ?", id).Cols("step").Find(&steps)
+	return steps, err
+}
+
+func (b *sessionBackend) insertStep(id, step string) error {
+	_, err := b.x.session.Table(b.stepTableName()).Insert(&stepRecord{MigrationID: id, Step: step})
+	return err
+}
+
+func (b *sessionBackend) deleteSteps(id string) error {
+	_, err := b.x.session.Table(b.stepTableName()).Where("migration_id = ?", id).Delete(&stepRecord{})
+	return err
+}
+
+func (b *sessionBackend) dialect() string {
+	return string(b.x.session.Engine().Dialect().URI().DBType)
+}
+
+func (b *sessionBackend) begin() {
+	b.x.session.Begin()
+}
+
+func (b *sessionBackend) commit() error {
+	return b.x.session.Commit()
+}
+
+func (b *sessionBackend) rollback() {
+	b.x.session.Rollback()
+}
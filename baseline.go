@@ -0,0 +1,78 @@
+package xormigrate
+
+import (
+	"context"
+	"time"
+)
+
+// baselineReason is the skip reason of the migrations recorded by Baseline.
+const baselineReason = "baseline"
+
+// Baseline records the migrations up to and including the one matching
+// migrationID as applied, without running them, e.g. to adopt xormigrate on a
+// database provisioned beforehand. Repeatable migrations are not recorded, so
+// that the next run applies them. With Options.RecordStatus, the migrations
+// are recorded as skipped, for the "baseline" reason, and a MigrationSkipped
+// event is emitted for each one.
+func (x *Xormigrate) Baseline(migrationID string) error {
+	_, err := x.withResult(nil, func() error {
+		return x.baseline(migrationID)
+	})
+	return err
+}
+
+// BaselineContext is like Baseline, with a context, see MigrateContext.
+func (x *Xormigrate) BaselineContext(ctx context.Context, migrationID string) error {
+	_, err := x.withResult(ctx, func() error {
+		return x.baseline(migrationID)
+	})
+	return err
+}
+
+func (x *Xormigrate) baseline(migrationID string) (err error) {
+	if err := x.checkWritable(); err != nil {
+		return err
+	}
+	if err := x.checkIDExist(migrationID); err != nil {
+		return err
+	}
+	if err := x.checkReservedID(); err != nil {
+		return err
+	}
+	if err := x.checkDuplicatedID(); err != nil {
+		return err
+	}
+	x.emitRunStarted(false)
+	defer x.emitRunFinished(false, time.Now(), &err)
+
+	defer x.end()
+	if err := x.begin(); err != nil {
+		return err
+	}
+	if err := x.createMigrationTableIfNotExists(); err != nil {
+		return err
+	}
+
+	var baselined []*Migration
+	for _, migration := range x.migrations {
+		if !migration.Repeatable {
+			migrationRan, err := x.migrationRan(migration)
+			if err != nil {
+				return err
+			}
+			if !migrationRan {
+				baselined = append(baselined, migration)
+			}
+		}
+		if migration.ID == migrationID {
+			break
+		}
+	}
+	if err := x.insertMigrations(baselined, baselineReason); err != nil {
+		return err
+	}
+	for _, migration := range baselined {
+		x.emit(&MigrationSkipped{ID: migration.ID, Reason: baselineReason})
+	}
+	return x.finish()
+}
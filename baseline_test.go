@@ -0,0 +1,53 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xorm.io/xorm"
+)
+
+func TestBaseline(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		// Provisioned beforehand.
+		assert.NoError(t, db.Sync2(&Person{}, &Pet{}))
+
+		m := New(db.NewSession(), &Options{TableName: "migration", RecordStatus: true}, extendedMigrations)
+		assert.Equal(t, ErrMigrationIDDoesNotExist, m.Baseline("201609011200"))
+		assert.NoError(t, m.Baseline("201608301430"))
+		assert.Equal(t, int64(2), tableCount(t, db))
+
+		status, err := m.Status()
+		require.NoError(t, err)
+		if assert.Len(t, status.Applied, 2) {
+			assert.True(t, status.Applied[0].Skipped)
+			assert.Equal(t, "baseline", status.Applied[0].SkipReason)
+		}
+		// Baselining again records nothing more.
+		assert.NoError(t, m.Baseline("201608301430"))
+		assert.Equal(t, int64(2), tableCount(t, db))
+
+		assert.NoError(t, m.Migrate())
+		has, err := db.IsTableExist(&Book{})
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.Equal(t, int64(3), tableCount(t, db))
+	})
+}
+
+func TestBaselineEvents(t *testing.T) {
+	migrations := targetMigrations()
+	migrations[1].Repeatable = true
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{}, migrations)
+	var skipped []string
+	m.AddListener(ListenerFunc(func(event Event) {
+		if e, ok := event.(*MigrationSkipped); ok {
+			skipped = append(skipped, e.ID+" "+e.Reason)
+		}
+	}))
+	assert.NoError(t, m.Baseline("201609011200"))
+	assert.Equal(t, []string{"201608301400 baseline", "201609011200 baseline"}, skipped)
+	assert.Equal(t, []string{"201608301400", "201609011200"}, backend.Applied())
+}
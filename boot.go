@@ -0,0 +1,153 @@
+package xormigrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"xorm.io/xorm"
+)
+
+// ErrNotReady is returned by Ready until RunOnce succeeded.
+var ErrNotReady = errors.New("xormigrate: Migrations did not run yet")
+
+// BootError is returned by RunOnce and AutoMigrate when migrating at startup
+// failed. ID is the migration that failed, if any.
+type BootError struct {
+	ID  string
+	Err error
+}
+
+func (e *BootError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("xormigrate: Migrating at startup failed: %v", e.Err)
+	}
+	return fmt.Sprintf(`xormigrate: Migrating at startup failed on migration "%s": %v`, e.ID, e.Err)
+}
+
+func (e *BootError) Unwrap() error {
+	return e.Err
+}
+
+// bootCall is a RunOnce in progress, shared by the concurrent calls for the
+// same database and migration table.
+type bootCall struct {
+	done chan struct{}
+	err  error
+}
+
+var bootCalls = struct {
+	sync.Mutex
+	calls map[string]*bootCall
+}{calls: make(map[string]*bootCall)}
+
+// RunOnce applies the pending migrations, for applications migrating at
+// startup. It is cheap when there is nothing to do: the history is only read,
+// without locking. Otherwise, the concurrent calls of the process for the
+// same database and migration table share a single run, which holds the
+// migration lock, see Options.UseLock, to exclude the other processes. Its
+// error is a *BootError, also returned by Ready.
+func (x *Xormigrate) RunOnce(ctx context.Context) error {
+	key := x.bootKey()
+	bootCalls.Lock()
+	call, ok := bootCalls.calls[key]
+	if !ok {
+		call = &bootCall{done: make(chan struct{})}
+		bootCalls.calls[key] = call
+	}
+	bootCalls.Unlock()
+
+	if ok {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	} else {
+		call.err = x.runOnce(ctx)
+		bootCalls.Lock()
+		delete(bootCalls.calls, key)
+		bootCalls.Unlock()
+		close(call.done)
+	}
+
+	x.readyMu.Lock()
+	x.ready, x.readyErr = call.err == nil, call.err
+	x.readyMu.Unlock()
+	return call.err
+}
+
+func (x *Xormigrate) runOnce(ctx context.Context) error {
+	if pending, err := x.Pending(); err == nil && len(pending) == 0 {
+		return nil
+	}
+	result, err := x.withResult(ctx, func() error {
+		x.forceLock = true
+		defer func() {
+			x.forceLock = false
+		}()
+		return x.migrateAll()
+	})
+	if err == nil {
+		return nil
+	}
+	bootErr := &BootError{Err: err}
+	if result != nil && result.Failed != nil {
+		bootErr.ID = result.Failed.ID
+	}
+	return bootErr
+}
+
+// bootKey identifies the database and migration table of x in the process.
+func (x *Xormigrate) bootKey() string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	engine := x.engine
+	if x.session != nil {
+		engine = x.session.Engine()
+	}
+	if engine == nil {
+		return fmt.Sprintf("%p %s", x.backend, x.lockName())
+	}
+	return engine.DriverName() + " " + engine.DataSourceName() + " " + x.lockName()
+}
+
+// Ready returns nil once RunOnce succeeded, or the error of its last call,
+// ErrNotReady before. It never waits for a run, so it suits readiness
+// probes.
+func (x *Xormigrate) Ready() error {
+	x.readyMu.Lock()
+	defer x.readyMu.Unlock()
+
+	if x.ready {
+		return nil
+	}
+	if x.readyErr != nil {
+		return x.readyErr
+	}
+	return ErrNotReady
+}
+
+// AutoMigrate applies the migrations of source to engine at application
+// startup, with RunOnce. options may be nil.
+//
+//	if err := xormigrate.AutoMigrate(engine, xormigrate.SliceSource(migrations), nil); err != nil {
+//		log.Fatal(err)
+//	}
+func AutoMigrate(engine *xorm.Engine, source Source, options *Options) error {
+	return AutoMigrateContext(context.Background(), engine, source, options)
+}
+
+// AutoMigrateContext is like AutoMigrate, with a context.
+func AutoMigrateContext(ctx context.Context, engine *xorm.Engine, source Source, options *Options) error {
+	migrations, err := source.Load(ctx)
+	if err != nil {
+		return &BootError{Err: fmt.Errorf("loading migrations: %w", err)}
+	}
+	if options == nil {
+		options = &Options{}
+	}
+	return NewFromEngine(engine, options, migrations).RunOnce(ctx)
+}
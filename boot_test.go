@@ -0,0 +1,76 @@
+package xormigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestRunOnce(t *testing.T) {
+	started, proceed := make(chan struct{}), make(chan struct{})
+	runs := 0
+	bootMigrations := []*Migration{{
+		ID: "201608301400",
+		Migrate: func(tx *xorm.Session) error {
+			runs++
+			close(started)
+			<-proceed
+			return nil
+		},
+	}}
+	backend := &FakeBackend{}
+	first := NewFake(backend, &Options{}, bootMigrations)
+	second := NewFake(backend, &Options{}, bootMigrations)
+	assert.Equal(t, ErrNotReady, first.Ready())
+
+	done := make(chan error)
+	go func() {
+		done <- first.RunOnce(context.Background())
+	}()
+	<-started
+	go func() {
+		done <- second.RunOnce(context.Background())
+	}()
+	assert.Equal(t, ErrNotReady, first.Ready())
+	close(proceed)
+	assert.NoError(t, <-done)
+	assert.NoError(t, <-done)
+	assert.Equal(t, 1, runs)
+	assert.NoError(t, first.Ready())
+	assert.NoError(t, second.Ready())
+	assert.Equal(t, []string{"lock", "create table", "insert 201608301400", "unlock"}, backend.Ops())
+
+	// Nothing to do: the history is only read.
+	assert.NoError(t, second.RunOnce(context.Background()))
+	assert.Len(t, backend.Ops(), 4)
+}
+
+func TestRunOnceError(t *testing.T) {
+	errBoom := errors.New("boom")
+	m := NewFake(&FakeBackend{}, &Options{}, []*Migration{{
+		ID:      "201608301400",
+		Migrate: func(tx *xorm.Session) error { return errBoom },
+	}})
+	err := m.RunOnce(context.Background())
+	var bootErr *BootError
+	if assert.True(t, errors.As(err, &bootErr)) {
+		assert.Equal(t, "201608301400", bootErr.ID)
+		assert.True(t, errors.Is(err, errBoom))
+	}
+	assert.Equal(t, err, m.Ready())
+}
+
+func TestAutoMigrate(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		defer db.DropTables("migration_lock")
+
+		options := &Options{TableName: "migration"}
+		assert.NoError(t, AutoMigrate(db, SliceSource(migrations), options))
+		assert.Equal(t, int64(2), tableCount(t, db))
+		assert.NoError(t, AutoMigrate(db, SliceSource(migrations), options))
+		assert.Equal(t, int64(2), tableCount(t, db))
+	})
+}
@@ -0,0 +1,37 @@
+//go:build go1.18
+// +build go1.18
+
+package xormigrate
+
+import (
+	"runtime/debug"
+)
+
+// BuildVersion describes the running binary: the version of its main module,
+// followed by the VCS revision it was built from when known.
+func BuildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	version := info.Main.Version
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision == "" {
+		return version
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified == "true" {
+		revision += "-dirty"
+	}
+	return version + " (" + revision + ")"
+}
@@ -0,0 +1,18 @@
+//go:build !go1.18
+// +build !go1.18
+
+package xormigrate
+
+import (
+	"runtime/debug"
+)
+
+// BuildVersion describes the running binary: the version of its main module.
+// VCS information is only available when built with Go 1.18 or later.
+func BuildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	return info.Main.Version
+}
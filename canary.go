@@ -0,0 +1,240 @@
+package xormigrate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CanaryRollout applies risky migrations, the ones with its tag, to a canary
+// database first, e.g. an internal tenant. They become eligible on the other
+// databases, e.g. the remaining tenants or shards, once promoted with
+// PromoteCanary. The outcomes on the canary and the promotions are stored in
+// a "<TableName>_canary" table of the canary database.
+type CanaryRollout struct {
+	// Tag selects the risky migrations. Defaults to "canary".
+	Tag string
+	// Canary runs the migrations on the canary database.
+	Canary *Xormigrate
+	// Targets run the migrations on the other databases.
+	Targets []*Xormigrate
+	// BakeTime is how long a migration must have been applied on the
+	// canary before it can be promoted.
+	BakeTime time.Duration
+}
+
+// CanaryReport is the outcome of CanaryRollout.Run.
+type CanaryReport struct {
+	// Held are the risky migrations not promoted yet: the targets were
+	// migrated up to the first one.
+	Held []string
+	// Errors are the errors of the targets, by index, nil for the ones
+	// migrated successfully.
+	Errors []error
+}
+
+// Failed reports whether a target failed.
+func (r *CanaryReport) Failed() bool {
+	for _, err := range r.Errors {
+		if err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNotCanaried is returned by PromoteCanary when the migration was not
+// applied on the canary
+var ErrNotCanaried = errors.New("xormigrate: Migration was not applied on the canary")
+
+// BakeTimeError is returned by PromoteCanary when the migration was applied
+// on the canary for less than CanaryRollout.BakeTime.
+type BakeTimeError struct {
+	ID        string
+	Remaining time.Duration
+}
+
+func (e *BakeTimeError) Error() string {
+	return fmt.Sprintf(`xormigrate: Migration "%s" must stay on the canary %s longer before its promotion`, e.ID, e.Remaining)
+}
+
+// canaryRecord is a row of the canary table.
+type canaryRecord struct {
+	ID         string     `xorm:"VARCHAR(50) notnull pk 'id'"`
+	Status     string     `xorm:"VARCHAR(20) 'status'"`
+	Error      string     `xorm:"TEXT 'error'"`
+	AppliedAt  time.Time  `xorm:"'applied_at'"`
+	PromotedAt *time.Time `xorm:"'promoted_at'"`
+}
+
+// The statuses of the canary records.
+const (
+	canaryApplied = "applied"
+	canaryFailed  = "failed"
+)
+
+func (r *CanaryRollout) tag() string {
+	if r.Tag == "" {
+		return "canary"
+	}
+	return r.Tag
+}
+
+// Run migrates the canary, recording the outcome of the risky migrations,
+// then the targets, up to the first risky migration not promoted. The
+// targets are not migrated if the canary fails.
+func (r *CanaryRollout) Run() (*CanaryReport, error) {
+	result, err := r.Canary.MigrateResult()
+	if result != nil {
+		if recordErr := r.recordOutcome(result); recordErr != nil && err == nil {
+			err = recordErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := r.Canary.canaryRecords()
+	if err != nil {
+		return nil, err
+	}
+	report := &CanaryReport{Errors: make([]error, len(r.Targets))}
+	for _, migration := range r.Canary.migrations {
+		if contains(migration.Tags, r.tag()) && records[migration.ID].PromotedAt == nil {
+			report.Held = append(report.Held, migration.ID)
+		}
+	}
+	for i, target := range r.Targets {
+		_, report.Errors[i] = target.withResult(nil, func() error {
+			return target.migrate("", func(plan []*Migration) ([]*Migration, error) {
+				for j, migration := range plan {
+					if contains(report.Held, migration.ID) {
+						return plan[:j], nil
+					}
+				}
+				return plan, nil
+			})
+		})
+	}
+	return report, nil
+}
+
+// recordOutcome records the risky migrations applied, or failed, by the run
+// of the canary.
+func (r *CanaryRollout) recordOutcome(result *RunResult) error {
+	tagged := make(map[string]bool)
+	for _, migration := range r.Canary.migrations {
+		if contains(migration.Tags, r.tag()) {
+			tagged[migration.ID] = true
+		}
+	}
+	now := r.Canary.options.Clock.Now()
+	var records []canaryRecord
+	for _, applied := range result.Applied {
+		if tagged[applied.ID] {
+			records = append(records, canaryRecord{ID: applied.ID, Status: canaryApplied, AppliedAt: now})
+		}
+	}
+	if failed := result.Failed; failed != nil && tagged[failed.ID] {
+		records = append(records, canaryRecord{ID: failed.ID, Status: canaryFailed, Error: failed.Err.Error(), AppliedAt: now})
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return r.Canary.writeCanaryRecords(records)
+}
+
+// PromoteCanary makes the risky migration matching migrationID eligible on
+// the targets. It must have been applied on the canary for at least
+// BakeTime, otherwise ErrNotCanaried or a *BakeTimeError is returned.
+func (r *CanaryRollout) PromoteCanary(migrationID string) error {
+	records, err := r.Canary.canaryRecords()
+	if err != nil {
+		return err
+	}
+	record, ok := records[migrationID]
+	if !ok || record.Status != canaryApplied {
+		return ErrNotCanaried
+	}
+	now := r.Canary.options.Clock.Now()
+	if baked := now.Sub(record.AppliedAt); baked < r.BakeTime {
+		return &BakeTimeError{ID: migrationID, Remaining: r.BakeTime - baked}
+	}
+	record.PromotedAt = &now
+	return r.Canary.writeCanaryRecords([]canaryRecord{record})
+}
+
+func (x *Xormigrate) canaryTableName() string {
+	return x.options.TableName + "_canary"
+}
+
+// canaryRecords returns the records of the canary table, by ID.
+func (x *Xormigrate) canaryRecords() (map[string]canaryRecord, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	rows, err := x.backend.canaryRecords()
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]canaryRecord, len(rows))
+	for _, row := range rows {
+		records[row.ID] = row
+	}
+	return records, nil
+}
+
+// writeCanaryRecords replaces the records of the canary table with the same
+// IDs, creating the table if needed, in a run holding the migration lock.
+func (x *Xormigrate) writeCanaryRecords(records []canaryRecord) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	defer x.end()
+	if err := x.begin(); err != nil {
+		return err
+	}
+	if err := x.backend.writeCanaryRecords(records); err != nil {
+		return err
+	}
+	return x.finish()
+}
+
+func (b *sessionBackend) canaryRecords() ([]canaryRecord, error) {
+	table := b.x.canaryTableName()
+	exists, err := b.x.session.Engine().IsTableExist(table)
+	if err != nil || !exists {
+		return nil, err
+	}
+	var records []canaryRecord
+	err = b.x.session.Table(table).Find(&records)
+	return records, err
+}
+
+// writeCanaryRecords updates the existing records rather than deleting and
+// inserting them again, so that they are never missing, even without a
+// transaction.
+func (b *sessionBackend) writeCanaryRecords(records []canaryRecord) error {
+	session := b.x.session
+	table := b.x.canaryTableName()
+	if err := session.Table(table).Sync2(&canaryRecord{}); err != nil && !isAlreadyExists(err) {
+		return err
+	}
+	for i := range records {
+		exists, err := session.Table(table).ID(records[i].ID).Exist(&canaryRecord{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			_, err = session.Table(table).ID(records[i].ID).AllCols().Update(&records[i])
+		} else {
+			_, err = session.Table(table).Insert(&records[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
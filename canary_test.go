@@ -0,0 +1,86 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestCanaryRollout(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		defer db.DropTables("canary", "canary_canary", "tenant")
+
+		now := time.Date(2016, 8, 30, 14, 0, 0, 0, time.Local)
+		clock := ClockFunc(func() time.Time { return now })
+		stubs := targetMigrations()
+		stubs[1].Tags = []string{"canary"}
+		rollout := &CanaryRollout{
+			Canary:   New(db.NewSession(), &Options{TableName: "canary", Clock: clock}, stubs),
+			Targets:  []*Xormigrate{New(db.NewSession(), &Options{TableName: "tenant", Clock: clock}, stubs)},
+			BakeTime: time.Hour,
+		}
+
+		report, err := rollout.Run()
+		assert.NoError(t, err)
+		assert.False(t, report.Failed())
+		assert.Equal(t, []string{"201608301430"}, report.Held)
+		ids, err := rollout.Targets[0].AppliedIDs()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"201608301400"}, ids)
+
+		assert.Equal(t, ErrNotCanaried, rollout.PromoteCanary("201608301400"))
+		err = rollout.PromoteCanary("201608301430")
+		var bakeErr *BakeTimeError
+		if assert.True(t, errors.As(err, &bakeErr)) {
+			assert.Equal(t, time.Hour, bakeErr.Remaining)
+		}
+
+		now = now.Add(time.Hour)
+		assert.NoError(t, rollout.PromoteCanary("201608301430"))
+		report, err = rollout.Run()
+		assert.NoError(t, err)
+		assert.Empty(t, report.Held)
+		ids, err = rollout.Targets[0].AppliedIDs()
+		assert.NoError(t, err)
+		assert.Len(t, ids, 4)
+	})
+}
+
+func TestCanaryRolloutFake(t *testing.T) {
+	now := time.Date(2016, 8, 30, 14, 0, 0, 0, time.Local)
+	clock := ClockFunc(func() time.Time { return now })
+	stubs := targetMigrations()
+	stubs[1].Tags = []string{"canary"}
+	canary := &FakeBackend{}
+	rollout := &CanaryRollout{
+		Canary:   NewFake(canary, &Options{Clock: clock, UseTransaction: true}, stubs),
+		Targets:  []*Xormigrate{NewFake(&FakeBackend{}, &Options{Clock: clock}, stubs)},
+		BakeTime: time.Hour,
+	}
+
+	report, err := rollout.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"201608301430"}, report.Held)
+
+	now = now.Add(time.Hour)
+	errFailed := errors.New("failed")
+	canary.Fail = func(op string) error {
+		if op == "commit" {
+			return errFailed
+		}
+		return nil
+	}
+	assert.Equal(t, errFailed, rollout.PromoteCanary("201608301430"))
+	records, err := rollout.Canary.canaryRecords()
+	assert.NoError(t, err)
+	assert.Nil(t, records["201608301430"].PromotedAt)
+
+	canary.Fail = nil
+	assert.NoError(t, rollout.PromoteCanary("201608301430"))
+	report, err = rollout.Run()
+	assert.NoError(t, err)
+	assert.Empty(t, report.Held)
+}
@@ -0,0 +1,68 @@
+package xormigrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModifiedMigration is an applied migration whose checksum changed since it
+// ran.
+type ModifiedMigration struct {
+	ID       string
+	Recorded string
+	Current  string
+}
+
+// ChecksumError is returned by Verify, and by Migrate when
+// Options.VerifyChecksums is set, when applied migrations were modified.
+type ChecksumError struct {
+	Modified []ModifiedMigration
+}
+
+func (e *ChecksumError) Error() string {
+	ids := make([]string, len(e.Modified))
+	for i, m := range e.Modified {
+		ids[i] = m.ID
+	}
+	return fmt.Sprintf("xormigrate: Applied migrations were modified: %s", strings.Join(ids, ", "))
+}
+
+// Verify checks that the applied migrations were not modified since they ran,
+// by comparing their checksums, see Migration.Checksum, with the recorded
+// ones, see Options.RecordChecksum. Migrations without checksum, or applied
+// without recording it, are not verified. A *ChecksumError lists the
+// modified migrations. The migration table is never created.
+func (x *Xormigrate) Verify() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	initialized, err := x.initialized()
+	if err != nil || !initialized {
+		return err
+	}
+	return x.verifyChecksums()
+}
+
+func (x *Xormigrate) verifyChecksums() error {
+	records, err := x.backend.listRecords()
+	if err != nil {
+		return err
+	}
+	recorded := make(map[string]string, len(records))
+	for _, record := range records {
+		recorded[record.ID] = record.Checksum
+	}
+	var modified []ModifiedMigration
+	for _, m := range x.migrations {
+		checksum := m.checksum()
+		if m.Repeatable || recorded[m.ID] == "" || checksum == "" || strings.EqualFold(recorded[m.ID], checksum) {
+			continue
+		}
+		modified = append(modified, ModifiedMigration{ID: m.ID, Recorded: recorded[m.ID], Current: checksum})
+	}
+	if len(modified) > 0 {
+		return &ChecksumError{Modified: modified}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestVerify(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		sqlMigrations := func(personSQL string) []*Migration {
+			return []*Migration{
+				{ID: "201608301400", UpSQL: personSQL},
+				{ID: "201608301430", UpSQL: "CREATE TABLE pet (name VARCHAR(255), person_id INTEGER);"},
+			}
+		}
+		options := &Options{TableName: "migration", RecordChecksum: true}
+		m := New(db.NewSession(), options, sqlMigrations("CREATE TABLE person (id INTEGER);")[:1])
+		assert.NoError(t, m.Verify())
+		assert.NoError(t, m.Migrate())
+		assert.NoError(t, m.Verify())
+
+		edited := sqlMigrations("CREATE TABLE person (id INTEGER, name VARCHAR(255));")
+		m = New(db.NewSession(), options, edited)
+		err := m.Verify()
+		var checksumErr *ChecksumError
+		if assert.True(t, errors.As(err, &checksumErr)) && assert.Len(t, checksumErr.Modified, 1) {
+			modified := checksumErr.Modified[0]
+			assert.Equal(t, "201608301400", modified.ID)
+			assert.Equal(t, edited[0].checksum(), modified.Current)
+			assert.NotEqual(t, modified.Recorded, modified.Current)
+		}
+
+		// Runs are only refused when VerifyChecksums is set.
+		verified := *options
+		verified.VerifyChecksums = true
+		m = New(db.NewSession(), &verified, edited)
+		assert.True(t, errors.As(m.Migrate(), &checksumErr))
+		has, err := db.IsTableExist("pet")
+		assert.NoError(t, err)
+		assert.False(t, has)
+
+		m = New(db.NewSession(), options, edited)
+		assert.NoError(t, m.Migrate())
+	})
+}
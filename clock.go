@@ -0,0 +1,57 @@
+package xormigrate
+
+import "time"
+
+// Clock tells the current time. Set Options.Clock to freeze it, e.g. for
+// deterministic tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface.
+type ClockFunc func() time.Time
+
+// Now calls f().
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// IDGenerator generates IDs. Set Options.IDGenerator to make them
+// predictable, e.g. for deterministic tests.
+type IDGenerator interface {
+	// RunID returns the ID of a new run, see RunID.
+	RunID() string
+	// MigrationID returns the ID of a new migration created at now, see
+	// NewMigrationID.
+	MigrationID(now time.Time) string
+}
+
+// migrationIDLayout is the layout of the timestamps of the default migration
+// IDs, e.g. "20160830140000".
+const migrationIDLayout = "20060102150405"
+
+// defaultIDGenerator generates random run IDs and migration IDs made of the
+// UTC timestamp.
+type defaultIDGenerator struct{}
+
+func (defaultIDGenerator) RunID() string {
+	return newRunID()
+}
+
+func (defaultIDGenerator) MigrationID(now time.Time) string {
+	return now.UTC().Format(migrationIDLayout)
+}
+
+// NewMigrationID returns the ID of a migration created now, according to
+// Options.Clock and Options.IDGenerator, e.g. to scaffold the file of a new
+// SQL migration. By default it is the UTC timestamp, e.g. "20160830140000",
+// which TargetTime understands.
+func (x *Xormigrate) NewMigrationID() string {
+	return x.options.IDGenerator.MigrationID(x.options.Clock.Now())
+}
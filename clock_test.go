@@ -0,0 +1,68 @@
+package xormigrate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+// sequentialIDs numbers the runs and the migrations.
+type sequentialIDs struct {
+	runs int
+}
+
+func (s *sequentialIDs) RunID() string {
+	s.runs++
+	return fmt.Sprintf("run-%d", s.runs)
+}
+
+func (s *sequentialIDs) MigrationID(now time.Time) string {
+	return now.Format("2006-01-02") + "-migration"
+}
+
+func TestClock(t *testing.T) {
+	now := time.Date(2016, 8, 30, 14, 0, 0, 0, time.UTC)
+	m := NewFake(&FakeBackend{}, &Options{
+		Clock: ClockFunc(func() time.Time { return now }),
+	}, targetMigrations())
+	assert.Equal(t, "20160830140000", m.NewMigrationID())
+
+	m = NewFake(&FakeBackend{}, &Options{
+		Clock:       ClockFunc(func() time.Time { return now }),
+		IDGenerator: &sequentialIDs{},
+	}, targetMigrations())
+	assert.Equal(t, "2016-08-30-migration", m.NewMigrationID())
+	var runIDs []string
+	m.AddListener(ListenerFunc(func(event Event) {
+		if e, ok := event.(*RunStarted); ok {
+			runIDs = append(runIDs, e.RunID)
+		}
+	}))
+	assert.NoError(t, m.Migrate())
+	assert.NoError(t, m.RollbackLast())
+	assert.Equal(t, []string{"run-1", "run-2"}, runIDs)
+}
+
+func TestClockRecords(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		now := time.Date(2016, 8, 30, 14, 0, 0, 0, time.Local)
+		m := New(db.NewSession(), &Options{
+			TableName:       "migration",
+			RecordAppliedAt: true,
+			RecordRunID:     true,
+			Clock:           ClockFunc(func() time.Time { return now }),
+			IDGenerator:     &sequentialIDs{},
+		}, migrations)
+		assert.NoError(t, m.Migrate())
+
+		var records []migrationRecord
+		assert.NoError(t, db.Table("migration").Asc("id").Find(&records))
+		if assert.Len(t, records, 2) && assert.NotNil(t, records[0].AppliedAt) {
+			assert.Equal(t, "run-1", records[0].RunID)
+			assert.True(t, records[0].AppliedAt.Equal(now), records[0].AppliedAt)
+		}
+	})
+}
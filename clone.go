@@ -0,0 +1,74 @@
+package xormigrate
+
+import (
+	"xorm.io/xorm"
+)
+
+// CloneOption overrides a setting of the Xormigrate returned by Clone.
+type CloneOption func(*Xormigrate)
+
+// WithSession makes the clone run on the given session.
+func WithSession(session *xorm.Session) CloneOption {
+	return func(x *Xormigrate) {
+		x.session = session
+		x.engine = nil
+		x.backend = &sessionBackend{x}
+	}
+}
+
+// WithEngine makes the clone open a session of the given engine for each
+// operation, like NewFromEngine.
+func WithEngine(engine *xorm.Engine) CloneOption {
+	return func(x *Xormigrate) {
+		x.session = nil
+		x.engine = engine
+		x.backend = &sessionBackend{x}
+	}
+}
+
+// WithOptions replaces the options of the clone, as if passed to New: the
+// listeners of the options of x are replaced by the ones of Options.Stats
+// and Options.Logger, and the migrations are sorted if Options.SortByID is
+// set.
+func WithOptions(options *Options) CloneOption {
+	return func(x *Xormigrate) {
+		x.setOptions(options)
+	}
+}
+
+// Clone returns an independent Xormigrate sharing the migrations, the schema
+// initialization function, the listeners, the values and the approvals of x,
+// see Approve, with the given
+// overrides applied. It is useful to run the same migrations against another
+// database, e.g. a shadow database or a second tenant.
+func (x *Xormigrate) Clone(opts ...CloneOption) *Xormigrate {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	options := *x.options
+	clone := &Xormigrate{
+		session:       x.session,
+		engine:        x.engine,
+		options:       &options,
+		migrations:    x.migrations,
+		initSchema:    x.initSchema,
+		initSchemaAll: x.initSchemaAll,
+		listeners:     append([]Listener(nil), x.listeners...),
+		backend:       x.backend,
+
+		optionListeners: x.optionListeners,
+	}
+	if _, ok := x.backend.(*sessionBackend); ok {
+		clone.backend = &sessionBackend{clone}
+	}
+	for key, value := range x.values {
+		clone.WithValue(key, value)
+	}
+	for id, token := range x.approvals {
+		clone.Approve(id, token)
+	}
+	for _, opt := range opts {
+		opt(clone)
+	}
+	return clone
+}
@@ -0,0 +1,53 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestClone(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, migrations)
+		clone := m.Clone(WithEngine(db), WithOptions(&Options{
+			TableName: "shadow_migration",
+		}))
+		defer db.DropTables("shadow_migration")
+
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, int64(2), tableCount(t, db))
+
+		assert.NoError(t, clone.MigrateTo("201608301400"))
+		count, err := db.Table("shadow_migration").Count(&Migration{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+		assert.Equal(t, "migration", m.options.TableName)
+	})
+}
+
+func TestCloneWithOptions(t *testing.T) {
+	first, second := &recordingLogger{}, &recordingLogger{}
+	var events []Event
+	m := NewFake(&FakeBackend{}, &Options{Logger: first}, []*Migration{
+		{ID: "2", Migrate: func(tx *xorm.Session) error { return nil }},
+		{ID: "1", RequiresApproval: true, Migrate: func(tx *xorm.Session) error { return nil }},
+	})
+	m.AddListener(ListenerFunc(func(event Event) { events = append(events, event) }))
+	m.Approve("1", "CHG-42")
+
+	clone := m.Clone(WithOptions(&Options{
+		Logger:   second,
+		SortByID: true,
+		ApprovalVerifier: func(m *Migration, token string) error {
+			return nil
+		},
+	}))
+	assert.NoError(t, clone.Migrate())
+	assert.Equal(t, []string{"1", "2"}, planIDs(clone.migrations))
+	assert.Zero(t, first.count("info xormigrate: Migration started"))
+	assert.Equal(t, 1, second.count("info xormigrate: Migration started"))
+	assert.NotEmpty(t, events)
+}
@@ -0,0 +1,102 @@
+package xormigrate
+
+import (
+	"context"
+	"sort"
+
+	"xorm.io/xorm"
+)
+
+// ChecksumMismatch is a migration applied in both environments with
+// different checksums.
+type ChecksumMismatch struct {
+	ID string
+	A  string
+	B  string
+}
+
+// EnvironmentDiff is the difference between the migration histories of two
+// databases, as returned by CompareEnvironments.
+type EnvironmentDiff struct {
+	// OnlyInA are the IDs of the migrations applied only in the first
+	// database, sorted.
+	OnlyInA []string
+	// OnlyInB are the IDs of the migrations applied only in the second
+	// database, sorted.
+	OnlyInB []string
+	// ChecksumMismatches are the migrations applied in both databases
+	// with different checksums, sorted by ID.
+	ChecksumMismatches []ChecksumMismatch
+}
+
+// Equal reports whether both databases have the same migration history.
+func (d *EnvironmentDiff) Equal() bool {
+	return len(d.OnlyInA) == 0 && len(d.OnlyInB) == 0 && len(d.ChecksumMismatches) == 0
+}
+
+// CompareEnvironments compares the migration tables of two databases, e.g.
+// staging and production before a cutover. Checksums are compared when both
+// tables have a checksum column, see Options.RecordChecksum, and are ignored
+// for migrations recorded without one. The migration table named tableName,
+// or DefaultOptions.TableName if it is empty, is read, both databases are
+// left untouched.
+func CompareEnvironments(a, b *xorm.Engine, tableName string) (*EnvironmentDiff, error) {
+	if tableName == "" {
+		tableName = DefaultOptions.TableName
+	}
+	recordsA, err := readMigrationRecords(a, tableName)
+	if err != nil {
+		return nil, err
+	}
+	recordsB, err := readMigrationRecords(b, tableName)
+	if err != nil {
+		return nil, err
+	}
+	return diffMigrationRecords(recordsA, recordsB), nil
+}
+
+// readMigrationRecords returns the checksums of the migrations recorded in
+// the table, by ID. Checksums are empty if the table has no checksum column.
+func readMigrationRecords(engine *xorm.Engine, tableName string) (map[string]string, error) {
+	cols := []string{"id"}
+	hasChecksum, err := engine.Dialect().IsColumnExist(engine.DB(), context.Background(), tableName, "checksum")
+	if err != nil {
+		return nil, err
+	}
+	if hasChecksum {
+		cols = append(cols, "checksum")
+	}
+	var records []migrationRecord
+	if err := engine.Table(tableName).Cols(cols...).Find(&records); err != nil {
+		return nil, err
+	}
+	checksums := make(map[string]string, len(records))
+	for _, record := range records {
+		checksums[record.ID] = record.Checksum
+	}
+	return checksums, nil
+}
+
+func diffMigrationRecords(a, b map[string]string) *EnvironmentDiff {
+	diff := &EnvironmentDiff{}
+	for id, checksumA := range a {
+		checksumB, ok := b[id]
+		switch {
+		case !ok:
+			diff.OnlyInA = append(diff.OnlyInA, id)
+		case checksumA != "" && checksumB != "" && checksumA != checksumB:
+			diff.ChecksumMismatches = append(diff.ChecksumMismatches, ChecksumMismatch{ID: id, A: checksumA, B: checksumB})
+		}
+	}
+	for id := range b {
+		if _, ok := a[id]; !ok {
+			diff.OnlyInB = append(diff.OnlyInB, id)
+		}
+	}
+	sort.Strings(diff.OnlyInA)
+	sort.Strings(diff.OnlyInB)
+	sort.Slice(diff.ChecksumMismatches, func(i, j int) bool {
+		return diff.ChecksumMismatches[i].ID < diff.ChecksumMismatches[j].ID
+	})
+	return diff
+}
@@ -0,0 +1,67 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestCompareEnvironments(t *testing.T) {
+	staging := newScratchEngine(t)
+	defer staging.Close()
+	production, err := xorm.NewEngine("sqlite3", "file:"+t.Name()+"-production?mode=memory")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer production.Close()
+	staging.SetMaxOpenConns(1)
+	production.SetMaxOpenConns(1)
+
+	options := &Options{RecordChecksum: true}
+	assert.NoError(t, New(staging.NewSession(), options, []*Migration{
+		{ID: "1", UpSQL: "CREATE TABLE book (id INTEGER);"},
+		{ID: "2", UpSQL: "ALTER TABLE book ADD title VARCHAR(255);"},
+		{ID: "3", UpSQL: "CREATE TABLE author (id INTEGER);"},
+	}).Migrate())
+	assert.NoError(t, New(production.NewSession(), options, []*Migration{
+		{ID: "1", UpSQL: "CREATE TABLE book (id INTEGER);"},
+		{ID: "2", UpSQL: "ALTER TABLE book ADD name VARCHAR(255);"},
+		{ID: "4", Migrate: func(tx *xorm.Session) error { return nil }},
+	}).Migrate())
+
+	diff, err := CompareEnvironments(staging, production, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, diff.Equal())
+	assert.Equal(t, []string{"3"}, diff.OnlyInA)
+	assert.Equal(t, []string{"4"}, diff.OnlyInB)
+	if assert.Len(t, diff.ChecksumMismatches, 1) {
+		assert.Equal(t, "2", diff.ChecksumMismatches[0].ID)
+	}
+
+	diff, err = CompareEnvironments(staging, staging, "")
+	assert.NoError(t, err)
+	assert.True(t, diff.Equal())
+
+	diff, err = CompareEnvironments(staging, production, "schema_history")
+	assert.Error(t, err)
+	assert.Nil(t, diff)
+}
+
+func TestCompareEnvironmentsTableName(t *testing.T) {
+	staging := newScratchEngine(t)
+	defer staging.Close()
+	staging.SetMaxOpenConns(1)
+
+	options := &Options{TableName: "schema_history"}
+	assert.NoError(t, New(staging.NewSession(), options, []*Migration{
+		{ID: "1", UpSQL: "CREATE TABLE book (id INTEGER);"},
+	}).Migrate())
+
+	diff, err := CompareEnvironments(staging, staging, "schema_history")
+	if assert.NoError(t, err) {
+		assert.True(t, diff.Equal())
+	}
+}
@@ -0,0 +1,49 @@
+package xormigrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompensationError is returned when a run fails with
+// Options.RollbackOnFailure set. It wraps the error of the run.
+type CompensationError struct {
+	// Err is the error of the run.
+	Err error
+	// RolledBack are the IDs of the migrations rolled back, in order.
+	RolledBack []string
+	// RollbackErr is the error that stopped the rollback, if any: the
+	// migrations applied before the one that failed to roll back are
+	// still applied.
+	RollbackErr error
+}
+
+func (e *CompensationError) Error() string {
+	msg := fmt.Sprintf("xormigrate: Run failed, rolled back [%s]: %v", strings.Join(e.RolledBack, ", "), e.Err)
+	if e.RollbackErr != nil {
+		msg += fmt.Sprintf(", rollback failed: %v", e.RollbackErr)
+	}
+	return msg
+}
+
+func (e *CompensationError) Unwrap() error {
+	return e.Err
+}
+
+// compensate rolls back the migrations applied by the failed run, if
+// Options.RollbackOnFailure is set.
+func (x *Xormigrate) compensate(err error) error {
+	if !x.options.RollbackOnFailure || x.options.UseTransaction {
+		return err
+	}
+	compensationErr := &CompensationError{Err: err, RolledBack: []string{}}
+	for i := len(x.applied) - 1; i >= 0; i-- {
+		migration := x.applied[i]
+		if rollbackErr := x.rollbackMigration(migration); rollbackErr != nil {
+			compensationErr.RollbackErr = fmt.Errorf("%s: %w", migration.ID, rollbackErr)
+			break
+		}
+		compensationErr.RolledBack = append(compensationErr.RolledBack, migration.ID)
+	}
+	return compensationErr
+}
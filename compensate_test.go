@@ -0,0 +1,54 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestRollbackOnFailure(t *testing.T) {
+	errFailed := errors.New("failed")
+	stub := func(id string, err error, rollback bool) *Migration {
+		m := &Migration{
+			ID:      id,
+			Migrate: func(tx *xorm.Session) error { return err },
+		}
+		if rollback {
+			m.Rollback = func(tx *xorm.Session) error { return nil }
+		}
+		return m
+	}
+
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{RollbackOnFailure: true}, []*Migration{
+		stub("1", nil, true),
+		stub("2", nil, true),
+		stub("3", nil, true),
+		stub("4", errFailed, true),
+	})
+	assert.NoError(t, m.MigrateTo("1"))
+
+	err := m.Migrate()
+	assert.True(t, errors.Is(err, errFailed))
+	var compensationErr *CompensationError
+	if assert.True(t, errors.As(err, &compensationErr)) {
+		assert.Equal(t, []string{"3", "2"}, compensationErr.RolledBack)
+		assert.NoError(t, compensationErr.RollbackErr)
+	}
+	assert.Equal(t, []string{"1"}, backend.Applied())
+
+	backend = &FakeBackend{}
+	m = NewFake(backend, &Options{RollbackOnFailure: true}, []*Migration{
+		stub("1", nil, false),
+		stub("2", nil, true),
+		stub("3", errFailed, true),
+	})
+	err = m.Migrate()
+	if assert.True(t, errors.As(err, &compensationErr)) {
+		assert.Equal(t, []string{"2"}, compensationErr.RolledBack)
+		assert.True(t, errors.Is(compensationErr.RollbackErr, ErrRollbackImpossible))
+	}
+	assert.Equal(t, []string{"1"}, backend.Applied())
+}
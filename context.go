@@ -0,0 +1,160 @@
+package xormigrate
+
+import (
+	"context"
+	"time"
+
+	"xorm.io/xorm"
+)
+
+// MigrateFuncCtx is the func signature for migrating with the context of the
+// run.
+type MigrateFuncCtx func(ctx context.Context, tx *xorm.Session) error
+
+// RollbackFuncCtx is the func signature for rolling back with the context of
+// the run.
+type RollbackFuncCtx func(ctx context.Context, tx *xorm.Session) error
+
+// Context returns the context of the run on tx, given to MigrateContext or
+// another Context variant, or context.Background().
+func Context(tx *xorm.Session) context.Context {
+	x := runOf(tx)
+	if x == nil {
+		return context.Background()
+	}
+	return x.context()
+}
+
+// MigrateContext is like Migrate, with a context. Its cancellation aborts
+// the running statement, rolling back the run transaction if any, and
+// prevents the next migrations from running.
+func (x *Xormigrate) MigrateContext(ctx context.Context) error {
+	_, err := x.withResult(ctx, x.migrateAll)
+	return err
+}
+
+// MigrateToContext is like MigrateTo, with a context, see MigrateContext.
+func (x *Xormigrate) MigrateToContext(ctx context.Context, migrationID string) error {
+	_, err := x.withResult(ctx, func() error {
+		return x.migrateTo(migrationID)
+	})
+	return err
+}
+
+// RollbackLastContext is like RollbackLast, with a context, see
+// MigrateContext.
+func (x *Xormigrate) RollbackLastContext(ctx context.Context) error {
+	_, err := x.withResult(ctx, x.rollbackLast)
+	return err
+}
+
+// RollbackToContext is like RollbackTo, with a context, see MigrateContext.
+func (x *Xormigrate) RollbackToContext(ctx context.Context, migrationID string) error {
+	_, err := x.withResult(ctx, func() error {
+		return x.rollbackTo(migrationID)
+	})
+	return err
+}
+
+// RollbackMigrationContext is like RollbackMigration, with a context, see
+// MigrateContext.
+func (x *Xormigrate) RollbackMigrationContext(ctx context.Context, m *Migration) error {
+	_, err := x.withResult(ctx, func() error {
+		return x.rollbackOne(m)
+	})
+	return err
+}
+
+// RollbackGroupContext is like RollbackGroup, with a context, see
+// MigrateContext.
+func (x *Xormigrate) RollbackGroupContext(ctx context.Context, group string) error {
+	_, err := x.withResult(ctx, func() error {
+		return x.rollbackGroup(group)
+	})
+	return err
+}
+
+// RollbackAllContext is like RollbackAll, with a context, see
+// MigrateContext.
+func (x *Xormigrate) RollbackAllContext(ctx context.Context) error {
+	_, err := x.withResult(ctx, x.rollbackAll)
+	return err
+}
+
+// RehearseContext is like Rehearse, with a context, see MigrateContext.
+func (x *Xormigrate) RehearseContext(ctx context.Context) (*RunResult, error) {
+	return x.withResult(ctx, func() error {
+		return x.rehearse("", nil)
+	})
+}
+
+// ResetContext is like Reset, with a context, see MigrateContext.
+func (x *Xormigrate) ResetContext(ctx context.Context) error {
+	_, err := x.withResult(ctx, x.reset)
+	return err
+}
+
+// UpContext is like Up, with a context, see MigrateContext.
+func (x *Xormigrate) UpContext(ctx context.Context, n int) error {
+	_, err := x.withResult(ctx, func() error {
+		return x.steps(Up, n)
+	})
+	return err
+}
+
+// DownContext is like Down, with a context, see MigrateContext.
+func (x *Xormigrate) DownContext(ctx context.Context, n int) error {
+	_, err := x.withResult(ctx, func() error {
+		return x.steps(Down, n)
+	})
+	return err
+}
+
+// GotoContext is like Goto, with a context, see MigrateContext.
+func (x *Xormigrate) GotoContext(ctx context.Context, migrationID string) error {
+	_, err := x.withResult(ctx, func() error {
+		return x.goTo(migrationID)
+	})
+	return err
+}
+
+// context returns the context of the current call.
+func (x *Xormigrate) context() context.Context {
+	if x.ctx == nil {
+		return context.Background()
+	}
+	return x.ctx
+}
+
+// bindSession makes session run its statements with the context of the
+// current call, marked for Options.EchoSQL.
+func (x *Xormigrate) bindSession(session *xorm.Session) {
+	if session == nil || (x.ctx == nil && !x.echoesSQL()) {
+		return
+	}
+	ctx := x.context()
+	if x.echoesSQL() {
+		ctx = x.echoSQL(ctx, session)
+	}
+	session.Context(ctx)
+}
+
+// unbindSession resets the context set by bindSession at the end of a run.
+func (x *Xormigrate) unbindSession(session *xorm.Session) {
+	if session == nil || (x.ctx == nil && !x.echoesSQL()) {
+		return
+	}
+	session.Context(context.Background())
+}
+
+// sleep pauses for d, unless the context of the current call is done.
+func (x *Xormigrate) sleep(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-x.context().Done():
+		return x.context().Err()
+	}
+}
@@ -0,0 +1,60 @@
+package xormigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+type contextKey struct{}
+
+func TestMigrateContext(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), contextKey{}, "value"))
+		defer cancel()
+		var values []interface{}
+		ran := false
+		m := New(db.NewSession(), &Options{TableName: "migration"}, []*Migration{
+			{
+				ID: "201608301400",
+				MigrateContext: func(ctx context.Context, tx *xorm.Session) error {
+					values = append(values, ctx.Value(contextKey{}))
+					return nil
+				},
+				RollbackContext: func(ctx context.Context, tx *xorm.Session) error {
+					values = append(values, ctx.Value(contextKey{}))
+					return nil
+				},
+			},
+			{
+				ID: "201608301430",
+				Migrate: func(tx *xorm.Session) error {
+					cancel()
+					return nil
+				},
+			},
+			{
+				ID: "201608301500",
+				Migrate: func(tx *xorm.Session) error {
+					ran = true
+					return nil
+				},
+			},
+		})
+		assert.NoError(t, m.MigrateToContext(ctx, "201608301400"))
+		assert.NoError(t, m.RollbackLastContext(ctx))
+		assert.Equal(t, []interface{}{"value", "value"}, values)
+
+		err := m.MigrateContext(ctx)
+		assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+		assert.False(t, ran)
+		assert.Equal(t, context.Background(), Context(db.NewSession()))
+
+		// The session is usable again without the canceled context.
+		assert.NoError(t, m.Migrate())
+		assert.True(t, ran)
+	})
+}
@@ -0,0 +1,118 @@
+package xormigrate
+
+import (
+	"fmt"
+	"strings"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// The helpers below make DDL idempotent, so that a migration that partially
+// ran before a crash can run again. They use the IF [NOT] EXISTS forms where
+// the database supports them, and check the catalog first otherwise.
+
+// CreateTableIfNotExists creates table with the given column definitions,
+// e.g. "id INTEGER PRIMARY KEY, name VARCHAR(255)", unless it exists.
+func CreateTableIfNotExists(tx *xorm.Session, table, columns string) error {
+	create := fmt.Sprintf("CREATE TABLE %%s%s (%s)", tx.Engine().Quote(table), columns)
+	if tx.Engine().Dialect().URI().DBType != schemas.MSSQL {
+		return execDDL(tx, fmt.Sprintf(create, "IF NOT EXISTS "))
+	}
+	exists, err := HasTable(tx, table)
+	if err != nil || exists {
+		return err
+	}
+	return execDDL(tx, fmt.Sprintf(create, ""))
+}
+
+// DropTableIfExists drops table if it exists.
+func DropTableIfExists(tx *xorm.Session, table string) error {
+	return execDDL(tx, "DROP TABLE IF EXISTS "+tx.Engine().Quote(table))
+}
+
+// AddColumnIfNotExists adds the column to table with the given definition,
+// e.g. "VARCHAR(255) NULL", unless it exists.
+func AddColumnIfNotExists(tx *xorm.Session, table, column, definition string) error {
+	engine := tx.Engine()
+	add := "ADD"
+	if engine.Dialect().URI().DBType != schemas.MSSQL {
+		add = "ADD COLUMN"
+	}
+	statement := fmt.Sprintf("ALTER TABLE %s %s %%s%s %s", engine.Quote(table), add, engine.Quote(column), definition)
+	if engine.Dialect().URI().DBType == schemas.POSTGRES {
+		return execDDL(tx, fmt.Sprintf(statement, "IF NOT EXISTS "))
+	}
+	exists, err := HasColumn(tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+	return execDDL(tx, fmt.Sprintf(statement, ""))
+}
+
+// DropColumnIfExists drops the column of table if it exists. SQLite supports
+// dropping columns since version 3.35.
+func DropColumnIfExists(tx *xorm.Session, table, column string) error {
+	engine := tx.Engine()
+	statement := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %%s%s", engine.Quote(table), engine.Quote(column))
+	switch engine.Dialect().URI().DBType {
+	case schemas.POSTGRES, schemas.MSSQL:
+		return execDDL(tx, fmt.Sprintf(statement, "IF EXISTS "))
+	}
+	exists, err := HasColumn(tx, table, column)
+	if err != nil || !exists {
+		return err
+	}
+	return execDDL(tx, fmt.Sprintf(statement, ""))
+}
+
+// CreateIndexIfNotExists creates the index on the columns of table unless it
+// exists.
+func CreateIndexIfNotExists(tx *xorm.Session, table, index string, columns ...string) error {
+	return createIndexIfNotExists(tx, "INDEX", table, index, columns)
+}
+
+// CreateUniqueIndexIfNotExists creates the unique index on the columns of
+// table unless it exists.
+func CreateUniqueIndexIfNotExists(tx *xorm.Session, table, index string, columns ...string) error {
+	return createIndexIfNotExists(tx, "UNIQUE INDEX", table, index, columns)
+}
+
+func createIndexIfNotExists(tx *xorm.Session, kind, table, index string, columns []string) error {
+	engine := tx.Engine()
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = engine.Quote(column)
+	}
+	statement := fmt.Sprintf("CREATE %s %%s%s ON %s (%s)", kind, engine.Quote(index), engine.Quote(table), strings.Join(quoted, ", "))
+	switch engine.Dialect().URI().DBType {
+	case schemas.POSTGRES, schemas.SQLITE:
+		return execDDL(tx, fmt.Sprintf(statement, "IF NOT EXISTS "))
+	}
+	exists, err := HasIndex(tx, table, index)
+	if err != nil || exists {
+		return err
+	}
+	return execDDL(tx, fmt.Sprintf(statement, ""))
+}
+
+// DropIndexIfExists drops the index of table if it exists.
+func DropIndexIfExists(tx *xorm.Session, table, index string) error {
+	engine := tx.Engine()
+	switch engine.Dialect().URI().DBType {
+	case schemas.POSTGRES, schemas.SQLITE:
+		return execDDL(tx, "DROP INDEX IF EXISTS "+engine.Quote(index))
+	case schemas.MSSQL:
+		return execDDL(tx, fmt.Sprintf("DROP INDEX IF EXISTS %s ON %s", engine.Quote(index), engine.Quote(table)))
+	}
+	exists, err := HasIndex(tx, table, index)
+	if err != nil || !exists {
+		return err
+	}
+	return execDDL(tx, fmt.Sprintf("DROP INDEX %s ON %s", engine.Quote(index), engine.Quote(table)))
+}
+
+func execDDL(tx *xorm.Session, statement string) error {
+	_, err := tx.Exec(statement)
+	return err
+}
@@ -0,0 +1,62 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestIdempotentDDL(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		migrate := func(tx *xorm.Session) error {
+			for i := 0; i < 2; i++ {
+				if err := CreateTableIfNotExists(tx, "book", "id INTEGER PRIMARY KEY, name VARCHAR(255)"); err != nil {
+					return err
+				}
+				if err := AddColumnIfNotExists(tx, "book", "author", "VARCHAR(255) NULL"); err != nil {
+					return err
+				}
+				if err := CreateIndexIfNotExists(tx, "book", "idx_book_author", "author"); err != nil {
+					return err
+				}
+				if err := CreateUniqueIndexIfNotExists(tx, "book", "uqe_book_name", "name"); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		m := New(db.NewSession(), &Options{TableName: "migration"}, []*Migration{
+			{ID: "201608301400", Migrate: migrate},
+		})
+		assert.NoError(t, m.Migrate())
+
+		tx := db.NewSession()
+		defer tx.Close()
+		exists, err := HasColumn(tx, "book", "author")
+		assert.NoError(t, err)
+		assert.True(t, exists)
+		exists, err = HasIndex(tx, "book", "idx_book_author")
+		assert.NoError(t, err)
+		assert.True(t, exists)
+
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, DropIndexIfExists(tx, "book", "idx_book_author"))
+			assert.NoError(t, DropIndexIfExists(tx, "book", "uqe_book_name"))
+			assert.NoError(t, DropColumnIfExists(tx, "book", "author"))
+		}
+		exists, err = HasIndex(tx, "book", "idx_book_author")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+		exists, err = HasColumn(tx, "book", "author")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, DropTableIfExists(tx, "book"))
+		}
+		exists, err = HasTable(tx, "book")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
@@ -0,0 +1,154 @@
+package xormigrate
+
+import (
+	"fmt"
+	"time"
+)
+
+// MigrationInfo is everything known about a migration, see Describe.
+type MigrationInfo struct {
+	ID          string
+	Description string
+	Tags        []string
+	Dialects    []string
+	Group       string
+	// DependsOn are the migrations it depends on, Dependents the ones
+	// depending on it.
+	DependsOn  []string
+	Dependents []string
+	// Source is where the migration is defined: the file and line of
+	// Migrate for a Go migration, the files of a SQL migration loaded with
+	// LoadFS.
+	Source []string
+	// Overrides are the changes made to the migration by OverlaySource.
+	Overrides []string
+	// Checksum is the checksum of the definition, see Migration.Checksum,
+	// and AppliedChecksum the one recorded when it was applied, if
+	// Options.RecordChecksum was set then.
+	Checksum        string
+	AppliedChecksum string
+	// Applied reports whether the migration was applied, and Status
+	// describes it then.
+	Applied bool
+	Status  MigrationStatus
+	// Estimate is its historical duration according to Options.History,
+	// zero when unknown.
+	Estimate time.Duration
+	// UpSQL and DownSQL are the statements executed on the dialect of the
+	// database, none for Go migrations.
+	UpSQL   []string
+	DownSQL []string
+	// Targets is the state of the migration on other databases, by name,
+	// see DescribeAcross.
+	Targets map[string]*TargetInfo
+}
+
+// TargetInfo is the state of a migration on a database, see
+// DescribeAcross. Err is set if the database couldn't be read.
+type TargetInfo struct {
+	Applied bool
+	Status  MigrationStatus
+	Err     error
+}
+
+// Describe returns everything known about the migration matching
+// migrationID, e.g. for a detail page of an admin UI, or
+// ErrMigrationIDDoesNotExist. Like Pending, it is strictly read-only, and
+// only the definition is described if the migration table does not exist.
+func (x *Xormigrate) Describe(migrationID string) (*MigrationInfo, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	if err := x.checkIDExist(migrationID); err != nil {
+		return nil, err
+	}
+	var m *Migration
+	info := &MigrationInfo{ID: migrationID}
+	for _, migration := range x.migrations {
+		if migration.ID == migrationID {
+			m = migration
+		}
+		if contains(migration.DependsOn, migrationID) {
+			info.Dependents = append(info.Dependents, migration.ID)
+		}
+	}
+	info.Description, info.Tags, info.Dialects, info.Group = m.Description, m.Tags, m.dialects(), m.Group
+	info.DependsOn, info.Overrides = m.DependsOn, m.Overrides
+	info.Checksum = m.checksum()
+	if file, line := goMigrationFile(m); file != "" {
+		info.Source = []string{fmt.Sprintf("%s:%d", file, line)}
+	} else {
+		info.Source = m.files
+	}
+	if x.options.History != nil {
+		info.Estimate, _ = x.options.History.Duration(m.ID)
+	}
+	if m.Migrate == nil && m.MigrateContext == nil && len(m.Steps) == 0 {
+		dialect := x.backend.dialect()
+		info.UpSQL = splitStatements(m.upSQL(dialect))
+		info.DownSQL = splitStatements(m.downSQL(dialect))
+	}
+
+	record, err := x.record(migrationID)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		info.Applied, info.Status, info.AppliedChecksum = true, recordStatus(*record, m), record.Checksum
+	}
+	return info, nil
+}
+
+// DescribeAcross returns the migration as described by Describe, along with
+// its state on each of the targets, by name, e.g. the databases of the other
+// environments or tenants. The errors of the targets are reported in
+// MigrationInfo.Targets.
+func (x *Xormigrate) DescribeAcross(migrationID string, targets map[string]*Xormigrate) (*MigrationInfo, error) {
+	info, err := x.Describe(migrationID)
+	if err != nil {
+		return nil, err
+	}
+	info.Targets = make(map[string]*TargetInfo, len(targets))
+	for name, target := range targets {
+		info.Targets[name] = target.targetInfo(migrationID)
+	}
+	return info, nil
+}
+
+func (x *Xormigrate) targetInfo(migrationID string) *TargetInfo {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	record, err := x.record(migrationID)
+	if err != nil || record == nil {
+		return &TargetInfo{Err: err}
+	}
+	var m *Migration
+	for _, migration := range x.migrations {
+		if migration.ID == migrationID {
+			m = migration
+		}
+	}
+	return &TargetInfo{Applied: true, Status: recordStatus(*record, m)}
+}
+
+// record returns the record of the migration matching migrationID, nil if it
+// was not applied or the migration table does not exist.
+func (x *Xormigrate) record(migrationID string) (*migrationRecord, error) {
+	initialized, err := x.initialized()
+	if err != nil || !initialized {
+		return nil, err
+	}
+	records, err := x.backend.listRecords()
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		if records[i].ID == migrationID {
+			return &records[i], nil
+		}
+	}
+	return nil, nil
+}
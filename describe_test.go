@@ -0,0 +1,61 @@
+package xormigrate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xorm.io/xorm"
+)
+
+func describeMigrations() []*Migration {
+	return []*Migration{
+		{ID: "201608301400", Description: "create person", Tags: []string{"expand"}, UpSQL: "CREATE TABLE person (id INTEGER);\nCREATE INDEX idx_person ON person (id);", DownSQL: "DROP TABLE person;"},
+		{ID: "201608301430", DependsOn: []string{"201608301400"}, Migrate: func(tx *xorm.Session) error { return nil }},
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	now := time.Date(2016, 8, 30, 14, 0, 0, 0, time.UTC)
+	options := &Options{RecordChecksum: true, RecordAppliedAt: true, Clock: ClockFunc(func() time.Time { return now }), History: Durations{"201608301400": time.Minute}}
+	m := NewFake(&FakeBackend{Dialect: "postgres"}, options, describeMigrations())
+
+	_, err := m.Describe("201609011200")
+	assert.Equal(t, ErrMigrationIDDoesNotExist, err)
+
+	info, err := m.Describe("201608301400")
+	require.NoError(t, err)
+	assert.False(t, info.Applied)
+	assert.Equal(t, "create person", info.Description)
+	assert.Equal(t, []string{"expand"}, info.Tags)
+	assert.Equal(t, []string{"201608301430"}, info.Dependents)
+	assert.Equal(t, time.Minute, info.Estimate)
+	assert.Len(t, info.UpSQL, 2)
+	assert.Len(t, info.DownSQL, 1)
+	assert.NotEmpty(t, info.Checksum)
+
+	assert.NoError(t, m.MigrateTo("201608301400"))
+	pending := NewFake(&FakeBackend{}, &Options{}, describeMigrations())
+	broken := NewFake(&FakeBackend{}, &Options{AssumeTableExists: true}, describeMigrations())
+
+	info, err = m.DescribeAcross("201608301400", map[string]*Xormigrate{"staging": pending, "production": m, "broken": broken})
+	require.NoError(t, err)
+	assert.True(t, info.Applied)
+	assert.Equal(t, now, info.Status.AppliedAt)
+	assert.Equal(t, info.Checksum, info.AppliedChecksum)
+	assert.False(t, info.Targets["staging"].Applied)
+	assert.True(t, info.Targets["production"].Applied)
+	var missing *MissingTableError
+	assert.True(t, errors.As(info.Targets["broken"].Err, &missing))
+
+	info, err = m.Describe("201608301430")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"201608301400"}, info.DependsOn)
+	assert.Empty(t, info.UpSQL)
+	if assert.Len(t, info.Source, 1) {
+		assert.True(t, strings.Contains(info.Source[0], "describe_test.go:"), info.Source[0])
+	}
+}
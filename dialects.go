@@ -0,0 +1,37 @@
+package xormigrate
+
+import "xorm.io/xorm/schemas"
+
+// OnlyDialects restricts migrations to the listed databases, see
+// Migration.Dialects, and returns them, e.g.
+//
+//	migrations := append(common, xormigrate.OnlyPostgres(&xormigrate.Migration{
+//		ID:    "201608301400",
+//		UpSQL: "CREATE EXTENSION IF NOT EXISTS pg_trgm;",
+//	})...)
+func OnlyDialects(dialects []string, migrations ...*Migration) []*Migration {
+	for _, migration := range migrations {
+		migration.Dialects = append([]string(nil), dialects...)
+	}
+	return migrations
+}
+
+// OnlyPostgres restricts migrations to PostgreSQL, see OnlyDialects.
+func OnlyPostgres(migrations ...*Migration) []*Migration {
+	return OnlyDialects([]string{string(schemas.POSTGRES)}, migrations...)
+}
+
+// OnlyMySQL restricts migrations to MySQL, see OnlyDialects.
+func OnlyMySQL(migrations ...*Migration) []*Migration {
+	return OnlyDialects([]string{string(schemas.MYSQL)}, migrations...)
+}
+
+// OnlySQLite restricts migrations to SQLite, see OnlyDialects.
+func OnlySQLite(migrations ...*Migration) []*Migration {
+	return OnlyDialects([]string{string(schemas.SQLITE)}, migrations...)
+}
+
+// OnlySQLServer restricts migrations to SQL Server, see OnlyDialects.
+func OnlySQLServer(migrations ...*Migration) []*Migration {
+	return OnlyDialects([]string{string(schemas.MSSQL)}, migrations...)
+}
@@ -0,0 +1,31 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestOnlyDialects(t *testing.T) {
+	var ran []string
+	stub := func(id string) *Migration {
+		return &Migration{ID: id, Migrate: func(tx *xorm.Session) error {
+			ran = append(ran, id)
+			return nil
+		}}
+	}
+	migrations := []*Migration{stub("201608301400")}
+	migrations = append(migrations, OnlyPostgres(stub("201608301430"), stub("201608301500"))...)
+	migrations = append(migrations, OnlySQLite(stub("201609011200"))...)
+	migrations = append(migrations, OnlyMySQL(stub("201609021200"))...)
+	migrations = append(migrations, OnlySQLServer(stub("201609031200"))...)
+	assert.Equal(t, []string{"postgres"}, migrations[2].Dialects)
+
+	backend := &FakeBackend{Dialect: "sqlite3"}
+	m := NewFake(backend, &Options{}, migrations)
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, []string{"201608301400", "201609011200"}, ran)
+	// The other ones are skipped, but still recorded.
+	assert.Len(t, backend.Applied(), len(migrations))
+}
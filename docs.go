@@ -0,0 +1,114 @@
+package xormigrate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"xorm.io/xorm"
+)
+
+// WriteSchemaDocs writes a Markdown documentation of the schema of engine to
+// w: a table listing the columns of each database table, followed by a
+// Mermaid entity-relationship diagram. As xorm doesn't report foreign keys,
+// relationships are inferred from columns named "<table>_id". The excluded
+// tables, e.g. the migration table, are left out.
+func WriteSchemaDocs(w io.Writer, engine *xorm.Engine, exclude ...string) error {
+	tables, err := engine.DBMetas()
+	if err != nil {
+		return err
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+	names := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		names[table.Name] = !contains(exclude, table.Name)
+	}
+
+	dialect := engine.Dialect()
+	var b bytes.Buffer
+	b.WriteString("# Database schema\n")
+	for _, table := range tables {
+		if !names[table.Name] {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", table.Name)
+		b.WriteString("| Column | Type | Nullable | Default | Key |\n")
+		b.WriteString("|--------|------|----------|---------|-----|\n")
+		for _, col := range table.Columns() {
+			nullable, key := "NO", ""
+			if col.Nullable {
+				nullable = "YES"
+			}
+			if col.IsPrimaryKey {
+				key = "PK"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", col.Name, dialect.SQLType(col), nullable, col.Default, key)
+		}
+	}
+
+	b.WriteString("\n## Diagram\n\n```mermaid\nerDiagram\n")
+	for _, table := range tables {
+		if !names[table.Name] {
+			continue
+		}
+		fmt.Fprintf(&b, "    %s {\n", table.Name)
+		for _, col := range table.Columns() {
+			key := ""
+			if col.IsPrimaryKey {
+				key = " PK"
+			}
+			fmt.Fprintf(&b, "        %s %s%s\n", strings.ReplaceAll(col.SQLType.Name, " ", "_"), col.Name, key)
+		}
+		b.WriteString("    }\n")
+	}
+	for _, table := range tables {
+		if !names[table.Name] {
+			continue
+		}
+		for _, col := range table.Columns() {
+			ref := strings.TrimSuffix(col.Name, "_id")
+			if ref != col.Name && names[ref] {
+				fmt.Fprintf(&b, "    %s ||--o{ %s : %s\n", ref, table.Name, col.Name)
+			}
+		}
+	}
+	b.WriteString("```\n")
+
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// SchemaDocsListener is a Listener writing the documentation of the schema
+// of Engine, as done by WriteSchemaDocs, to the file at Path after each
+// successful run.
+type SchemaDocsListener struct {
+	Engine *xorm.Engine
+	Path   string
+	// Exclude lists the tables left out, e.g. the migration table.
+	Exclude []string
+	// OnError is called when the documentation can't be written. Can be nil.
+	OnError func(err error)
+}
+
+// OnEvent writes the documentation when a run finishes successfully.
+func (l *SchemaDocsListener) OnEvent(event Event) {
+	if e, ok := event.(*RunFinished); !ok || e.Err != nil {
+		return
+	}
+	if err := l.write(); err != nil && l.OnError != nil {
+		l.OnError(err)
+	}
+}
+
+func (l *SchemaDocsListener) write() error {
+	var b bytes.Buffer
+	if err := WriteSchemaDocs(&b, l.Engine, l.Exclude...); err != nil {
+		return err
+	}
+	return os.WriteFile(l.Path, b.Bytes(), 0o644)
+}
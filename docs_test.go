@@ -0,0 +1,38 @@
+package xormigrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestSchemaDocsListener(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		path := filepath.Join(t.TempDir(), "schema.md")
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, migrations)
+		m.AddListener(&SchemaDocsListener{
+			Engine:  db,
+			Path:    path,
+			Exclude: []string{"migration"},
+			OnError: func(err error) {
+				t.Error(err)
+			},
+		})
+		assert.NoError(t, m.Migrate())
+
+		docs, err := os.ReadFile(path)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, string(docs), "\n## person\n")
+		assert.Contains(t, string(docs), "\n## pet\n")
+		assert.Contains(t, string(docs), "| person_id |")
+		assert.Contains(t, string(docs), "    person ||--o{ pet : person_id\n")
+		assert.NotContains(t, string(docs), "## migration")
+	})
+}
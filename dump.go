@@ -0,0 +1,57 @@
+package xormigrate
+
+import (
+	"io/fs"
+	"strings"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// InitSchemaFromSQL sets the schema initialization, see InitSchema, to
+// execute a full schema dump read from the file name of fsys, e.g. the
+// output of pg_dump --schema-only, and to record all the migrations as
+// applied, so that new environments are bootstrapped from the dump instead of
+// replaying every migration. The statements are separated by semicolons, and
+// psql meta-commands, the lines starting with a backslash, are ignored.
+func (x *Xormigrate) InitSchemaFromSQL(fsys fs.FS, name string) error {
+	dump, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	statements := splitStatements(stripMetaCommands(string(dump)))
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.initSchema = func(tx *xorm.Session) error {
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		if tx.Engine().Dialect().URI().DBType == schemas.POSTGRES {
+			// pg_dump empties the search path, which the migration table
+			// is found with.
+			if _, err := tx.Exec("RESET search_path"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	x.initSchemaAll = true
+	return nil
+}
+
+// stripMetaCommands removes the psql meta-commands of a dump, e.g.
+// "\connect db", which are not SQL.
+func stripMetaCommands(dump string) string {
+	lines := strings.Split(dump, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), `\`) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,55 @@
+package xormigrate
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestInitSchemaFromSQL(t *testing.T) {
+	dump := fstest.MapFS{"schema.sql": {Data: []byte(`-- Dumped schema
+\connect test
+CREATE TABLE person (name VARCHAR(255), created_at TIMESTAMP);
+CREATE TABLE pet (name VARCHAR(255), person_id INTEGER);
+`)}}
+
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+		assert.Error(t, m.InitSchemaFromSQL(dump, "missing.sql"))
+		assert.NoError(t, m.InitSchemaFromSQL(dump, "schema.sql"))
+		assert.NoError(t, m.Migrate())
+
+		has, err := db.IsTableExist("pet")
+		assert.NoError(t, err)
+		assert.True(t, has)
+		// The initialization and both migrations.
+		assert.Equal(t, int64(3), tableCount(t, db))
+		pending, err := m.Pending()
+		assert.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+}
+
+func TestInitSchemaFromSQLBatches(t *testing.T) {
+	dump := fstest.MapFS{"schema.sql": {Data: []byte("CREATE TABLE person (name VARCHAR(255));\n")}}
+	var many []*Migration
+	for i := 0; i < 250; i++ {
+		many = append(many, &Migration{ID: fmt.Sprintf("2016083014%04d", i), UpSQL: "SELECT 1;"})
+	}
+
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration", RecordChecksum: true, RecordStatus: true, RecordAppliedAt: true}, many)
+		assert.NoError(t, m.InitSchemaFromSQL(dump, "schema.sql"))
+		assert.NoError(t, m.Migrate())
+
+		assert.Equal(t, int64(251), tableCount(t, db))
+		status, err := m.Status()
+		assert.NoError(t, err)
+		assert.Len(t, status.Applied, 250)
+		assert.Empty(t, status.Pending)
+		assert.False(t, status.Applied[249].AppliedAt.IsZero())
+	})
+}
@@ -0,0 +1,215 @@
+package xormigrate
+
+import (
+	"time"
+)
+
+// Event is emitted to the registered listeners while running migrations.
+// It is one of *RunStarted, *MigrationStarting, *MigrationApplied,
+// *StepApplied, *StatementExecuted, *MigrationSkipped, *MigrationRetrying,
+// *MigrationFailed, *BudgetExceeded, *LongTransaction, *Warning,
+// *RolledBack or *RunFinished.
+type Event interface {
+	event()
+}
+
+// RunInfo identifies the run emitting an event. It is embedded in all
+// events.
+type RunInfo struct {
+	// RunID is the unique ID of the Migrate or Rollback call, see RunID.
+	RunID string
+}
+
+func (r *RunInfo) info() *RunInfo {
+	return r
+}
+
+// RunStarted is emitted when a migration or rollback run starts.
+type RunStarted struct {
+	RunInfo
+
+	Rollback bool
+}
+
+// MigrationStarting is emitted before a migration is applied. Index is its
+// position, from 1, among the Total migrations the run applies. Expected is
+// its historical duration and Remaining the sum of the historical durations
+// of the migrations left, itself included, according to Options.History.
+// Both are zero when unknown, and migrations without history are not counted
+// in Remaining.
+type MigrationStarting struct {
+	RunInfo
+
+	ID          string
+	Description string
+	Index       int
+	Total       int
+	Expected    time.Duration
+	Remaining   time.Duration
+}
+
+// MigrationApplied is emitted after a migration was applied and recorded.
+type MigrationApplied struct {
+	RunInfo
+
+	ID          string
+	Description string
+	Duration    time.Duration
+}
+
+// StepApplied is emitted after a step of a migration completed, see
+// Migration.Steps.
+type StepApplied struct {
+	RunInfo
+
+	ID       string
+	Step     string
+	Duration time.Duration
+}
+
+// StatementExecuted is emitted after a statement of the UpSQL of a migration
+// was executed. Index is its position, from 1, among the Total statements of
+// the script.
+type StatementExecuted struct {
+	RunInfo
+
+	ID       string
+	Index    int
+	Total    int
+	Duration time.Duration
+}
+
+// MigrationSkipped is emitted after a migration that does not apply to the
+// database was recorded without running, e.g. because of its Dialects.
+type MigrationSkipped struct {
+	RunInfo
+
+	ID     string
+	Reason string
+}
+
+// MigrationRetrying is emitted when an idempotent migration failed with a
+// transient error and is about to run again, see Options.Retry.
+type MigrationRetrying struct {
+	RunInfo
+
+	ID      string
+	Attempt int
+	Err     error
+}
+
+// MigrationFailed is emitted when a migration or its recording fails.
+type MigrationFailed struct {
+	RunInfo
+
+	ID  string
+	Err error
+}
+
+// BudgetExceeded is emitted after a migration took longer than its Budget.
+type BudgetExceeded struct {
+	RunInfo
+
+	ID       string
+	Duration time.Duration
+	Budget   time.Duration
+}
+
+// LongTransaction is emitted when the run transaction has been open for
+// longer than Options.WarnTransactionAfter.
+type LongTransaction struct {
+	RunInfo
+
+	Elapsed time.Duration
+}
+
+// Warning is emitted for a non-fatal issue found by a run, e.g. a migration
+// without rollback. ID is the migration concerned, if any.
+type Warning struct {
+	RunInfo
+
+	ID      string
+	Message string
+}
+
+// RolledBack is emitted after a migration was rolled back.
+type RolledBack struct {
+	RunInfo
+
+	ID string
+}
+
+// RunFinished is emitted when a migration or rollback run ends. Err is the
+// error returned by the run, if any.
+type RunFinished struct {
+	RunInfo
+
+	Rollback bool
+	Duration time.Duration
+	Err      error
+}
+
+func (*RunStarted) event()        {}
+func (*MigrationStarting) event() {}
+func (*MigrationApplied) event()  {}
+func (*StepApplied) event()       {}
+func (*StatementExecuted) event() {}
+func (*MigrationSkipped) event()  {}
+func (*MigrationRetrying) event() {}
+func (*MigrationFailed) event()   {}
+func (*BudgetExceeded) event()    {}
+func (*LongTransaction) event()   {}
+func (*Warning) event()           {}
+func (*RolledBack) event()        {}
+func (*RunFinished) event()       {}
+
+// Listener receives the events emitted while running migrations.
+// Events are delivered synchronously, in order.
+type Listener interface {
+	OnEvent(event Event)
+}
+
+// ListenerFunc adapts a function to the Listener interface.
+type ListenerFunc func(event Event)
+
+// OnEvent calls f(event).
+func (f ListenerFunc) OnEvent(event Event) {
+	f(event)
+}
+
+// AddListener registers a listener receiving all events.
+func (x *Xormigrate) AddListener(listener Listener) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.listeners = append(x.listeners, listener)
+}
+
+func (x *Xormigrate) emit(event Event) {
+	if e, ok := event.(interface{ info() *RunInfo }); ok {
+		e.info().RunID = x.runID
+	}
+	x.collect(event)
+	for _, listener := range x.listeners {
+		listener.OnEvent(event)
+	}
+}
+
+// emitRunStarted starts a run with a new ID, calling Options.BeforeAll.
+func (x *Xormigrate) emitRunStarted(rollback bool) {
+	x.runID = x.options.IDGenerator.RunID()
+	x.emit(&RunStarted{Rollback: rollback})
+	if x.options.BeforeAll != nil {
+		x.options.BeforeAll(rollback)
+	}
+}
+
+// emitRunFinished ends the run, calling Options.AfterAll.
+func (x *Xormigrate) emitRunFinished(rollback bool, start time.Time, err *error) {
+	duration := time.Since(start)
+	if x.options.AfterAll != nil {
+		x.options.AfterAll(rollback, *err, duration)
+	}
+	x.emit(&RunFinished{Rollback: rollback, Duration: duration, Err: *err})
+	x.runID = ""
+}
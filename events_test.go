@@ -0,0 +1,125 @@
+package xormigrate
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestEvents(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		errFailed := errors.New("failed")
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, append(migrations, &Migration{
+			ID: "201807221927",
+			Migrate: func(tx *xorm.Session) error {
+				return errFailed
+			},
+		}))
+
+		var events []string
+		m.AddListener(ListenerFunc(func(event Event) {
+			switch e := event.(type) {
+			case *RunStarted:
+				events = append(events, fmt.Sprintf("started rollback=%v", e.Rollback))
+			case *MigrationApplied:
+				events = append(events, "applied "+e.ID)
+			case *MigrationFailed:
+				events = append(events, fmt.Sprintf("failed %s: %v", e.ID, e.Err))
+			case *RolledBack:
+				events = append(events, "rolled back "+e.ID)
+			case *RunFinished:
+				events = append(events, fmt.Sprintf("finished rollback=%v err=%v", e.Rollback, e.Err))
+			}
+		}))
+
+		assert.ErrorIs(t, m.Migrate(), errFailed)
+		assert.NoError(t, m.RollbackLast())
+		assert.Equal(t, []string{
+			"started rollback=false",
+			"applied 201608301400",
+			"applied 201608301430",
+			"failed 201807221927: failed",
+			"finished rollback=false err=xormigrate: Migration 201807221927 failed during migrate: failed",
+			"started rollback=true",
+			"rolled back 201608301430",
+			"finished rollback=true err=<nil>",
+		}, events)
+	})
+}
+
+func TestBudgetExceeded(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, []*Migration{
+			{
+				ID:     "201608301400",
+				Budget: time.Nanosecond,
+				Migrate: func(tx *xorm.Session) error {
+					time.Sleep(time.Millisecond)
+					return nil
+				},
+			},
+			{
+				ID:      "201608301430",
+				Budget:  time.Hour,
+				Migrate: func(tx *xorm.Session) error { return nil },
+			},
+		})
+		var exceeded []*BudgetExceeded
+		m.AddListener(ListenerFunc(func(event Event) {
+			if e, ok := event.(*BudgetExceeded); ok {
+				exceeded = append(exceeded, e)
+			}
+		}))
+		assert.NoError(t, m.Migrate())
+
+		if assert.Len(t, exceeded, 1) {
+			assert.Equal(t, "201608301400", exceeded[0].ID)
+			assert.Equal(t, time.Nanosecond, exceeded[0].Budget)
+		}
+		var records []migrationRecord
+		assert.NoError(t, db.Table("migration").Cols("id", "over_budget").Asc("id").Find(&records))
+		if assert.Len(t, records, 2) {
+			assert.True(t, records[0].OverBudget)
+			assert.False(t, records[1].OverBudget)
+		}
+	})
+}
+
+func TestHooks(t *testing.T) {
+	var calls []string
+	errFailed := errors.New("failed")
+	m := NewFake(&FakeBackend{}, &Options{
+		BeforeAll: func(rollback bool) {
+			calls = append(calls, fmt.Sprintf("before all %t", rollback))
+		},
+		AfterAll: func(rollback bool, err error, d time.Duration) {
+			calls = append(calls, fmt.Sprintf("after all %t %v", rollback, err))
+		},
+		BeforeMigration: func(m *Migration) {
+			calls = append(calls, "before "+m.ID)
+		},
+		AfterMigration: func(m *Migration, err error, d time.Duration) {
+			calls = append(calls, fmt.Sprintf("after %s %v", m.ID, err))
+		},
+	}, []*Migration{
+		{ID: "201608301400", Migrate: func(tx *xorm.Session) error { return nil }},
+		{ID: "201608301430", Migrate: func(tx *xorm.Session) error { return errFailed }},
+	})
+	assert.ErrorIs(t, m.Migrate(), errFailed)
+	assert.Equal(t, []string{
+		"before all false",
+		"before 201608301400",
+		"after 201608301400 <nil>",
+		"before 201608301430",
+		"after 201608301430 xormigrate: Migration 201608301430 failed during migrate: failed",
+		"after all false xormigrate: Migration 201608301430 failed during migrate: failed",
+	}, calls)
+}
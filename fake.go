@@ -0,0 +1,383 @@
+package xormigrate
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"xorm.io/xorm"
+)
+
+// FakeBackend is an in-memory migration history replacing the database, to
+// unit test how migrations are wired: their order, listeners, policies and
+// error handling. It records the operations made on the history, see Ops.
+//
+// Migrations run on a fake backend receive a nil session, so they should
+// only be stubs, and their SQL scripts are not executed. Transactions are
+// emulated: on rollback, the history is restored to its state at the
+// beginning of the transaction.
+type FakeBackend struct {
+	// Dialect is the database type reported to Migration.Dialects and
+	// policies, e.g. "postgres".
+	Dialect string
+	// Fail, if set, is called before each operation modifying the
+	// history with its description, e.g. "insert 201608301400": a non-nil
+	// error fails the operation.
+	Fail func(op string) error
+
+	mu          sync.Mutex
+	table       bool
+	records     map[string]migrationRecord
+	saved       map[string]migrationRecord
+	steps       map[string][]string
+	savedSteps  map[string][]string
+	canary      map[string]canaryRecord
+	savedCanary map[string]canaryRecord
+	inTx        bool
+	locks       map[string]chan struct{}
+	ops         []string
+}
+
+// NewFake returns a Xormigrate storing its history in backend instead of a
+// database.
+func NewFake(backend *FakeBackend, options *Options, migrations []*Migration) *Xormigrate {
+	x := New(nil, options, migrations)
+	x.backend = backend
+	return x
+}
+
+// Ops returns the operations made on the history, in order, e.g.
+// "create table", "begin", "insert 201608301400", "delete 201608301400",
+// "step 201608301400 backfill", "canary 201608301400", "commit", "rollback",
+// "lock" or "unlock".
+func (f *FakeBackend) Ops() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.ops...)
+}
+
+// Applied returns the sorted IDs of the migrations in the history.
+func (f *FakeBackend) Applied() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids := make([]string, 0, len(f.records))
+	for id := range f.records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// do records an operation, unless Fail rejects it.
+func (f *FakeBackend) do(op string) error {
+	if f.Fail != nil {
+		if err := f.Fail(op); err != nil {
+			return err
+		}
+	}
+	f.ops = append(f.ops, op)
+	return nil
+}
+
+func (f *FakeBackend) tableExists() (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.table, nil
+}
+
+func (f *FakeBackend) probeTable() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.table {
+		return &MissingTableError{TableName: "fake", Err: ErrNotInitialized}
+	}
+	return nil
+}
+
+func (f *FakeBackend) createTable() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.do("create table"); err != nil {
+		return err
+	}
+	f.table = true
+	f.records = make(map[string]migrationRecord)
+	return nil
+}
+
+func (f *FakeBackend) upgradeTable() error {
+	return nil
+}
+
+func (f *FakeBackend) recordIDs() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids := make([]string, 0, len(f.records))
+	for id := range f.records {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (f *FakeBackend) countRecords(exclude []string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var count int64
+	for id := range f.records {
+		if !contains(exclude, id) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *FakeBackend) insertRecord(record *migrationRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.do("insert " + record.ID); err != nil {
+		return err
+	}
+	f.records[record.ID] = *record
+	return nil
+}
+
+func (f *FakeBackend) insertRecords(records []migrationRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, record := range records {
+		if err := f.do("insert " + record.ID); err != nil {
+			return err
+		}
+		f.records[record.ID] = record
+	}
+	return nil
+}
+
+func (f *FakeBackend) deleteRecord(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.do("delete " + id); err != nil {
+		return err
+	}
+	delete(f.records, id)
+	return nil
+}
+
+func (f *FakeBackend) setChecksum(id, checksum string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.do("checksum " + id); err != nil {
+		return err
+	}
+	record := f.records[id]
+	record.Checksum = checksum
+	f.records[id] = record
+	return nil
+}
+
+func (f *FakeBackend) listRecords() ([]migrationRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records := make([]migrationRecord, 0, len(f.records))
+	for _, record := range f.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+	return records, nil
+}
+
+func (f *FakeBackend) replaceRecords(records []migrationRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.do("replace"); err != nil {
+		return err
+	}
+	f.records = make(map[string]migrationRecord, len(records))
+	for _, record := range records {
+		f.records[record.ID] = record
+	}
+	return nil
+}
+
+func (f *FakeBackend) createStepTable() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.steps == nil {
+		f.steps = make(map[string][]string)
+	}
+	return nil
+}
+
+func (f *FakeBackend) completedSteps(id string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.steps[id]...), nil
+}
+
+func (f *FakeBackend) insertStep(id, step string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.do("step " + id + " " + step); err != nil {
+		return err
+	}
+	f.steps[id] = append(f.steps[id], step)
+	return nil
+}
+
+func (f *FakeBackend) deleteSteps(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.steps, id)
+	return nil
+}
+
+func (f *FakeBackend) canaryRecords() ([]canaryRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records := make([]canaryRecord, 0, len(f.canary))
+	for _, record := range f.canary {
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (f *FakeBackend) writeCanaryRecords(records []canaryRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, record := range records {
+		if err := f.do("canary " + record.ID); err != nil {
+			return err
+		}
+		if f.canary == nil {
+			f.canary = make(map[string]canaryRecord)
+		}
+		f.canary[record.ID] = record
+	}
+	return nil
+}
+
+func (f *FakeBackend) dialect() string {
+	return f.Dialect
+}
+
+func (f *FakeBackend) dataSourceName() string {
+	return ""
+}
+
+func (f *FakeBackend) engine() *xorm.Engine {
+	return nil
+}
+
+// exec does nothing: the statements of SQL migrations are not executed.
+func (f *FakeBackend) exec(statement string) error {
+	return nil
+}
+
+func (f *FakeBackend) maintain(tables []string, vacuum bool, failed func(statement string, err error)) {
+}
+
+// lock emulates the migration lock: several instances sharing the backend
+// exclude each other.
+func (f *FakeBackend) lock(ctx context.Context, name string) (func() error, error) {
+	f.mu.Lock()
+	if f.locks == nil {
+		f.locks = make(map[string]chan struct{})
+	}
+	held, ok := f.locks[name]
+	if !ok {
+		held = make(chan struct{}, 1)
+		f.locks[name] = held
+	}
+	f.mu.Unlock()
+
+	select {
+	case held <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.do("lock"); err != nil {
+		<-held
+		return nil, err
+	}
+	return func() error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.ops = append(f.ops, "unlock")
+		<-held
+		return nil
+	}, nil
+}
+
+func (f *FakeBackend) begin() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.ops = append(f.ops, "begin")
+	f.saved = make(map[string]migrationRecord, len(f.records))
+	for id, record := range f.records {
+		f.saved[id] = record
+	}
+	f.savedSteps = make(map[string][]string, len(f.steps))
+	for id, steps := range f.steps {
+		f.savedSteps[id] = steps
+	}
+	f.savedCanary = make(map[string]canaryRecord, len(f.canary))
+	for id, record := range f.canary {
+		f.savedCanary[id] = record
+	}
+	f.inTx = true
+}
+
+func (f *FakeBackend) commit() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.do("commit"); err != nil {
+		return err
+	}
+	f.saved, f.savedSteps, f.savedCanary, f.inTx = nil, nil, nil, false
+	return nil
+}
+
+func (f *FakeBackend) rollback() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.inTx {
+		return
+	}
+	f.ops = append(f.ops, "rollback")
+	if f.records != nil {
+		f.records = f.saved
+	}
+	if f.steps != nil {
+		f.steps = f.savedSteps
+	}
+	if f.canary != nil {
+		f.canary = f.savedCanary
+	}
+	f.saved, f.savedSteps, f.savedCanary, f.inTx = nil, nil, nil, false
+}
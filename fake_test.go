@@ -0,0 +1,58 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestFakeBackend(t *testing.T) {
+	var ran []string
+	stub := func(id string, err error) *Migration {
+		return &Migration{
+			ID: id,
+			Migrate: func(tx *xorm.Session) error {
+				ran = append(ran, id)
+				return err
+			},
+			Rollback: func(tx *xorm.Session) error {
+				return nil
+			},
+		}
+	}
+
+	backend := &FakeBackend{Dialect: "postgres"}
+	m := NewFake(backend, &Options{UseTransaction: true}, []*Migration{
+		stub("1", nil),
+		{ID: "2", Dialects: []string{"mysql"}},
+		stub("3", errors.New("failed")),
+	})
+	assert.EqualError(t, m.Migrate(), "xormigrate: Migration 3 failed during migrate: failed")
+	assert.Equal(t, []string{"1", "3"}, ran)
+	assert.Empty(t, backend.Applied())
+	assert.Equal(t, []string{"begin", "create table", "insert 1", "insert 2", "rollback"}, backend.Ops())
+
+	assert.NoError(t, m.MigrateTo("2"))
+	assert.Equal(t, []string{"1", "2"}, backend.Applied())
+	assert.NoError(t, m.RollbackLast())
+	assert.Equal(t, []string{"1"}, backend.Applied())
+}
+
+func TestFakeBackendFail(t *testing.T) {
+	backend := &FakeBackend{
+		Fail: func(op string) error {
+			if op == "insert 201608301400" {
+				return errors.New("disk full")
+			}
+			return nil
+		},
+	}
+	m := NewFake(backend, &Options{}, []*Migration{{
+		ID:      "201608301400",
+		Migrate: func(tx *xorm.Session) error { return nil },
+	}})
+	assert.EqualError(t, m.Migrate(), "xormigrate: Migration 201608301400 failed during record: disk full")
+	assert.Empty(t, backend.Applied())
+}
@@ -0,0 +1,55 @@
+package xormigrate
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// LoadFS loads the SQL migrations of a directory of fsys, e.g. an embed.FS.
+// Each migration is made of a "<id>_<description>.up.sql" file and an
+// optional "<id>_<description>.down.sql" file, whose statements are
+// separated by semicolons. Variants for a dialect are named
+// "<id>_<description>.<dialect>.up.sql", see Migration.UpSQLByDialect. A
+// "-- xormigrate:no-transaction" or "-- xormigrate:long-running" comment
+// before the first statement of an up file sets Migration.NoTransaction or
+// Migration.LongRunning. Repeatable migrations are named
+// "R__<description>.up.sql", which is also their ID, see
+// Migration.Repeatable.
+// Migrations are sorted by file name, other files and subdirectories are
+// ignored.
+func LoadFS(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []sqlFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sqlFile{name: entry.Name(), content: content})
+	}
+	return newSQLMigrations(files)
+}
+
+// FSSource is a Source loading the SQL migrations of a directory, see
+// LoadFS.
+type FSSource struct {
+	FS  fs.FS
+	Dir string
+}
+
+// Load reads the migration directory.
+func (s *FSSource) Load(ctx context.Context) ([]*Migration, error) {
+	return LoadFS(s.FS, s.Dir)
+}
+
+func (s *FSSource) String() string {
+	return s.Dir
+}
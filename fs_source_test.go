@@ -0,0 +1,63 @@
+package xormigrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+var sqlDir = fstest.MapFS{
+	"migrations/201608301400_create_person.up.sql":   {Data: []byte("CREATE TABLE person (id INTEGER, name VARCHAR(255));\nCREATE INDEX idx_person_name ON person (name);")},
+	"migrations/201608301400_create_person.down.sql": {Data: []byte("DROP TABLE person;")},
+	"migrations/201608301430_create_pet.up.sql":      {Data: []byte("CREATE TABLE pet (name VARCHAR(255), person_id INTEGER);")},
+	"migrations/README.md":                           {Data: []byte("Migrations")},
+	"migrations/old/201501010000_init.up.sql":        {Data: []byte("SELECT 1;")},
+}
+
+func TestLoadFS(t *testing.T) {
+	loaded, err := LoadFS(sqlDir, "migrations")
+	assert.NoError(t, err)
+	if assert.Len(t, loaded, 2) {
+		assert.Equal(t, "201608301400", loaded[0].ID)
+		assert.Equal(t, "create person", loaded[0].Description)
+		assert.Equal(t, "DROP TABLE person;", loaded[0].DownSQL)
+		assert.Equal(t, "201608301430", loaded[1].ID)
+		assert.Empty(t, loaded[1].DownSQL)
+	}
+
+	_, err = LoadFS(fstest.MapFS{"migrations/201608301400_create_person.down.sql": {}}, "migrations")
+	assert.EqualError(t, err, `xormigrate: Missing up SQL file for migration "201608301400"`)
+
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, loaded)
+		assert.NoError(t, m.Migrate())
+		for _, table := range []string{"person", "pet"} {
+			has, err := db.IsTableExist(table)
+			assert.NoError(t, err)
+			assert.True(t, has)
+		}
+		has, err := HasIndex(db.NewSession(), "person", "idx_person_name")
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.Equal(t, ErrRollbackImpossible, m.RollbackLast())
+	})
+}
+
+func TestLoadFSDirectives(t *testing.T) {
+	loaded, err := LoadFS(fstest.MapFS{
+		"migrations/201608301400_create_person.up.sql":            {Data: []byte("-- Persons.\n-- xormigrate:no-transaction\nCREATE TABLE person (id INTEGER);\n-- xormigrate:long-running\n")},
+		"migrations/201608301430_backfill_person.postgres.up.sql": {Data: []byte("\n-- xormigrate:long-running\nUPDATE person SET id = 1;")},
+	}, "migrations")
+	assert.NoError(t, err)
+	if assert.Len(t, loaded, 2) {
+		assert.True(t, loaded[0].NoTransaction)
+		// Only the comments before the first statement are directives.
+		assert.False(t, loaded[0].LongRunning)
+		assert.True(t, loaded[1].LongRunning)
+	}
+
+	_, err = LoadFS(fstest.MapFS{"migrations/201608301400_create_person.up.sql": {Data: []byte("-- xormigrate:no-transactions\nSELECT 1;")}}, "migrations")
+	assert.EqualError(t, err, `xormigrate: Unknown directive "no-transactions" in SQL migration file "201608301400_create_person.up.sql"`)
+}
@@ -0,0 +1,138 @@
+package xormigrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ServiceMigration is a migration of another service, see
+// Migration.Requires.
+type ServiceMigration struct {
+	Service string
+	ID      string
+}
+
+// Gate reports whether a migration of another service was applied, e.g. by
+// querying the status endpoint of the service, see HTTPGate.
+type Gate interface {
+	Applied(ctx context.Context, service, migrationID string) (bool, error)
+}
+
+// GateFunc adapts a function to the Gate interface.
+type GateFunc func(ctx context.Context, service, migrationID string) (bool, error)
+
+// Applied calls f(ctx, service, migrationID).
+func (f GateFunc) Applied(ctx context.Context, service, migrationID string) (bool, error) {
+	return f(ctx, service, migrationID)
+}
+
+// ErrNoGate is returned when a migration requires migrations of other
+// services without Options.Gate
+var ErrNoGate = errors.New("xormigrate: No gate to check the migrations of other services")
+
+// GateError is returned when a migration of another service required by a
+// migration is still not applied once Options.GateWait is over.
+type GateError struct {
+	ID       string
+	Requires ServiceMigration
+}
+
+func (e *GateError) Error() string {
+	return fmt.Sprintf(`xormigrate: Migration "%s" requires migration "%s" of service "%s", which is not applied`, e.ID, e.Requires.ID, e.Requires.Service)
+}
+
+// checkGates waits for the migrations of other services required by m.
+func (x *Xormigrate) checkGates(m *Migration) error {
+	if len(m.Requires) == 0 {
+		return nil
+	}
+	gate := x.options.Gate
+	if gate == nil {
+		return ErrNoGate
+	}
+	interval := x.options.GatePollInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	deadline := time.Now().Add(x.options.GateWait)
+	for _, required := range m.Requires {
+		for {
+			applied, err := gate.Applied(x.context(), required.Service, required.ID)
+			if err != nil {
+				return err
+			}
+			if applied {
+				break
+			}
+			if time.Now().Add(interval).After(deadline) {
+				return &GateError{ID: m.ID, Requires: required}
+			}
+			if err := x.sleep(interval); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// HTTPGate is a Gate querying the status endpoints of the services, which
+// serve the IDs of their applied migrations as a JSON array, see
+// AppliedHandler.
+type HTTPGate struct {
+	// Endpoints are the URLs of the status endpoints, by service.
+	Endpoints map[string]string
+	// Client is used to send the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Applied fetches the applied migrations of the service.
+func (g *HTTPGate) Applied(ctx context.Context, service, migrationID string) (bool, error) {
+	endpoint, ok := g.Endpoints[service]
+	if !ok {
+		return false, fmt.Errorf(`xormigrate: No status endpoint for service "%s"`, service)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf(`xormigrate: Fetching "%s": %s`, req.URL, resp.Status)
+	}
+	var ids []string
+	if err := json.NewDecoder(resp.Body).Decode(&ids); err != nil {
+		return false, fmt.Errorf(`xormigrate: Decoding "%s": %w`, req.URL, err)
+	}
+	return contains(ids, migrationID), nil
+}
+
+// AppliedHandler returns a handler serving the IDs of the applied
+// migrations, see AppliedIDs, as a JSON array, for the HTTPGate of other
+// services.
+func (x *Xormigrate) AppliedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids, err := x.AppliedIDs()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if ids == nil {
+			ids = []string{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ids)
+	})
+}
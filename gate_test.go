@@ -0,0 +1,68 @@
+package xormigrate
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func gatedMigrations() []*Migration {
+	migrations := targetMigrations()
+	migrations[1].Requires = []ServiceMigration{{Service: "billing", ID: "42"}}
+	return migrations
+}
+
+func TestGate(t *testing.T) {
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{}, gatedMigrations())
+	assert.Equal(t, ErrNoGate, m.Migrate())
+
+	checks := 0
+	gate := GateFunc(func(ctx context.Context, service, migrationID string) (bool, error) {
+		assert.Equal(t, "billing", service)
+		assert.Equal(t, "42", migrationID)
+		checks++
+		return checks == 3, nil
+	})
+	m = NewFake(backend, &Options{Gate: gate}, gatedMigrations())
+	err := m.Migrate()
+	var gateErr *GateError
+	if assert.True(t, errors.As(err, &gateErr)) {
+		assert.Equal(t, &GateError{ID: "201608301430", Requires: ServiceMigration{Service: "billing", ID: "42"}}, gateErr)
+	}
+	assert.Equal(t, []string{"201608301400"}, backend.Applied())
+
+	m = NewFake(backend, &Options{
+		Gate:             gate,
+		GateWait:         time.Second,
+		GatePollInterval: time.Millisecond,
+	}, gatedMigrations())
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, 3, checks)
+	assert.Len(t, backend.Applied(), 4)
+}
+
+func TestHTTPGate(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		billing := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+		server := httptest.NewServer(billing.AppliedHandler())
+		defer server.Close()
+
+		gate := &HTTPGate{Endpoints: map[string]string{"billing": server.URL}}
+		applied, err := gate.Applied(context.Background(), "billing", "201608301400")
+		assert.NoError(t, err)
+		assert.False(t, applied)
+
+		assert.NoError(t, billing.MigrateTo("201608301400"))
+		applied, err = gate.Applied(context.Background(), "billing", "201608301400")
+		assert.NoError(t, err)
+		assert.True(t, applied)
+		_, err = gate.Applied(context.Background(), "shipping", "201608301400")
+		assert.Error(t, err)
+	})
+}
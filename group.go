@@ -0,0 +1,87 @@
+package xormigrate
+
+import (
+	"fmt"
+	"time"
+)
+
+// DependentError is returned when rolling back a migration that an applied
+// migration depends on.
+type DependentError struct {
+	ID        string
+	Dependent string
+}
+
+func (e *DependentError) Error() string {
+	return fmt.Sprintf(`xormigrate: Can't roll back "%s", applied migration "%s" depends on it`, e.ID, e.Dependent)
+}
+
+// RollbackGroup rolls back, in reverse order, the applied migrations of the
+// group, wherever they are interleaved with the migrations of other groups.
+// A *DependentError is returned, before rolling back anything, if an applied
+// migration outside the group depends on one of them.
+func (x *Xormigrate) RollbackGroup(group string) error {
+	_, err := x.RollbackGroupResult(group)
+	return err
+}
+
+// RollbackGroupResult is like RollbackGroup, also returning the result of the run.
+func (x *Xormigrate) RollbackGroupResult(group string) (*RunResult, error) {
+	return x.withResult(nil, func() error {
+		return x.rollbackGroup(group)
+	})
+}
+
+func (x *Xormigrate) rollbackGroup(group string) (err error) {
+	if err := x.checkWritable(); err != nil {
+		return err
+	}
+	if err := x.checkProtection("RollbackGroup"); err != nil {
+		return err
+	}
+	inGroup := make(map[string]bool)
+	for _, migration := range x.migrations {
+		if migration.Group == group {
+			inGroup[migration.ID] = true
+		}
+	}
+	if len(inGroup) == 0 {
+		return ErrUnknownGroup
+	}
+	x.emitRunStarted(true)
+	defer x.emitRunFinished(true, time.Now(), &err)
+
+	defer x.end()
+	if err := x.begin(); err != nil {
+		return err
+	}
+
+	var applied []*Migration
+	for _, migration := range x.migrations {
+		if migration.Repeatable {
+			continue
+		}
+		migrationRan, err := x.migrationRan(migration)
+		if err != nil {
+			return err
+		}
+		if !migrationRan {
+			continue
+		}
+		if inGroup[migration.ID] {
+			applied = append(applied, migration)
+			continue
+		}
+		for _, id := range migration.DependsOn {
+			if inGroup[id] {
+				return &DependentError{ID: id, Dependent: migration.ID}
+			}
+		}
+	}
+	for i := len(applied) - 1; i >= 0; i-- {
+		if err := x.rollbackMigration(applied[i]); err != nil {
+			return err
+		}
+	}
+	return x.finish()
+}
@@ -0,0 +1,44 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestRollbackGroup(t *testing.T) {
+	var rolledBack []string
+	stub := func(id, group string, dependsOn ...string) *Migration {
+		return &Migration{
+			ID:        id,
+			Group:     group,
+			DependsOn: dependsOn,
+			Migrate:   func(tx *xorm.Session) error { return nil },
+			Rollback: func(tx *xorm.Session) error {
+				rolledBack = append(rolledBack, id)
+				return nil
+			},
+		}
+	}
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{}, []*Migration{
+		stub("1", "core"),
+		stub("2", "wishlist"),
+		stub("3", "core"),
+		stub("4", "wishlist", "2"),
+		stub("5", "reviews", "1"),
+	})
+	assert.NoError(t, m.Migrate())
+
+	assert.Equal(t, ErrUnknownGroup, m.RollbackGroup("billing"))
+	assert.NoError(t, m.RollbackGroup("wishlist"))
+	assert.Equal(t, []string{"4", "2"}, rolledBack)
+	assert.Equal(t, []string{"1", "3", "5"}, backend.Applied())
+
+	var dependentErr *DependentError
+	assert.True(t, errors.As(m.RollbackGroup("core"), &dependentErr))
+	assert.Equal(t, &DependentError{ID: "1", Dependent: "5"}, dependentErr)
+	assert.Equal(t, []string{"1", "3", "5"}, backend.Applied())
+}
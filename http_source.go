@@ -0,0 +1,134 @@
+package xormigrate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// HTTPSource loads SQL migrations published over HTTP(S), e.g. by a web
+// server or an S3 bucket, so schema changes can be distributed separately
+// from the application binary.
+//
+// BaseURL must serve a "manifest.sum" file listing the migration files in
+// order, each with its SHA-256 checksum, in the format of sha256sum:
+//
+//	2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae  201608301400_create_person.up.sql
+//	fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9  201608301400_create_person.down.sql
+//
+// Every file is downloaded from BaseURL and verified against its checksum.
+// When SigningKey is set, the manifest must also be signed: BaseURL must
+// serve a "manifest.sum.sig" file holding the signature of the manifest, as
+// returned by Sign. As the manifest holds the checksums of all files, this
+// prevents a compromised server from injecting schema changes. The Go
+// migrations of the binary are not signed, see Sign.
+type HTTPSource struct {
+	// BaseURL is the URL of the directory holding the manifest and the files.
+	BaseURL string
+	// Client is used to send the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+	// SigningKey is the HMAC key the manifest is signed with. Can be nil,
+	// but not empty, see ErrEmptySigningKey.
+	SigningKey []byte
+}
+
+// ChecksumMismatchError is returned when the content of a migration file
+// doesn't match the checksum it was published with
+type ChecksumMismatchError struct {
+	File string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf(`xormigrate: Checksum mismatch for "%s"`, e.File)
+}
+
+// Load downloads the manifest and the migration files.
+func (s *HTTPSource) Load(ctx context.Context) ([]*Migration, error) {
+	if s.SigningKey != nil && len(s.SigningKey) == 0 {
+		return nil, ErrEmptySigningKey
+	}
+	manifest, err := s.get(ctx, "manifest.sum")
+	if err != nil {
+		return nil, err
+	}
+	if s.SigningKey != nil {
+		signature, err := s.get(ctx, "manifest.sum.sig")
+		if err != nil {
+			return nil, err
+		}
+		if !verifySignature(s.SigningKey, manifest, string(signature)) {
+			return nil, ErrInvalidSignature
+		}
+	}
+	entries, err := parseManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]sqlFile, 0, len(entries))
+	for _, entry := range entries {
+		content, err := s.get(ctx, entry.name)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+		if hex.EncodeToString(sum[:]) != entry.checksum {
+			return nil, &ChecksumMismatchError{File: entry.name}
+		}
+		files = append(files, sqlFile{name: entry.name, content: content})
+	}
+	return newSQLMigrations(files)
+}
+
+func (s *HTTPSource) String() string {
+	return s.BaseURL
+}
+
+func (s *HTTPSource) get(ctx context.Context, name string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(s.BaseURL, "/")+"/"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(`xormigrate: Fetching "%s": %s`, req.URL, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+type manifestEntry struct {
+	checksum string
+	name     string
+}
+
+// parseManifest parses the lines "<sha256 hex>  <file name>" of a manifest.
+func parseManifest(manifest []byte) ([]manifestEntry, error) {
+	var entries []manifestEntry
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len(fields[0]) != sha256.Size*2 {
+			return nil, fmt.Errorf(`xormigrate: Invalid manifest line "%s"`, line)
+		}
+		entries = append(entries, manifestEntry{checksum: strings.ToLower(fields[0]), name: fields[1]})
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,90 @@
+package xormigrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func newBundleServer(files [][2]string, tamper string, key []byte) *httptest.Server {
+	mux := http.NewServeMux()
+	var manifest string
+	for _, file := range files {
+		name, content := file[0], file[1]
+		sum := sha256.Sum256([]byte(content))
+		manifest += fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), name)
+		if name == tamper {
+			content += "DROP TABLE person;"
+		}
+		mux.HandleFunc("/bundle/"+name, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, content)
+		})
+	}
+	mux.HandleFunc("/bundle/manifest.sum", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, manifest)
+	})
+	mux.HandleFunc("/bundle/manifest.sum.sig", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, Sign(key, []byte(manifest)))
+	})
+	return httptest.NewServer(mux)
+}
+
+var bundleFiles = [][2]string{
+	{"201608301400_create_person.up.sql", "CREATE TABLE person (id INTEGER, name VARCHAR(255));"},
+	{"201608301400_create_person.down.sql", "DROP TABLE person;"},
+	{"201608301430_create_pet.up.sql", "CREATE TABLE pet (name VARCHAR(255), person_id INTEGER);"},
+}
+
+func TestHTTPSource(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		server := newBundleServer(bundleFiles, "", nil)
+		defer server.Close()
+
+		m, err := NewFromSources(context.Background(), db.NewSession(), &Options{
+			TableName: "migration",
+		}, &HTTPSource{BaseURL: server.URL + "/bundle"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, m.Migrate())
+		has, _ := db.IsTableExist(&Person{})
+		assert.True(t, has)
+		has, _ = db.IsTableExist(&Pet{})
+		assert.True(t, has)
+		assert.Equal(t, int64(2), tableCount(t, db))
+	})
+}
+
+func TestHTTPSourceChecksumMismatch(t *testing.T) {
+	server := newBundleServer(bundleFiles, "201608301430_create_pet.up.sql", nil)
+	defer server.Close()
+
+	_, err := (&HTTPSource{BaseURL: server.URL + "/bundle"}).Load(context.Background())
+	var mismatchError *ChecksumMismatchError
+	if assert.True(t, errors.As(err, &mismatchError)) {
+		assert.Equal(t, "201608301430_create_pet.up.sql", mismatchError.File)
+	}
+}
+
+func TestHTTPSourceSignature(t *testing.T) {
+	server := newBundleServer(bundleFiles, "", []byte("secret"))
+	defer server.Close()
+
+	loaded, err := (&HTTPSource{BaseURL: server.URL + "/bundle", SigningKey: []byte("secret")}).Load(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, loaded, 2)
+
+	_, err = (&HTTPSource{BaseURL: server.URL + "/bundle", SigningKey: []byte("other")}).Load(context.Background())
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	_, err = (&HTTPSource{BaseURL: server.URL + "/bundle", SigningKey: []byte{}}).Load(context.Background())
+	assert.Equal(t, ErrEmptySigningKey, err)
+}
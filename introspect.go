@@ -0,0 +1,99 @@
+package xormigrate
+
+import (
+	"fmt"
+	"regexp"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// The Has functions check the database catalog for a schema object. They run
+// on the session, so that objects created by its transaction are seen, which
+// makes them usable inside migrations to skip work already done.
+
+// HasTable reports whether the table exists.
+func HasTable(tx *xorm.Session, table string) (bool, error) {
+	return tx.IsTableExist(table)
+}
+
+// HasColumn reports whether table has the column.
+func HasColumn(tx *xorm.Session, table, column string) (bool, error) {
+	var query string
+	switch tx.Engine().Dialect().URI().DBType {
+	case schemas.POSTGRES:
+		query = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?"
+	case schemas.MYSQL:
+		query = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?"
+	case schemas.MSSQL:
+		query = "SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = SCHEMA_NAME() AND TABLE_NAME = ? AND COLUMN_NAME = ?"
+	case schemas.SQLITE:
+		query = "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?"
+	default:
+		return false, unsupportedDatabase(tx)
+	}
+	return catalogCount(tx, query, table, column)
+}
+
+// HasIndex reports whether table has the index, given its name in the
+// database, e.g. "IDX_person_name" for an index created by xorm.
+func HasIndex(tx *xorm.Session, table, index string) (bool, error) {
+	var query string
+	switch tx.Engine().Dialect().URI().DBType {
+	case schemas.POSTGRES:
+		query = "SELECT COUNT(*) FROM pg_indexes WHERE schemaname = current_schema() AND tablename = ? AND indexname = ?"
+	case schemas.MYSQL:
+		query = "SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?"
+	case schemas.MSSQL:
+		query = "SELECT COUNT(*) FROM sys.indexes WHERE object_id = OBJECT_ID(?) AND name = ?"
+	case schemas.SQLITE:
+		query = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND name = ?"
+	default:
+		return false, unsupportedDatabase(tx)
+	}
+	return catalogCount(tx, query, table, index)
+}
+
+// HasConstraint reports whether table has the named constraint: primary key,
+// foreign key, unique or check. SQLite has no constraint catalog, so the
+// CREATE TABLE statement of the table is searched for the constraint name.
+func HasConstraint(tx *xorm.Session, table, constraint string) (bool, error) {
+	var query string
+	switch tx.Engine().Dialect().URI().DBType {
+	case schemas.POSTGRES:
+		query = "SELECT COUNT(*) FROM information_schema.table_constraints WHERE table_schema = current_schema() AND table_name = ? AND constraint_name = ?"
+	case schemas.MYSQL:
+		query = "SELECT COUNT(*) FROM information_schema.table_constraints WHERE table_schema = DATABASE() AND table_name = ? AND constraint_name = ?"
+	case schemas.MSSQL:
+		query = "SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS WHERE TABLE_SCHEMA = SCHEMA_NAME() AND TABLE_NAME = ? AND CONSTRAINT_NAME = ?"
+	case schemas.SQLITE:
+		rows, err := tx.QueryString("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table)
+		if err != nil || len(rows) == 0 {
+			return false, err
+		}
+		pattern := `(?i)\bCONSTRAINT\s+["` + "`" + `\[]?` + regexp.QuoteMeta(constraint) + `["` + "`" + `\]]?\s`
+		return regexp.MustCompile(pattern).MatchString(rows[0]["sql"]), nil
+	default:
+		return false, unsupportedDatabase(tx)
+	}
+	return catalogCount(tx, query, table, constraint)
+}
+
+// catalogCount runs a catalog query counting rows.
+func catalogCount(tx *xorm.Session, query string, args ...interface{}) (bool, error) {
+	rows, err := tx.QueryString(append([]interface{}{query}, args...)...)
+	if err != nil {
+		return false, err
+	}
+	if len(rows) == 0 {
+		return false, nil
+	}
+	for _, value := range rows[0] {
+		return value != "0", nil
+	}
+	return false, nil
+}
+
+func unsupportedDatabase(tx *xorm.Session) error {
+	return fmt.Errorf("xormigrate: Unsupported database %s", tx.Engine().Dialect().URI().DBType)
+}
@@ -0,0 +1,50 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestIntrospection(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		tx := db.NewSession()
+		defer tx.Close()
+
+		exists, err := HasTable(tx, "book")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+		exists, err = HasColumn(tx, "book", "name")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+		exists, err = HasConstraint(tx, "book", "uqe_book_name")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+
+		_, err = tx.Exec("CREATE TABLE book (id INTEGER PRIMARY KEY, name VARCHAR(255), CONSTRAINT uqe_book_name UNIQUE (name))")
+		assert.NoError(t, err)
+		_, err = tx.Exec("CREATE INDEX idx_book_id_name ON book (id, name)")
+		assert.NoError(t, err)
+
+		for _, check := range []struct {
+			has    func(tx *xorm.Session, table, name string) (bool, error)
+			name   string
+			exists bool
+		}{
+			{HasColumn, "name", true},
+			{HasColumn, "author", false},
+			{HasIndex, "idx_book_id_name", true},
+			{HasIndex, "idx_book_author", false},
+			{HasConstraint, "uqe_book_name", true},
+			{HasConstraint, "uqe_book", false},
+		} {
+			exists, err := check.has(tx, "book", check.name)
+			assert.NoError(t, err)
+			assert.Equal(t, check.exists, exists, check.name)
+		}
+		exists, err = HasTable(tx, "book")
+		assert.NoError(t, err)
+		assert.True(t, exists)
+	})
+}
@@ -0,0 +1,213 @@
+package xormigrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"xorm.io/xorm/schemas"
+)
+
+// LockTimeoutError is returned when another run still holds the migration
+// lock once Options.LockTimeout is over.
+type LockTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *LockTimeoutError) Error() string {
+	return fmt.Sprintf("xormigrate: Another run held the migration lock for more than %s", e.Timeout)
+}
+
+// lockRecord is the row locked by the runs on the databases without advisory
+// locks, in the "<TableName>_lock" table. Host and RunID identify the run
+// holding the lock: they are written in the locking transaction, readable
+// with READ UNCOMMITTED or NOLOCK, and cleared by its rollback on release.
+type lockRecord struct {
+	ID    int64  `xorm:"pk 'id'"`
+	Host  string `xorm:"VARCHAR(255) 'host'"`
+	RunID string `xorm:"VARCHAR(32) 'run_id'"`
+}
+
+// lockName is the name of the migration lock: runs using different migration
+// tables don't exclude each other.
+func (x *Xormigrate) lockName() string {
+	return "xormigrate:" + x.options.TableName
+}
+
+// lock acquires the migration lock when Options.UseLock is set or in RunOnce,
+// waiting at most Options.LockTimeout. It is released by unlock.
+func (x *Xormigrate) lock() error {
+	if !x.options.UseLock && !x.forceLock {
+		return nil
+	}
+	ctx := x.context()
+	if timeout := x.options.LockTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	release, err := x.backend.lock(ctx, x.lockName())
+	if err != nil {
+		// The deadline of the lock, not the one of the call.
+		timedOut := errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded)
+		if timedOut && x.context().Err() == nil {
+			return &LockTimeoutError{Timeout: x.options.LockTimeout}
+		}
+		return err
+	}
+	x.release = release
+	return nil
+}
+
+// unlock releases the migration lock, if held.
+func (x *Xormigrate) unlock() {
+	if x.release == nil {
+		return
+	}
+	if err := x.release(); err != nil {
+		x.warn("", "Could not release the migration lock: %v", err)
+	}
+	x.release = nil
+}
+
+// lock holds the lock on a dedicated connection, as advisory locks belong to
+// the connection that acquired them.
+func (b *sessionBackend) lock(ctx context.Context, name string) (func() error, error) {
+	engine := b.x.session.Engine()
+	dbType := engine.Dialect().URI().DBType
+	if dbType == schemas.SQLITE {
+		// Writers are serialized by the lock of the database file, and a
+		// transaction held on another connection would block the run.
+		return func() error { return nil }, nil
+	}
+	var holder string
+	if dbType != schemas.POSTGRES && dbType != schemas.MYSQL {
+		if err := b.createLockRow(); err != nil {
+			return nil, err
+		}
+		host, err := b.x.options.HostResolver()
+		if err != nil {
+			return nil, err
+		}
+		holder = fmt.Sprintf("UPDATE %s SET %s = %s, %s = %s WHERE id = 1", engine.Quote(b.lockTableName()),
+			engine.Quote("host"), b.x.sqlLiteral(host), engine.Quote("run_id"), b.x.sqlLiteral(b.x.runID))
+	}
+
+	conn, err := engine.DB().DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var release func() error
+	switch dbType {
+	case schemas.POSTGRES:
+		release, err = postgresLock(ctx, conn, name)
+	case schemas.MYSQL:
+		release, err = mysqlLock(ctx, conn, name)
+	default:
+		release, err = rowLock(ctx, conn, engine.Quote(b.lockTableName()), dbType, holder)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return func() error {
+		err := release()
+		if closeErr := conn.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+func (b *sessionBackend) lockTableName() string {
+	return b.x.options.TableName + "_lock"
+}
+
+// createLockRow creates the lock table and its single row, if needed.
+func (b *sessionBackend) createLockRow() error {
+	session := b.x.session.Engine().NewSession()
+	defer session.Close()
+
+	if err := session.Table(b.lockTableName()).Sync2(&lockRecord{}); err != nil && !isAlreadyExists(err) {
+		return err
+	}
+	exists, err := session.Table(b.lockTableName()).ID(1).Exist(&lockRecord{})
+	if err != nil || exists {
+		return err
+	}
+	if _, err := session.Table(b.lockTableName()).Insert(&lockRecord{ID: 1}); err != nil {
+		// Another run may have inserted it meanwhile.
+		if exists, _ := session.Table(b.lockTableName()).ID(1).Exist(&lockRecord{}); !exists {
+			return err
+		}
+	}
+	return nil
+}
+
+// postgresLock acquires an advisory lock, keyed by a hash of name.
+func postgresLock(ctx context.Context, conn *sql.Conn, name string) (func() error, error) {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	key := int64(h.Sum64())
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+		return err
+	}, nil
+}
+
+// mysqlLock acquires a named lock with GET_LOCK, which takes its timeout in
+// seconds and returns 0 once it is over.
+func mysqlLock(ctx context.Context, conn *sql.Conn, name string) (func() error, error) {
+	timeout := int64(-1)
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = int64(time.Until(deadline).Seconds())
+		if timeout < 0 {
+			timeout = 0
+		}
+	}
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, timeout).Scan(&acquired); err != nil {
+		return nil, err
+	}
+	if !acquired.Valid {
+		return nil, fmt.Errorf("xormigrate: GET_LOCK(%q) failed", name)
+	}
+	if acquired.Int64 != 1 {
+		return nil, context.DeadlineExceeded
+	}
+	return func() error {
+		_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name)
+		return err
+	}, nil
+}
+
+// rowLock locks the row of the lock table with SELECT ... FOR UPDATE in a
+// transaction kept open until the release, and executes holder in it to
+// record the run holding the lock.
+func rowLock(ctx context.Context, conn *sql.Conn, table string, dbType schemas.DBType, holder string) (func() error, error) {
+	query := "SELECT id FROM " + table + " WHERE id = 1 FOR UPDATE"
+	if dbType == schemas.MSSQL {
+		query = "SELECT id FROM " + table + " WITH (UPDLOCK, HOLDLOCK) WHERE id = 1"
+	}
+	// Not using ctx, which would roll the transaction back once done.
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var id int64
+	if err := tx.QueryRowContext(ctx, query).Scan(&id); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, holder); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx.Rollback, nil
+}
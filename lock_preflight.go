@@ -0,0 +1,145 @@
+package xormigrate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// LockHolder is a session holding locks on, or running a query against, a
+// table a migration is about to alter.
+type LockHolder struct {
+	PID   int64
+	Age   time.Duration
+	Query string
+}
+
+// LockPreflight checks, before running heavy DDL, that no long-running
+// session holds locks on the tables of the migration: an ALTER TABLE queued
+// behind such a session blocks every other query of the table.
+type LockPreflight struct {
+	// Tag selects the migrations checked. Defaults to "ddl".
+	Tag string
+	// MinAge ignores the sessions younger than it.
+	MinAge time.Duration
+	// Wait is how long to wait for the lock holders to finish before
+	// failing the run. Zero fails it immediately.
+	Wait time.Duration
+	// PollInterval is the delay between checks while waiting. Defaults to
+	// one second.
+	PollInterval time.Duration
+	// Holders lists the lock holders of a table. Defaults to LockHolders.
+	Holders func(tx *xorm.Session, table string, minAge time.Duration) ([]LockHolder, error)
+}
+
+// LockContentionError is returned when long-running sessions still hold
+// locks on a table of a migration once LockPreflight.Wait is over.
+type LockContentionError struct {
+	ID      string
+	Table   string
+	Holders []LockHolder
+}
+
+func (e *LockContentionError) Error() string {
+	pids := make([]string, len(e.Holders))
+	for i, holder := range e.Holders {
+		pids[i] = fmt.Sprint(holder.PID)
+	}
+	return fmt.Sprintf(`xormigrate: Migration "%s" would wait for locks on "%s" held by sessions %s`, e.ID, e.Table, strings.Join(pids, ", "))
+}
+
+// LockHolders returns the sessions older than minAge holding locks on table,
+// from pg_locks on PostgreSQL, or running a query mentioning it, from the
+// process list on MySQL. Other databases are not supported and report none.
+func LockHolders(tx *xorm.Session, table string, minAge time.Duration) ([]LockHolder, error) {
+	var query string
+	var args []interface{}
+	switch tx.Engine().Dialect().URI().DBType {
+	case schemas.POSTGRES:
+		query = `SELECT DISTINCT a.pid AS pid, EXTRACT(EPOCH FROM now() - a.xact_start) AS age, COALESCE(a.query, '') AS query
+			FROM pg_locks l JOIN pg_stat_activity a ON a.pid = l.pid
+			WHERE l.relation = to_regclass(?) AND a.pid <> pg_backend_pid()
+			AND a.xact_start < now() - make_interval(secs => ?)`
+		args = []interface{}{table, minAge.Seconds()}
+	case schemas.MYSQL:
+		query = `SELECT ID AS pid, TIME AS age, COALESCE(INFO, '') AS query FROM information_schema.PROCESSLIST
+			WHERE ID <> CONNECTION_ID() AND COMMAND <> 'Sleep' AND TIME >= ? AND INFO LIKE ?`
+		args = []interface{}{int64(minAge.Seconds()), "%" + table + "%"}
+	default:
+		return nil, nil
+	}
+	// Using the session, so that its own locks are excluded.
+	rows, err := tx.QueryString(append([]interface{}{query}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	holders := make([]LockHolder, 0, len(rows))
+	for _, row := range rows {
+		pid, err := strconv.ParseInt(row["pid"], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		seconds, err := strconv.ParseFloat(row["age"], 64)
+		if err != nil {
+			return nil, err
+		}
+		holders = append(holders, LockHolder{
+			PID:   pid,
+			Age:   time.Duration(seconds * float64(time.Second)),
+			Query: row["query"],
+		})
+	}
+	return holders, nil
+}
+
+// checkLocks runs Options.LockPreflight for the migration, if it has the
+// tag, on the tables listed by Migration.Tables or inferred from its SQL.
+func (x *Xormigrate) checkLocks(m *Migration) error {
+	preflight := x.options.LockPreflight
+	if preflight == nil {
+		return nil
+	}
+	tag := preflight.Tag
+	if tag == "" {
+		tag = "ddl"
+	}
+	if !contains(m.Tags, tag) {
+		return nil
+	}
+	holders := preflight.Holders
+	if holders == nil {
+		holders = LockHolders
+	}
+	interval := preflight.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	tables := m.Tables
+	if len(tables) == 0 {
+		tables = touchedTables([]*Migration{m})
+	}
+	deadline := time.Now().Add(preflight.Wait)
+	for _, table := range tables {
+		for {
+			found, err := holders(x.session, table, preflight.MinAge)
+			if err != nil {
+				return err
+			}
+			if len(found) == 0 {
+				break
+			}
+			if time.Now().Add(interval).After(deadline) {
+				return &LockContentionError{ID: m.ID, Table: table, Holders: found}
+			}
+			if err := x.sleep(interval); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestLockPreflight(t *testing.T) {
+	var checked []string
+	busy := 2
+	preflight := &LockPreflight{
+		Wait:         time.Second,
+		PollInterval: time.Millisecond,
+		Holders: func(tx *xorm.Session, table string, minAge time.Duration) ([]LockHolder, error) {
+			checked = append(checked, table)
+			if table == "book" && busy > 0 {
+				busy--
+				return []LockHolder{{PID: 42, Age: time.Hour}}, nil
+			}
+			return nil, nil
+		},
+	}
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{LockPreflight: preflight}, []*Migration{
+		{ID: "1", Tags: []string{"ddl"}, UpSQL: "ALTER TABLE book ADD title VARCHAR(255); CREATE INDEX idx ON person (name);"},
+		{ID: "2", UpSQL: "ALTER TABLE book ADD isbn VARCHAR(13);"},
+		{ID: "3", Tags: []string{"ddl"}, Tables: []string{"pet"}, Migrate: func(tx *xorm.Session) error { return nil }},
+	})
+	assert.NoError(t, m.MigrateTo("3"))
+	assert.Equal(t, []string{"book", "book", "book", "person", "pet"}, checked)
+
+	busy = 100
+	preflight.Wait = 0
+	m = NewFake(&FakeBackend{}, &Options{LockPreflight: preflight}, []*Migration{
+		{ID: "1", Tags: []string{"ddl"}, Tables: []string{"book"}, Migrate: func(tx *xorm.Session) error { return nil }},
+	})
+	var contentionErr *LockContentionError
+	assert.True(t, errors.As(m.Migrate(), &contentionErr))
+	assert.Equal(t, `xormigrate: Migration "1" would wait for locks on "book" held by sessions 42`, contentionErr.Error())
+}
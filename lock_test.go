@@ -0,0 +1,79 @@
+package xormigrate
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestLock(t *testing.T) {
+	backend := &FakeBackend{Dialect: "postgres"}
+	started, done := make(chan struct{}), make(chan struct{})
+	migrations := []*Migration{{
+		ID: "201608301400",
+		Migrate: func(tx *xorm.Session) error {
+			close(started)
+			<-done
+			return nil
+		},
+	}}
+
+	first := NewFake(backend, &Options{UseLock: true}, migrations)
+	errs := make(chan error)
+	go func() { errs <- first.Migrate() }()
+	<-started
+
+	second := NewFake(backend, &Options{UseLock: true, LockTimeout: 20 * time.Millisecond}, migrations)
+	err := second.Migrate()
+	var lockErr *LockTimeoutError
+	if assert.True(t, errors.As(err, &lockErr)) {
+		assert.Equal(t, 20*time.Millisecond, lockErr.Timeout)
+	}
+
+	close(done)
+	assert.NoError(t, <-errs)
+	assert.NoError(t, second.Migrate())
+	assert.Equal(t, []string{"lock", "create table", "insert 201608301400", "unlock", "lock", "unlock"}, backend.Ops())
+}
+
+func TestLockConcurrentRuns(t *testing.T) {
+	backend := &FakeBackend{Dialect: "mysql"}
+	var mu sync.Mutex
+	runs := 0
+	migrations := []*Migration{{
+		ID: "201608301400",
+		Migrate: func(tx *xorm.Session) error {
+			mu.Lock()
+			defer mu.Unlock()
+			runs++
+			return nil
+		},
+	}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, NewFake(backend, &Options{UseLock: true}, migrations).Migrate())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, runs)
+}
+
+func TestLockDatabase(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		defer db.DropTables("migration_lock")
+
+		options := &Options{TableName: "migration", UseLock: true, LockTimeout: time.Second}
+		m := New(db.NewSession(), options, migrations)
+		assert.NoError(t, m.Migrate())
+		assert.NoError(t, m.RollbackLast())
+		assert.NoError(t, m.Migrate())
+	})
+}
@@ -0,0 +1,233 @@
+package xormigrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/contexts"
+)
+
+// Logger receives the messages logged by the runs, see Options.Logger.
+// The loggers of xorm, e.g. log.NewSimpleLogger, implement it.
+type Logger interface {
+	Debugf(format string, v ...interface{})
+	Infof(format string, v ...interface{})
+	Warnf(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+}
+
+// LogLevel is the minimum level of the messages logged, see Options.LogLevel.
+type LogLevel int
+
+// Log levels, from the most verbose.
+const (
+	LogDebug LogLevel = iota + 1
+	LogInfo
+	LogWarn
+	LogError
+)
+
+// ParseLogLevel parses "debug", "info", "warn" or "error".
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LogDebug, nil
+	case "info":
+		return LogInfo, nil
+	case "warn", "warning":
+		return LogWarn, nil
+	case "error":
+		return LogError, nil
+	}
+	return 0, fmt.Errorf(`xormigrate: Invalid log level "%s"`, s)
+}
+
+// logLevelFromEnv returns the level set by XORMIGRATE_LOG_LEVEL, or LogInfo
+// if it is unset or invalid.
+func logLevelFromEnv() LogLevel {
+	level, err := ParseLogLevel(os.Getenv("XORMIGRATE_LOG_LEVEL"))
+	if err != nil {
+		return LogInfo
+	}
+	return level
+}
+
+// levelLogger drops the messages below its level.
+type levelLogger struct {
+	Logger
+	level LogLevel
+}
+
+func (l *levelLogger) Debugf(format string, v ...interface{}) {
+	if l.level <= LogDebug {
+		l.Logger.Debugf(format, v...)
+	}
+}
+
+func (l *levelLogger) Infof(format string, v ...interface{}) {
+	if l.level <= LogInfo {
+		l.Logger.Infof(format, v...)
+	}
+}
+
+func (l *levelLogger) Warnf(format string, v ...interface{}) {
+	if l.level <= LogWarn {
+		l.Logger.Warnf(format, v...)
+	}
+}
+
+// logger returns Options.Logger, filtered according to Options.LogLevel.
+func (x *Xormigrate) logger() Logger {
+	return &levelLogger{Logger: x.options.Logger, level: x.options.LogLevel}
+}
+
+type logListener struct {
+	logger Logger
+	quiet  bool
+}
+
+// NewLogListener returns a listener logging runs and migrations to logger.
+// When quiet is set, only the start and the end of runs and errors are
+// logged.
+func NewLogListener(logger Logger, quiet bool) Listener {
+	return &logListener{logger: logger, quiet: quiet}
+}
+
+func (l *logListener) OnEvent(event Event) {
+	logger := l.logger
+	if e, ok := event.(interface{ info() *RunInfo }); ok {
+		logger = withRunID(logger, e.info().RunID)
+	}
+	switch e := event.(type) {
+	case *RunStarted:
+		logger.Infof("xormigrate: %s started", runName(e.Rollback))
+	case *MigrationStarting:
+		if !l.quiet {
+			logger.Debugf("xormigrate: Applying %d/%d: %s%s%s", e.Index, e.Total, e.ID, described(e.Description), estimate(e))
+		}
+	case *MigrationApplied:
+		if !l.quiet {
+			logger.Infof("xormigrate: Applied %s%s in %s", e.ID, described(e.Description), e.Duration)
+		}
+	case *StepApplied:
+		if !l.quiet {
+			logger.Infof("xormigrate: Applied step %s of %s in %s", e.Step, e.ID, e.Duration)
+		}
+	case *StatementExecuted:
+		if !l.quiet {
+			logger.Debugf("xormigrate: Executed statement %d/%d of %s in %s", e.Index, e.Total, e.ID, e.Duration)
+		}
+	case *MigrationSkipped:
+		if !l.quiet {
+			logger.Infof("xormigrate: Skipped %s: %s", e.ID, e.Reason)
+		}
+	case *MigrationRetrying:
+		logger.Warnf("xormigrate: Migration %s failed on attempt %d, retrying: %v", e.ID, e.Attempt, e.Err)
+	case *MigrationFailed:
+		logger.Errorf("xormigrate: Migration %s failed: %v", e.ID, e.Err)
+	case *BudgetExceeded:
+		logger.Warnf("xormigrate: Migration %s took %s, over its budget of %s", e.ID, e.Duration, e.Budget)
+	case *LongTransaction:
+		logger.Warnf("xormigrate: Run transaction open for %s, consider running without UseTransaction to commit each migration", e.Elapsed)
+	case *Warning:
+		logger.Warnf("xormigrate: %s", e.Message)
+	case *RolledBack:
+		if !l.quiet {
+			logger.Infof("xormigrate: Rolled back %s", e.ID)
+		}
+	case *RunFinished:
+		if e.Err != nil {
+			logger.Errorf("xormigrate: %s failed after %s: %v", runName(e.Rollback), e.Duration, e.Err)
+		} else {
+			logger.Infof("xormigrate: %s finished in %s", runName(e.Rollback), e.Duration)
+		}
+	}
+}
+
+// described formats the description of a migration, if any, to follow its
+// ID.
+func described(description string) string {
+	if description == "" {
+		return ""
+	}
+	return " - " + description
+}
+
+// estimate describes the historical durations of a MigrationStarting event.
+func estimate(e *MigrationStarting) string {
+	switch {
+	case e.Expected > 0:
+		return fmt.Sprintf(" (historically ~%s, ~%s left)", roughly(e.Expected), roughly(e.Remaining))
+	case e.Remaining > 0:
+		return fmt.Sprintf(" (~%s left)", roughly(e.Remaining))
+	}
+	return ""
+}
+
+// roughly rounds d for display.
+func roughly(d time.Duration) time.Duration {
+	if d >= time.Second {
+		return d.Round(time.Second)
+	}
+	return d.Round(time.Millisecond)
+}
+
+func runName(rollback bool) string {
+	if rollback {
+		return "Rollback"
+	}
+	return "Migration"
+}
+
+// echoSQLKey is the context key marking the sessions of the runs echoing
+// their statements, its value is the *Xormigrate running on the session.
+type echoSQLKey struct{}
+
+// echoHooks holds the engines the echo hook was added to.
+var echoHooks sync.Map
+
+// echoSQL returns ctx marked so that the statements of the sessions using it
+// are logged to Options.Logger. As xorm hooks are global to an engine, the
+// hook is added once per engine and only logs the statements of marked
+// sessions.
+func (x *Xormigrate) echoSQL(ctx context.Context, session *xorm.Session) context.Context {
+	engine := session.Engine()
+	if _, loaded := echoHooks.LoadOrStore(engine, true); !loaded {
+		engine.AddHook(echoHook{})
+	}
+	return context.WithValue(ctx, echoSQLKey{}, x)
+}
+
+func (x *Xormigrate) echoesSQL() bool {
+	return x.options.Logger != nil && (x.options.EchoSQL || x.options.LogLevel == LogDebug)
+}
+
+type echoHook struct{}
+
+func (echoHook) BeforeProcess(c *contexts.ContextHook) (context.Context, error) {
+	return c.Ctx, nil
+}
+
+func (echoHook) AfterProcess(c *contexts.ContextHook) error {
+	x, ok := c.Ctx.Value(echoSQLKey{}).(*Xormigrate)
+	if !ok {
+		return nil
+	}
+	logger := withRunID(x.logger(), x.runID)
+	logf := logger.Debugf
+	if x.options.EchoSQL {
+		logf = logger.Infof
+	}
+	// The statements of SQL migrations hold the secrets they reference.
+	if len(c.Args) > 0 {
+		logf("xormigrate: [SQL] %s %s - %s", x.redact(c.SQL), x.redact(fmt.Sprint(c.Args)), c.ExecuteTime)
+	} else {
+		logf("xormigrate: [SQL] %s - %s", x.redact(c.SQL), c.ExecuteTime)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package xormigrate
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) logf(level, format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.messages = append(l.messages, level+" "+fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLogger) Debugf(format string, v ...interface{}) { l.logf("debug", format, v...) }
+func (l *recordingLogger) Infof(format string, v ...interface{})  { l.logf("info", format, v...) }
+func (l *recordingLogger) Warnf(format string, v ...interface{})  { l.logf("warn", format, v...) }
+func (l *recordingLogger) Errorf(format string, v ...interface{}) { l.logf("error", format, v...) }
+
+func (l *recordingLogger) count(prefix string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := 0
+	for _, message := range l.messages {
+		if strings.HasPrefix(message, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLogger(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		logger := &recordingLogger{}
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			Logger:    logger,
+		}, migrations)
+		assert.NoError(t, m.Migrate())
+
+		assert.Equal(t, 1, logger.count("info xormigrate: Migration started"))
+		assert.Equal(t, 2, logger.count("info xormigrate: Applied "))
+		assert.Zero(t, logger.count("info xormigrate: Applying "))
+		assert.Equal(t, 1, logger.count("info xormigrate: Migration finished in "))
+		assert.Zero(t, logger.count("info xormigrate: [SQL]"))
+	})
+}
+
+func TestLogDescription(t *testing.T) {
+	logger := &recordingLogger{}
+	m := NewFake(&FakeBackend{}, &Options{Logger: logger, LogLevel: LogDebug}, []*Migration{
+		{ID: "201608301400", Description: "Create persons", Migrate: func(tx *xorm.Session) error { return nil }},
+		{ID: "201608301430", Migrate: func(tx *xorm.Session) error { return nil }},
+	})
+	assert.NoError(t, m.Migrate())
+
+	assert.Equal(t, 1, logger.count("debug xormigrate: Applying 1/2: 201608301400 - Create persons"))
+	assert.Equal(t, 1, logger.count("info xormigrate: Applied 201608301400 - Create persons in "))
+	assert.Equal(t, 1, logger.count("debug xormigrate: Applying 2/2: 201608301430 ["))
+	assert.Equal(t, 1, logger.count("info xormigrate: Applied 201608301430 in "))
+}
+
+func TestLoggerQuietEchoSQL(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		logger := &recordingLogger{}
+		session := db.NewSession()
+		m := New(session, &Options{
+			TableName:      "migration",
+			UseTransaction: true,
+			Logger:         logger,
+			EchoSQL:        true,
+			Quiet:          true,
+		}, migrations)
+		assert.NoError(t, m.Migrate())
+
+		assert.Zero(t, logger.count("info xormigrate: Applied "))
+		assert.Equal(t, 1, logger.count("info xormigrate: Migration finished in "))
+		assert.NotZero(t, logger.count("info xormigrate: [SQL] CREATE TABLE"))
+
+		// Statements outside of runs are not echoed.
+		n := logger.count("info xormigrate: [SQL]")
+		_, err := session.Exec("SELECT 1")
+		assert.NoError(t, err)
+		_, err = db.Exec("SELECT 1")
+		assert.NoError(t, err)
+		assert.Equal(t, n, logger.count("info xormigrate: [SQL]"))
+	})
+}
+
+func TestLogLevel(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		t.Setenv("XORMIGRATE_LOG_LEVEL", "debug")
+		logger := &recordingLogger{}
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			Logger:    logger,
+		}, migrations)
+		assert.NoError(t, m.Migrate())
+		assert.NotZero(t, logger.count("debug xormigrate: [SQL] CREATE TABLE"))
+
+		logger = &recordingLogger{}
+		m = New(db.NewSession(), &Options{
+			TableName: "migration",
+			Logger:    logger,
+			LogLevel:  LogError,
+		}, migrations)
+		assert.NoError(t, m.RollbackLast())
+		assert.Empty(t, logger.messages)
+	})
+}
+
+func TestParseLogLevel(t *testing.T) {
+	level, err := ParseLogLevel("WARN")
+	assert.NoError(t, err)
+	assert.Equal(t, LogWarn, level)
+	_, err = ParseLogLevel("verbose")
+	assert.Error(t, err)
+}
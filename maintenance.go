@@ -0,0 +1,126 @@
+package xormigrate
+
+import (
+	"regexp"
+	"strings"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// tableStatementRegexp matches the statements touching a table, capturing
+// its name.
+var tableStatementRegexp = regexp.MustCompile(`(?i)^(?:CREATE\s+(?:UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?\S+\s+ON\s+(?:ONLY\s+)?|CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?|ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?|INSERT\s+INTO\s+|UPDATE\s+(?:ONLY\s+)?|DELETE\s+FROM\s+(?:ONLY\s+)?)([^\s(;,]+)`)
+
+// touchedTables returns the tables created, altered or written by the SQL
+// scripts of migrations, in order of appearance. Go migrations are ignored.
+func touchedTables(migrations []*Migration) []string {
+	var tables []string
+	for _, migration := range migrations {
+		if migration.Migrate != nil || migration.MigrateContext != nil {
+			continue
+		}
+		for _, script := range migration.upSQLs() {
+			for _, statement := range splitStatements(script) {
+				statement = leadingCommentsRegexp.ReplaceAllString(statement, "")
+				match := tableStatementRegexp.FindStringSubmatch(statement)
+				if match == nil {
+					continue
+				}
+				table := strings.Trim(match[1], "\"`[]")
+				if !contains(tables, table) {
+					tables = append(tables, table)
+				}
+			}
+		}
+	}
+	return tables
+}
+
+// maintenanceStatements returns the statements refreshing the statistics of
+// table, and reclaiming its space if vacuum is set.
+func maintenanceStatements(engine *xorm.Engine, table string, vacuum bool) []string {
+	quoted := engine.Quote(table)
+	switch engine.Dialect().URI().DBType {
+	case schemas.POSTGRES:
+		if vacuum {
+			return []string{"VACUUM ANALYZE " + quoted}
+		}
+		return []string{"ANALYZE " + quoted}
+	case schemas.MYSQL:
+		if vacuum {
+			// OPTIMIZE TABLE also refreshes the statistics.
+			return []string{"OPTIMIZE TABLE " + quoted}
+		}
+		return []string{"ANALYZE TABLE " + quoted}
+	case schemas.MSSQL:
+		// sp_recompile invalidates the cached plans using the table.
+		return []string{"UPDATE STATISTICS " + quoted, "EXEC sp_recompile " + quoted}
+	case schemas.SQLITE:
+		// SQLite only vacuums whole databases, see maintain.
+		return []string{"ANALYZE " + quoted}
+	}
+	return nil
+}
+
+// RefreshStatistics refreshes the statistics of tables, and invalidates the
+// plans cached for them where supported, e.g. from a migration after a large
+// backfill. See also Migration.AnalyzeTables.
+func RefreshStatistics(tx *xorm.Session, tables ...string) error {
+	for _, table := range tables {
+		for _, statement := range maintenanceStatements(tx.Engine(), table, false) {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// maintain refreshes the statistics of the tables listed by the
+// AnalyzeTables of the migrations applied by the run, once it is committed,
+// and of the tables they touched if Options.Analyze or Options.Vacuum is set.
+// As the run succeeded, failures are only logged.
+func (x *Xormigrate) maintain(applied []*Migration) {
+	var tables []string
+	for _, migration := range applied {
+		for _, table := range migration.AnalyzeTables {
+			if !contains(tables, table) {
+				tables = append(tables, table)
+			}
+		}
+	}
+	if x.options.Analyze || x.options.Vacuum {
+		for _, table := range touchedTables(applied) {
+			if !contains(tables, table) {
+				tables = append(tables, table)
+			}
+		}
+	}
+	if len(tables) == 0 {
+		return
+	}
+	x.backend.maintain(tables, x.options.Vacuum, func(statement string, err error) {
+		if x.options.Logger != nil {
+			withRunID(x.logger(), x.runID).Warnf("xormigrate: Maintenance %q failed: %v", statement, err)
+		}
+	})
+}
+
+func (b *sessionBackend) maintain(tables []string, vacuum bool, failed func(statement string, err error)) {
+	// Not using the session: VACUUM can't run in a transaction and xorm
+	// keeps using the committed one.
+	engine := b.x.session.Engine()
+	var statements []string
+	for _, table := range tables {
+		statements = append(statements, maintenanceStatements(engine, table, vacuum)...)
+	}
+	if vacuum && engine.Dialect().URI().DBType == schemas.SQLITE {
+		statements = append(statements, "VACUUM")
+	}
+	for _, statement := range statements {
+		if _, err := engine.Exec(statement); err != nil {
+			failed(statement, err)
+		}
+	}
+}
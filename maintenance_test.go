@@ -0,0 +1,67 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+func TestTouchedTables(t *testing.T) {
+	assert.Equal(t, []string{"book", "person", "pet"}, touchedTables([]*Migration{
+		{UpSQL: `CREATE TABLE IF NOT EXISTS "book" (id INTEGER);
+			-- Backfill
+			INSERT INTO book (id) VALUES (1);
+			CREATE UNIQUE INDEX CONCURRENTLY idx ON person (name);`},
+		{Migrate: func(tx *xorm.Session) error { return nil }},
+		{UpSQL: "UPDATE pet SET name = ''; SELECT 1 FROM dog; DELETE FROM book;"},
+	}))
+}
+
+func TestAnalyzeVacuum(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		logger := &recordingLogger{}
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			Analyze:   true,
+			Vacuum:    true,
+			Logger:    logger,
+		}, []*Migration{{
+			ID:    "201608301400",
+			UpSQL: "CREATE TABLE book (name VARCHAR(255)); INSERT INTO book (name) VALUES ('a');",
+		}})
+		assert.NoError(t, m.Migrate())
+		assert.Zero(t, logger.count("warn xormigrate: Maintenance"))
+	})
+}
+
+func TestRefreshStatistics(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		logger := &recordingLogger{}
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			Logger:    logger,
+		}, []*Migration{
+			{
+				ID: "201608301400",
+				Migrate: func(tx *xorm.Session) error {
+					if err := tx.Sync2(&Book{}); err != nil {
+						return err
+					}
+					return RefreshStatistics(tx, "book")
+				},
+			},
+			{
+				ID:            "201608301430",
+				AnalyzeTables: []string{"unknown_table"},
+				Migrate:       func(tx *xorm.Session) error { return nil },
+			},
+		})
+		assert.NoError(t, m.Migrate())
+		if db.Dialect().URI().DBType == schemas.SQLITE {
+			// The post-run refresh failure is only logged.
+			assert.Equal(t, 1, logger.count(`warn xormigrate: Maintenance "ANALYZE`))
+		}
+	})
+}
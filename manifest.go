@@ -0,0 +1,99 @@
+package xormigrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestSource loads SQL migrations described by a YAML manifest, which
+// gives file-based migrations the same metadata as the ones defined in Go:
+//
+//	migrations:
+//	  - id: "201608301400"
+//	    description: Create persons
+//	    up: create_person.up.sql
+//	    down: create_person.down.sql
+//	    tags: [expand]
+//	  - id: "201608301430"
+//	    up: person_name_index.up.sql
+//	    dialects: [postgres]
+//	    no_transaction: true
+//	    depends_on: ["201608301400"]
+//
+// The paths of the SQL files are relative to the directory of the manifest.
+type ManifestSource struct {
+	// FS holds the manifest and the SQL files.
+	FS fs.FS
+	// Path is the path of the manifest in FS, e.g. "migrations.yaml".
+	Path string
+}
+
+type yamlManifest struct {
+	Migrations []struct {
+		ID            string   `yaml:"id"`
+		Description   string   `yaml:"description"`
+		Up            string   `yaml:"up"`
+		Down          string   `yaml:"down"`
+		Tags          []string `yaml:"tags"`
+		Dialects      []string `yaml:"dialects"`
+		NoTransaction bool     `yaml:"no_transaction"`
+		LongRunning   bool     `yaml:"long_running"`
+		Repeatable    bool     `yaml:"repeatable"`
+		DependsOn     []string `yaml:"depends_on"`
+	} `yaml:"migrations"`
+}
+
+// Load parses the manifest and reads the SQL files it references.
+func (s *ManifestSource) Load(ctx context.Context) ([]*Migration, error) {
+	content, err := fs.ReadFile(s.FS, s.Path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest yamlManifest
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf(`xormigrate: Parsing manifest "%s": %w`, s.Path, err)
+	}
+
+	dir := path.Dir(s.Path)
+	migrations := make([]*Migration, 0, len(manifest.Migrations))
+	for _, entry := range manifest.Migrations {
+		if entry.ID == "" {
+			return nil, ErrMissingID
+		}
+		if entry.Up == "" {
+			return nil, fmt.Errorf(`xormigrate: Missing up SQL file for migration "%s"`, entry.ID)
+		}
+		migration := &Migration{
+			ID:            entry.ID,
+			Description:   entry.Description,
+			Tags:          entry.Tags,
+			Dialects:      entry.Dialects,
+			NoTransaction: entry.NoTransaction,
+			LongRunning:   entry.LongRunning,
+			Repeatable:    entry.Repeatable,
+			DependsOn:     entry.DependsOn,
+		}
+		up, err := fs.ReadFile(s.FS, path.Join(dir, entry.Up))
+		if err != nil {
+			return nil, err
+		}
+		migration.UpSQL = string(up)
+		if entry.Down != "" {
+			down, err := fs.ReadFile(s.FS, path.Join(dir, entry.Down))
+			if err != nil {
+				return nil, err
+			}
+			migration.DownSQL = string(down)
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+func (s *ManifestSource) String() string {
+	return s.Path
+}
@@ -0,0 +1,179 @@
+package xormigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+var manifestFS = fstest.MapFS{
+	"db/migrations.yaml": {Data: []byte(`
+migrations:
+  - id: "201608301400"
+    description: Create persons
+    up: sql/create_person.up.sql
+    down: sql/create_person.down.sql
+    tags: [expand]
+  - id: "201608301430"
+    up: sql/create_pet.up.sql
+    dialects: [postgres]
+    no_transaction: true
+    depends_on: ["201608301400"]
+`)},
+	"db/sql/create_person.up.sql":   {Data: []byte("CREATE TABLE person (id INTEGER, name VARCHAR(255));")},
+	"db/sql/create_person.down.sql": {Data: []byte("DROP TABLE person;")},
+	"db/sql/create_pet.up.sql":      {Data: []byte("CREATE TABLE pet (name VARCHAR(255), person_id INTEGER);")},
+}
+
+func TestManifestSource(t *testing.T) {
+	loaded, err := (&ManifestSource{FS: manifestFS, Path: "db/migrations.yaml"}).Load(context.Background())
+	if !assert.NoError(t, err) || !assert.Len(t, loaded, 2) {
+		return
+	}
+	assert.Equal(t, "Create persons", loaded[0].Description)
+	assert.Equal(t, []string{"expand"}, loaded[0].Tags)
+	assert.NotEmpty(t, loaded[0].DownSQL)
+	assert.Equal(t, []string{"postgres"}, loaded[1].Dialects)
+	assert.True(t, loaded[1].NoTransaction)
+	assert.Equal(t, []string{"201608301400"}, loaded[1].DependsOn)
+	assert.Empty(t, loaded[1].DownSQL)
+}
+
+func TestDialects(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		dbType := string(db.Dialect().URI().DBType)
+		m := New(db.NewSession(), &Options{
+			TableName:      "migration",
+			UseTransaction: true,
+		}, []*Migration{
+			{
+				ID:       "201608301400",
+				Dialects: []string{dbType},
+				Migrate: func(tx *xorm.Session) error {
+					return tx.Sync2(&Person{})
+				},
+			},
+			{
+				ID:       "201608301430",
+				Dialects: []string{"nodb"},
+				Migrate: func(tx *xorm.Session) error {
+					return tx.Sync2(&Pet{})
+				},
+			},
+		})
+		assert.NoError(t, m.Migrate())
+		has, _ := db.IsTableExist(&Person{})
+		assert.True(t, has)
+		has, _ = db.IsTableExist(&Pet{})
+		assert.False(t, has)
+		assert.Equal(t, int64(2), tableCount(t, db))
+
+		// The skipped migration has no rollback, but it can be rolled back.
+		assert.NoError(t, m.RollbackLast())
+		assert.Equal(t, int64(1), tableCount(t, db))
+	})
+}
+
+func TestNoTransaction(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		var inTx []bool
+		m := New(db.NewSession(), &Options{
+			TableName:      "migration",
+			UseTransaction: true,
+		}, []*Migration{
+			{
+				ID: "201608301400",
+				Migrate: func(tx *xorm.Session) error {
+					inTx = append(inTx, tx.IsInTx())
+					return tx.Sync2(&Person{})
+				},
+			},
+			{
+				ID:            "201608301430",
+				NoTransaction: true,
+				Migrate: func(tx *xorm.Session) error {
+					inTx = append(inTx, tx.IsInTx())
+					return tx.Sync2(&Pet{})
+				},
+			},
+			{
+				ID: "201807221927",
+				Migrate: func(tx *xorm.Session) error {
+					inTx = append(inTx, tx.IsInTx())
+					return tx.Sync2(&Book{})
+				},
+			},
+		})
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, []bool{true, false, true}, inTx)
+		assert.Equal(t, int64(3), tableCount(t, db))
+	})
+}
+
+func TestLongRunning(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		var sessions []*xorm.Session
+		m := New(db.NewSession(), &Options{
+			TableName:      "migration",
+			UseTransaction: true,
+		}, []*Migration{
+			{
+				ID: "201608301400",
+				Migrate: func(tx *xorm.Session) error {
+					sessions = append(sessions, tx)
+					return tx.Sync2(&Person{})
+				},
+			},
+			{
+				ID:          "201608301430",
+				LongRunning: true,
+				Migrate: func(tx *xorm.Session) error {
+					assert.True(t, tx.IsInTx())
+					sessions = append(sessions, tx)
+					return tx.Sync2(&Pet{})
+				},
+			},
+			{
+				ID: "201807221927",
+				Migrate: func(tx *xorm.Session) error {
+					sessions = append(sessions, tx)
+					return errors.New("failed")
+				},
+			},
+		})
+		assert.Error(t, m.Migrate())
+		assert.NotSame(t, sessions[0], sessions[1])
+		assert.Same(t, sessions[0], sessions[2])
+		// The long-running migration was committed along with the one
+		// before it, only the failed one was rolled back.
+		assert.Equal(t, int64(2), tableCount(t, db))
+	})
+}
+
+func TestDependencies(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, []*Migration{
+			{
+				ID:        "201608301400",
+				DependsOn: []string{"201608301430"},
+				Migrate: func(tx *xorm.Session) error {
+					return nil
+				},
+			},
+			{
+				ID: "201608301430",
+				Migrate: func(tx *xorm.Session) error {
+					return nil
+				},
+			},
+		})
+		err := m.Migrate()
+		assert.Equal(t, &DependencyError{ID: "201608301400", DependsOn: "201608301430"}, err)
+	})
+}
@@ -0,0 +1,120 @@
+package xormigrate
+
+import (
+	"context"
+	"time"
+)
+
+// markedReason is the skip reason of the migrations recorded by MarkApplied.
+const markedReason = "marked applied"
+
+// MarkApplied records the migration matching migrationID as applied without
+// running it, e.g. after its change was applied by hand to repair a drifted
+// database. Nothing is done if it is already recorded. With
+// Options.RecordStatus, it is recorded as skipped, for the "marked applied"
+// reason, and a MigrationSkipped event is emitted.
+func (x *Xormigrate) MarkApplied(migrationID string) error {
+	_, err := x.withResult(nil, func() error {
+		return x.markApplied(migrationID)
+	})
+	return err
+}
+
+// MarkAppliedContext is like MarkApplied, with a context, see
+// MigrateContext.
+func (x *Xormigrate) MarkAppliedContext(ctx context.Context, migrationID string) error {
+	_, err := x.withResult(ctx, func() error {
+		return x.markApplied(migrationID)
+	})
+	return err
+}
+
+// MarkUnapplied removes the record of the migration matching migrationID
+// without rolling it back, e.g. after its change was reverted by hand, so
+// that the next run applies it again. The ID may also be one of a record
+// whose migration is no longer defined. Nothing is done if it is not
+// recorded. A RolledBack event is emitted, although nothing ran.
+func (x *Xormigrate) MarkUnapplied(migrationID string) error {
+	_, err := x.withResult(nil, func() error {
+		return x.markUnapplied(migrationID)
+	})
+	return err
+}
+
+// MarkUnappliedContext is like MarkUnapplied, with a context, see
+// MigrateContext.
+func (x *Xormigrate) MarkUnappliedContext(ctx context.Context, migrationID string) error {
+	_, err := x.withResult(ctx, func() error {
+		return x.markUnapplied(migrationID)
+	})
+	return err
+}
+
+func (x *Xormigrate) markApplied(migrationID string) (err error) {
+	if err := x.checkWritable(); err != nil {
+		return err
+	}
+	if err := x.checkIDExist(migrationID); err != nil {
+		return err
+	}
+	if err := x.checkReservedID(); err != nil {
+		return err
+	}
+	x.emitRunStarted(false)
+	defer x.emitRunFinished(false, time.Now(), &err)
+
+	defer x.end()
+	if err := x.begin(); err != nil {
+		return err
+	}
+	if err := x.createMigrationTableIfNotExists(); err != nil {
+		return err
+	}
+
+	for _, migration := range x.migrations {
+		if migration.ID != migrationID {
+			continue
+		}
+		migrationRan, err := x.migrationRan(migration)
+		if err != nil {
+			return err
+		}
+		if !migrationRan {
+			if err := x.skipMigration(migration, markedReason); err != nil {
+				return err
+			}
+		}
+		break
+	}
+	return x.finish()
+}
+
+func (x *Xormigrate) markUnapplied(migrationID string) (err error) {
+	if err := x.checkWritable(); err != nil {
+		return err
+	}
+	if migrationID == initSchemaMigrationID {
+		return &ReservedIDError{ID: migrationID}
+	}
+	x.emitRunStarted(true)
+	defer x.emitRunFinished(true, time.Now(), &err)
+
+	defer x.end()
+	if err := x.begin(); err != nil {
+		return err
+	}
+	record, err := x.record(migrationID)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		if err := x.checkIDExist(migrationID); err != nil {
+			return err
+		}
+		return x.finish()
+	}
+	if err := x.deleteMigration(&Migration{ID: migrationID}); err != nil {
+		return err
+	}
+	return x.finish()
+}
@@ -0,0 +1,66 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xorm.io/xorm"
+)
+
+func TestMarkApplied(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		// Applied by hand.
+		assert.NoError(t, db.Sync2(&Person{}))
+
+		m := New(db.NewSession(), &Options{TableName: "migration", RecordStatus: true}, migrations)
+		assert.Equal(t, ErrMigrationIDDoesNotExist, m.MarkApplied("201609011200"))
+		assert.NoError(t, m.MarkApplied("201608301400"))
+		assert.NoError(t, m.MarkApplied("201608301400"))
+		assert.Equal(t, int64(1), tableCount(t, db))
+
+		status, err := m.Status()
+		require.NoError(t, err)
+		if assert.Len(t, status.Applied, 1) {
+			assert.Equal(t, "marked applied", status.Applied[0].SkipReason)
+		}
+
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, int64(2), tableCount(t, db))
+	})
+}
+
+func TestMarkUnapplied(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+		assert.NoError(t, m.Migrate())
+
+		// Reverted by hand.
+		assert.NoError(t, db.DropTables(&Pet{}))
+		assert.NoError(t, m.MarkUnapplied("201608301430"))
+		assert.NoError(t, m.MarkUnapplied("201608301430"))
+		assert.Equal(t, int64(1), tableCount(t, db))
+		assert.Equal(t, ErrMigrationIDDoesNotExist, m.MarkUnapplied("201609011200"))
+
+		assert.NoError(t, m.Migrate())
+		has, err := db.IsTableExist(&Pet{})
+		assert.NoError(t, err)
+		assert.True(t, has)
+	})
+}
+
+func TestMarkUnappliedUnknown(t *testing.T) {
+	backend := &FakeBackend{}
+	removed := &Migration{ID: "201512311200", Migrate: func(tx *xorm.Session) error { return nil }}
+	require.NoError(t, NewFake(backend, &Options{}, append([]*Migration{removed}, targetMigrations()[0])).Migrate())
+	m := NewFake(backend, &Options{}, targetMigrations())
+	var rolledBack []string
+	m.AddListener(ListenerFunc(func(event Event) {
+		if e, ok := event.(*RolledBack); ok {
+			rolledBack = append(rolledBack, e.ID)
+		}
+	}))
+	assert.NoError(t, m.MarkUnapplied("201512311200"))
+	assert.Equal(t, []string{"201512311200"}, rolledBack)
+	assert.Equal(t, []string{"201608301400"}, backend.Applied())
+}
@@ -0,0 +1,24 @@
+package xormigrate
+
+import "context"
+
+// Migrator is the public behavior of Xormigrate. Applications running
+// migrations at startup can depend on it and use MockMigrator in their tests.
+type Migrator interface {
+	Migrate() error
+	MigrateTo(migrationID string) error
+	RollbackLast() error
+	RollbackTo(migrationID string) error
+	RollbackMigration(m *Migration) error
+	Run(direction Direction, target Target) error
+	Plan(direction Direction, target Target) ([]*Migration, error)
+	RunOnce(ctx context.Context) error
+	Ready() error
+	Initialized() (bool, error)
+	Pending() ([]*Migration, error)
+	MigrationRan(id string) (bool, error)
+	AppliedIDs() ([]string, error)
+	Status() (*Status, error)
+}
+
+var _ Migrator = (*Xormigrate)(nil)
@@ -0,0 +1,174 @@
+package xormigrate
+
+import (
+	"context"
+	"sync"
+)
+
+var _ Migrator = (*MockMigrator)(nil)
+
+// MockMigrator is a Migrator for tests. Each method calls the matching On
+// function if set, or returns zero values otherwise, and is recorded in
+// Calls.
+type MockMigrator struct {
+	OnMigrate           func() error
+	OnMigrateTo         func(migrationID string) error
+	OnRollbackLast      func() error
+	OnRollbackTo        func(migrationID string) error
+	OnRollbackMigration func(m *Migration) error
+	OnRun               func(direction Direction, target Target) error
+	OnPlan              func(direction Direction, target Target) ([]*Migration, error)
+	OnRunOnce           func(ctx context.Context) error
+	OnReady             func() error
+	OnInitialized       func() (bool, error)
+	OnPending           func() ([]*Migration, error)
+	OnMigrationRan      func(id string) (bool, error)
+	OnAppliedIDs        func() ([]string, error)
+	OnStatus            func() (*Status, error)
+
+	mu    sync.Mutex
+	calls []string
+}
+
+// Calls returns the methods called so far, in order, followed by their
+// migration ID argument if any, e.g. "MigrateTo 201608301400" or
+// "Run down 2 steps".
+func (m *MockMigrator) Calls() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.calls...)
+}
+
+func (m *MockMigrator) record(call string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = append(m.calls, call)
+}
+
+// Migrate implements Migrator.
+func (m *MockMigrator) Migrate() error {
+	m.record("Migrate")
+	if m.OnMigrate == nil {
+		return nil
+	}
+	return m.OnMigrate()
+}
+
+// MigrateTo implements Migrator.
+func (m *MockMigrator) MigrateTo(migrationID string) error {
+	m.record("MigrateTo " + migrationID)
+	if m.OnMigrateTo == nil {
+		return nil
+	}
+	return m.OnMigrateTo(migrationID)
+}
+
+// RollbackLast implements Migrator.
+func (m *MockMigrator) RollbackLast() error {
+	m.record("RollbackLast")
+	if m.OnRollbackLast == nil {
+		return nil
+	}
+	return m.OnRollbackLast()
+}
+
+// RollbackTo implements Migrator.
+func (m *MockMigrator) RollbackTo(migrationID string) error {
+	m.record("RollbackTo " + migrationID)
+	if m.OnRollbackTo == nil {
+		return nil
+	}
+	return m.OnRollbackTo(migrationID)
+}
+
+// RollbackMigration implements Migrator.
+func (m *MockMigrator) RollbackMigration(migration *Migration) error {
+	m.record("RollbackMigration " + migration.ID)
+	if m.OnRollbackMigration == nil {
+		return nil
+	}
+	return m.OnRollbackMigration(migration)
+}
+
+// Run implements Migrator.
+func (m *MockMigrator) Run(direction Direction, target Target) error {
+	m.record("Run " + direction.String() + " " + target.String())
+	if m.OnRun == nil {
+		return nil
+	}
+	return m.OnRun(direction, target)
+}
+
+// Plan implements Migrator.
+func (m *MockMigrator) Plan(direction Direction, target Target) ([]*Migration, error) {
+	m.record("Plan " + direction.String() + " " + target.String())
+	if m.OnPlan == nil {
+		return nil, nil
+	}
+	return m.OnPlan(direction, target)
+}
+
+// RunOnce implements Migrator.
+func (m *MockMigrator) RunOnce(ctx context.Context) error {
+	m.record("RunOnce")
+	if m.OnRunOnce == nil {
+		return nil
+	}
+	return m.OnRunOnce(ctx)
+}
+
+// Ready implements Migrator.
+func (m *MockMigrator) Ready() error {
+	m.record("Ready")
+	if m.OnReady == nil {
+		return nil
+	}
+	return m.OnReady()
+}
+
+// Initialized implements Migrator.
+func (m *MockMigrator) Initialized() (bool, error) {
+	m.record("Initialized")
+	if m.OnInitialized == nil {
+		return false, nil
+	}
+	return m.OnInitialized()
+}
+
+// Pending implements Migrator.
+func (m *MockMigrator) Pending() ([]*Migration, error) {
+	m.record("Pending")
+	if m.OnPending == nil {
+		return nil, nil
+	}
+	return m.OnPending()
+}
+
+// MigrationRan implements Migrator.
+func (m *MockMigrator) MigrationRan(id string) (bool, error) {
+	m.record("MigrationRan " + id)
+	if m.OnMigrationRan == nil {
+		return false, nil
+	}
+	return m.OnMigrationRan(id)
+}
+
+// AppliedIDs implements Migrator.
+func (m *MockMigrator) AppliedIDs() ([]string, error) {
+	m.record("AppliedIDs")
+	if m.OnAppliedIDs == nil {
+		return nil, nil
+	}
+	return m.OnAppliedIDs()
+}
+
+// Status implements Migrator.
+func (m *MockMigrator) Status() (*Status, error) {
+	m.record("Status")
+	if m.OnStatus == nil {
+		return nil, nil
+	}
+	return m.OnStatus()
+}
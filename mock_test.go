@@ -0,0 +1,45 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startup is an example of application code depending on Migrator.
+func startup(m Migrator) error {
+	pending, err := m.Pending()
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+	return m.Migrate()
+}
+
+func TestMockMigrator(t *testing.T) {
+	m := &MockMigrator{
+		OnPending: func() ([]*Migration, error) {
+			return []*Migration{{ID: "201608301400"}}, nil
+		},
+		OnMigrate: func() error {
+			return errors.New("failed")
+		},
+	}
+	assert.EqualError(t, startup(m), "failed")
+	assert.Equal(t, []string{"Pending", "Migrate"}, m.Calls())
+
+	m = &MockMigrator{}
+	assert.NoError(t, startup(m))
+	assert.NoError(t, m.MigrateTo("201608301400"))
+	assert.Equal(t, []string{"Pending", "MigrateTo 201608301400"}, m.Calls())
+
+	m = &MockMigrator{
+		OnStatus: func() (*Status, error) {
+			return &Status{Pending: []MigrationStatus{{ID: "201608301400"}}}, nil
+		},
+	}
+	status, err := m.Status()
+	assert.NoError(t, err)
+	assert.Len(t, status.Pending, 1)
+	assert.Equal(t, []string{"Status"}, m.Calls())
+}
@@ -0,0 +1,155 @@
+package xormigrate
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// NamingConvention is the naming convention of the migrations, enforced on
+// Go and SQL migrations alike, see Check and Options.Naming. The zero value
+// enforces nothing.
+type NamingConvention struct {
+	// IDLength is the number of digits of the IDs, e.g. 12 for
+	// "201608301400" or 14 for "20160830140000", except for repeatable
+	// migrations. Zero doesn't check the IDs.
+	IDLength int
+	// SnakeCaseDescriptions requires non-empty descriptions made of lower
+	// case words, e.g. "create person" for the file
+	// "201608301400_create_person.up.sql".
+	SnakeCaseDescriptions bool
+	// RequireDown requires every migration to be reversible: Go migrations
+	// must have a Rollback, and each up file of SQL migrations, including
+	// the variants for a dialect, a matching down file.
+	RequireDown bool
+	// DescriptionMatchesFile requires the description of Go migrations to
+	// match the name of the file defining Migrate, e.g.
+	// "201608301400_create_person.go" for "create person", and all the
+	// files of SQL migrations to have the same description.
+	DescriptionMatchesFile bool
+}
+
+// NamingViolation is a migration violating the naming convention. File is
+// set when a file is at fault.
+type NamingViolation struct {
+	ID      string
+	File    string
+	Problem string
+}
+
+func (v NamingViolation) String() string {
+	if v.File != "" {
+		return fmt.Sprintf(`migration "%s", file "%s": %s`, v.ID, v.File, v.Problem)
+	}
+	return fmt.Sprintf(`migration "%s": %s`, v.ID, v.Problem)
+}
+
+// NamingError is returned when migrations violate the naming convention. It
+// lists all the violations at once.
+type NamingError struct {
+	Violations []NamingViolation
+}
+
+func (e *NamingError) Error() string {
+	lines := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		lines[i] = "\n  " + violation.String()
+	}
+	return fmt.Sprintf("xormigrate: %d naming convention violations:%s", len(e.Violations), strings.Join(lines, ""))
+}
+
+var (
+	digitsRegexp    = regexp.MustCompile(`^[0-9]+$`)
+	snakeCaseRegexp = regexp.MustCompile(`^[a-z0-9]+( [a-z0-9]+)*$`)
+)
+
+// Check returns a *NamingError listing the violations of the convention by
+// migrations, or nil.
+func (c *NamingConvention) Check(migrations []*Migration) error {
+	var violations []NamingViolation
+	for _, m := range migrations {
+		for _, problem := range c.problems(m) {
+			violations = append(violations, NamingViolation{ID: m.ID, Problem: problem})
+		}
+		violations = append(violations, c.fileViolations(m)...)
+	}
+	if len(violations) > 0 {
+		return &NamingError{Violations: violations}
+	}
+	return nil
+}
+
+// problems returns the violations of the convention by the definition of m.
+func (c *NamingConvention) problems(m *Migration) []string {
+	var problems []string
+	if c.IDLength > 0 && !m.Repeatable && (len(m.ID) != c.IDLength || !digitsRegexp.MatchString(m.ID)) {
+		problems = append(problems, fmt.Sprintf("ID must be made of %d digits", c.IDLength))
+	}
+	if c.SnakeCaseDescriptions && !snakeCaseRegexp.MatchString(m.Description) {
+		problems = append(problems, fmt.Sprintf(`description "%s" must be made of lower case words`, m.Description))
+	}
+	if len(m.files) > 0 {
+		return problems
+	}
+	if c.RequireDown && m.Rollback == nil && m.RollbackContext == nil && m.DownSQL == "" {
+		problems = append(problems, "no rollback")
+	}
+	if c.DescriptionMatchesFile {
+		if file, _ := goMigrationFile(m); file != "" {
+			expected := m.ID + "_" + strings.ReplaceAll(m.Description, " ", "_") + ".go"
+			if filepath.Base(file) != expected {
+				problems = append(problems, fmt.Sprintf(`defined in "%s" instead of "%s"`, filepath.Base(file), expected))
+			}
+		}
+	}
+	return problems
+}
+
+// fileViolations returns the violations of the convention by the files of a
+// SQL migration loaded with LoadFS.
+func (c *NamingConvention) fileViolations(m *Migration) []NamingViolation {
+	var violations []NamingViolation
+	for _, file := range m.files {
+		_, description, _, up, _ := parseSQLFileName(file)
+		if c.RequireDown && up {
+			down := strings.TrimSuffix(file, ".up.sql") + ".down.sql"
+			if !contains(m.files, down) {
+				violations = append(violations, NamingViolation{ID: m.ID, File: file, Problem: fmt.Sprintf(`no "%s"`, down)})
+			}
+		}
+		if c.DescriptionMatchesFile && description != m.Description {
+			violations = append(violations, NamingViolation{ID: m.ID, File: file, Problem: fmt.Sprintf(`description "%s" differs from "%s"`, description, m.Description)})
+		}
+	}
+	return violations
+}
+
+// goMigrationFile returns the file and line defining the function of a Go
+// migration, or "" for SQL migrations.
+func goMigrationFile(m *Migration) (file string, line int) {
+	var fn interface{}
+	switch {
+	case m.Migrate != nil:
+		fn = m.Migrate
+	case m.MigrateContext != nil:
+		fn = m.MigrateContext
+	default:
+		return "", 0
+	}
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "", 0
+	}
+	return f.FileLine(f.Entry())
+}
+
+// checkNaming enforces Options.Naming.
+func (x *Xormigrate) checkNaming() error {
+	if x.options.Naming == nil {
+		return nil
+	}
+	return x.options.Naming.Check(x.migrations)
+}
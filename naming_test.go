@@ -0,0 +1,53 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xorm.io/xorm"
+)
+
+func TestNamingConvention(t *testing.T) {
+	files, err := LoadFS(fstest.MapFS{
+		"migrations/201608301400_create_person.up.sql":             {Data: []byte("CREATE TABLE person (name VARCHAR(255));")},
+		"migrations/201608301400_create_person.down.sql":           {Data: []byte("DROP TABLE person;")},
+		"migrations/201608301430_create_pet.up.sql":                {Data: []byte("CREATE TABLE pet (name VARCHAR(255));")},
+		"migrations/201608301430_create_pet.postgres.up.sql":       {Data: []byte("CREATE TABLE pet (name TEXT);")},
+		"migrations/201608301430_create_pet.down.sql":              {Data: []byte("DROP TABLE pet;")},
+		"migrations/2016090112_AddBook.up.sql":                     {Data: []byte("CREATE TABLE book (name VARCHAR(255));")},
+		"migrations/201609021200_create_index.up.sql":              {Data: []byte("CREATE INDEX idx_person ON person (name);")},
+		"migrations/201609021200_create_person_index.down.sql":     {Data: []byte("DROP INDEX idx_person;")},
+		"migrations/201609031200_backfill_person.sqlite3.up.sql":   {Data: []byte("UPDATE person SET name = '';")},
+		"migrations/201609031200_backfill_person.sqlite3.down.sql": {Data: []byte("SELECT 1;")},
+	}, "migrations")
+	require.NoError(t, err)
+	migrations := append(files,
+		&Migration{ID: "201609041200", Description: "seed books", Migrate: func(tx *xorm.Session) error { return nil }},
+		&Migration{ID: "201609051200", Description: "seed pets", Migrate: func(tx *xorm.Session) error { return nil }, Rollback: func(tx *xorm.Session) error { return nil }},
+	)
+
+	assert.NoError(t, (&NamingConvention{}).Check(migrations))
+	convention := &NamingConvention{IDLength: 12, SnakeCaseDescriptions: true, RequireDown: true, DescriptionMatchesFile: true}
+	err = convention.Check(migrations)
+	var namingErr *NamingError
+	require.True(t, errors.As(err, &namingErr))
+	assert.Equal(t, []NamingViolation{
+		{ID: "201608301430", File: "201608301430_create_pet.postgres.up.sql", Problem: `no "201608301430_create_pet.postgres.down.sql"`},
+		{ID: "2016090112", Problem: "ID must be made of 12 digits"},
+		{ID: "2016090112", Problem: `description "AddBook" must be made of lower case words`},
+		{ID: "2016090112", File: "2016090112_AddBook.up.sql", Problem: `no "2016090112_AddBook.down.sql"`},
+		{ID: "201609021200", File: "201609021200_create_index.up.sql", Problem: `no "201609021200_create_index.down.sql"`},
+		{ID: "201609021200", File: "201609021200_create_person_index.down.sql", Problem: `description "create person index" differs from "create index"`},
+		{ID: "201609041200", Problem: "no rollback"},
+		{ID: "201609041200", Problem: `defined in "naming_test.go" instead of "201609041200_seed_books.go"`},
+		{ID: "201609051200", Problem: `defined in "naming_test.go" instead of "201609051200_seed_pets.go"`},
+	}, namingErr.Violations)
+	assert.Contains(t, err.Error(), "xormigrate: 9 naming convention violations:\n  migration \"201608301430\", file")
+
+	m := NewFake(&FakeBackend{}, &Options{Naming: &NamingConvention{IDLength: 14}}, migrations)
+	assert.True(t, errors.As(m.Migrate(), &namingErr))
+	assert.Len(t, namingErr.Violations, len(migrations))
+}
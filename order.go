@@ -0,0 +1,75 @@
+package xormigrate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// OrderError is returned when the ID of a migration does not come after the
+// one of the migration before it, see Options.StrictOrder.
+type OrderError struct {
+	ID       string
+	Previous string
+}
+
+func (e *OrderError) Error() string {
+	return fmt.Sprintf(`xormigrate: Migration ID "%s" does not come after "%s"`, e.ID, e.Previous)
+}
+
+// InvalidIDError is returned when the ID of a migration is rejected by
+// Options.ValidateID.
+type InvalidIDError struct {
+	ID  string
+	Err error
+}
+
+func (e *InvalidIDError) Error() string {
+	return fmt.Sprintf(`xormigrate: Invalid migration ID "%s": %v`, e.ID, e.Err)
+}
+
+func (e *InvalidIDError) Unwrap() error {
+	return e.Err
+}
+
+// IDLayout returns an Options.ValidateID accepting the IDs which are times
+// formatted with the layout of the time package, e.g. "200601021504" for
+// "201608301400".
+func IDLayout(layout string) func(id string) error {
+	return func(id string) error {
+		if _, err := time.Parse(layout, id); err != nil {
+			return fmt.Errorf(`not a time formatted as "%s"`, layout)
+		}
+		return nil
+	}
+}
+
+// sortByID returns the migrations sorted by ID, keeping the order of the
+// ones with the same ID.
+func sortByID(migrations []*Migration) []*Migration {
+	sorted := append([]*Migration(nil), migrations...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+// checkOrder enforces Options.StrictOrder and Options.ValidateID.
+func (x *Xormigrate) checkOrder() error {
+	previous := ""
+	for _, m := range x.migrations {
+		if m.Repeatable {
+			continue
+		}
+		if x.options.ValidateID != nil {
+			if err := x.options.ValidateID(m.ID); err != nil {
+				return &InvalidIDError{ID: m.ID, Err: err}
+			}
+		}
+		if x.options.StrictOrder && previous != "" && m.ID <= previous {
+			return &OrderError{ID: m.ID, Previous: previous}
+		}
+		previous = m.ID
+	}
+	return nil
+}
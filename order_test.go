@@ -0,0 +1,53 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortByID(t *testing.T) {
+	migrations := targetMigrations()
+	shuffled := []*Migration{migrations[2], migrations[0], migrations[3], migrations[1]}
+	m := NewFake(&FakeBackend{}, &Options{SortByID: true, StrictOrder: true}, shuffled)
+	var applied []string
+	m.AddListener(ListenerFunc(func(event Event) {
+		if e, ok := event.(*MigrationApplied); ok {
+			applied = append(applied, e.ID)
+		}
+	}))
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, []string{"201608301400", "201608301430", "201609011200", "201609021200"}, applied)
+	// The given slice is left as is.
+	assert.Equal(t, "201609011200", shuffled[0].ID)
+}
+
+func TestStrictOrder(t *testing.T) {
+	migrations := targetMigrations()
+	m := NewFake(&FakeBackend{}, &Options{StrictOrder: true}, []*Migration{migrations[0], migrations[2], migrations[1]})
+	err := m.Migrate()
+	var orderErr *OrderError
+	if assert.True(t, errors.As(err, &orderErr)) {
+		assert.Equal(t, &OrderError{ID: "201608301430", Previous: "201609011200"}, orderErr)
+	}
+	assert.EqualError(t, err, `xormigrate: Migration ID "201608301430" does not come after "201609011200"`)
+
+	migrations[1].Repeatable, migrations[1].Checksum = true, "v1"
+	m = NewFake(&FakeBackend{}, &Options{StrictOrder: true}, []*Migration{migrations[0], migrations[2], migrations[1]})
+	assert.NoError(t, m.Migrate())
+}
+
+func TestValidateID(t *testing.T) {
+	migrations := append(targetMigrations(), &Migration{ID: "2016-09-03"})
+	m := NewFake(&FakeBackend{}, &Options{ValidateID: IDLayout("200601021504")}, migrations)
+	err := m.Migrate()
+	var invalidErr *InvalidIDError
+	if assert.True(t, errors.As(err, &invalidErr)) {
+		assert.Equal(t, "2016-09-03", invalidErr.ID)
+	}
+	assert.EqualError(t, err, `xormigrate: Invalid migration ID "2016-09-03": not a time formatted as "200601021504"`)
+
+	m = NewFake(&FakeBackend{}, &Options{ValidateID: IDLayout("200601021504")}, targetMigrations())
+	assert.NoError(t, m.Migrate())
+}
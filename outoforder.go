@@ -0,0 +1,88 @@
+package xormigrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OutOfOrderError is returned when pending migrations come before the applied
+// migration Last, both in the order they are defined and in the order runs
+// apply them, e.g. because they were merged from a branch after it was
+// applied, unless Options.AllowOutOfOrder is set.
+type OutOfOrderError struct {
+	IDs  []string
+	Last string
+}
+
+func (e *OutOfOrderError) Error() string {
+	return fmt.Sprintf("xormigrate: Pending migrations %s come before the applied migration %s, set Options.AllowOutOfOrder to apply them", strings.Join(e.IDs, ", "), e.Last)
+}
+
+// outOfOrderMigrations returns the migrations which did not run yet while an
+// applied migration both comes after them and would run after them, see
+// Migration.Priority, mapped to the last such applied migration in run order.
+// Repeatable migrations are never out of order.
+func (x *Xormigrate) outOfOrderMigrations(applied func(m *Migration) (bool, error)) (map[string]*Migration, error) {
+	order, err := x.runOrder("")
+	if err != nil {
+		return nil, err
+	}
+	positions := make(map[string]int, len(order))
+	for i, migration := range order {
+		positions[migration.ID] = i
+	}
+	// Going backwards, last is the applied migration running last among
+	// the ones defined after the current one.
+	outOfOrder := make(map[string]*Migration)
+	var last *Migration
+	for i := len(x.migrations) - 1; i >= 0; i-- {
+		migration := x.migrations[i]
+		if migration.Repeatable {
+			continue
+		}
+		migrationRan, err := applied(migration)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case migrationRan:
+			if last == nil || positions[migration.ID] > positions[last.ID] {
+				last = migration
+			}
+		case last != nil && positions[migration.ID] < positions[last.ID]:
+			outOfOrder[migration.ID] = last
+		}
+	}
+	return outOfOrder, nil
+}
+
+// checkOutOfOrder fails with an *OutOfOrderError if some of the pending
+// migrations of a run are out of order, see outOfOrderMigrations, or keeps
+// track of them with Options.AllowOutOfOrder.
+func (x *Xormigrate) checkOutOfOrder(pending []*Migration) error {
+	outOfOrder, err := x.outOfOrderMigrations(x.migrationRan)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	var last *Migration
+	for _, migration := range pending {
+		if before, ok := outOfOrder[migration.ID]; ok {
+			ids = append(ids, migration.ID)
+			if last == nil {
+				last = before
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if !x.options.AllowOutOfOrder {
+		return &OutOfOrderError{IDs: ids, Last: last.ID}
+	}
+	x.outOfOrder = make(map[string]bool, len(ids))
+	for _, id := range ids {
+		x.outOfOrder[id] = true
+	}
+	return nil
+}
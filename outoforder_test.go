@@ -0,0 +1,83 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xorm.io/xorm"
+)
+
+func TestOutOfOrder(t *testing.T) {
+	migrations := targetMigrations()
+	backend := &FakeBackend{}
+	// The release branch applied the newest migration first.
+	require.NoError(t, NewFake(backend, &Options{}, []*Migration{migrations[0], migrations[2]}).Migrate())
+
+	m := NewFake(backend, &Options{}, migrations)
+	err := m.Migrate()
+	var outOfOrderErr *OutOfOrderError
+	if assert.True(t, errors.As(err, &outOfOrderErr)) {
+		assert.Equal(t, []string{"201608301430"}, outOfOrderErr.IDs)
+		assert.Equal(t, "201609011200", outOfOrderErr.Last)
+	}
+	assert.EqualError(t, err, "xormigrate: Pending migrations 201608301430 come before the applied migration 201609011200, set Options.AllowOutOfOrder to apply them")
+	assert.Equal(t, []string{"201608301400", "201609011200"}, backend.Applied())
+	assert.True(t, errors.As(m.MigrateTo("201608301430"), &outOfOrderErr))
+}
+
+func TestAllowOutOfOrder(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		options := &Options{TableName: "migration", AllowOutOfOrder: true}
+		require.NoError(t, New(db.NewSession(), options, []*Migration{migrations[0]}).Migrate())
+		late := &Migration{
+			ID: "201608301300",
+			Migrate: func(tx *xorm.Session) error {
+				return nil
+			},
+		}
+		m := New(db.NewSession(), options, []*Migration{late, migrations[0], migrations[1]})
+
+		status, err := m.Status()
+		require.NoError(t, err)
+		if assert.Len(t, status.Pending, 2) {
+			assert.True(t, status.Pending[0].OutOfOrder)
+			assert.False(t, status.Pending[1].OutOfOrder)
+		}
+
+		assert.NoError(t, m.Migrate())
+		status, err = m.Status()
+		require.NoError(t, err)
+		if assert.Len(t, status.Applied, 3) {
+			assert.True(t, status.Applied[0].OutOfOrder)
+			assert.False(t, status.Applied[1].OutOfOrder)
+			assert.False(t, status.Applied[2].OutOfOrder)
+		}
+	})
+}
+
+func TestOutOfOrderPriority(t *testing.T) {
+	errFailed := errors.New("failed")
+	fail := true
+	migrations := []*Migration{
+		{ID: "1", Migrate: func(tx *xorm.Session) error {
+			if fail {
+				return errFailed
+			}
+			return nil
+		}},
+		{ID: "2", Priority: 10, Migrate: func(tx *xorm.Session) error { return nil }},
+	}
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{}, migrations)
+	assert.ErrorIs(t, m.Migrate(), errFailed)
+	assert.Equal(t, []string{"2"}, backend.Applied())
+
+	// "2" ran first because of its priority, "1" is not out of order.
+	status, err := m.Status()
+	require.NoError(t, err)
+	assert.False(t, status.Pending[0].OutOfOrder)
+	fail = false
+	assert.NoError(t, m.Migrate())
+}
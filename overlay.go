@@ -0,0 +1,139 @@
+package xormigrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OverlaySource applies the overrides of YAML overlay files to the
+// migrations of another source, so that an environment can tune some
+// migrations instead of maintaining a diverging copy of them:
+//
+//	overrides:
+//	  - id: "201608301400"
+//	    skip: not needed on the fork
+//	  - id: "201608301430"
+//	    up: fork/201608301430.up.sql
+//	    down: fork/201608301430.down.sql
+//	  - id: "201609011200"
+//	    params:
+//	      batch_size: "500"
+//
+// Skip sets Migration.Skip, up and down replace the migration, Go or SQL,
+// with the SQL files, whose paths are relative to the directory of the
+// overlay, and params are merged into Migration.Params. Overlays take
+// precedence over the source, and later overlays over earlier ones. Each
+// change is listed in Migration.Overrides.
+type OverlaySource struct {
+	// Source provides the migrations to override.
+	Source Source
+	// FS holds the overlays and the SQL files they reference.
+	FS fs.FS
+	// Paths are the paths of the overlays in FS, e.g. "overlays/prod.yaml",
+	// in increasing order of precedence.
+	Paths []string
+}
+
+type yamlOverlay struct {
+	Overrides []struct {
+		ID     string            `yaml:"id"`
+		Skip   string            `yaml:"skip"`
+		Up     string            `yaml:"up"`
+		Down   string            `yaml:"down"`
+		Params map[string]string `yaml:"params"`
+	} `yaml:"overrides"`
+}
+
+// Load loads the migrations of Source and applies the overlays, leaving the
+// migrations of Source untouched.
+func (s *OverlaySource) Load(ctx context.Context) ([]*Migration, error) {
+	migrations, err := s.Source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	migrations = append([]*Migration(nil), migrations...)
+	index := make(map[string]int, len(migrations))
+	for i, migration := range migrations {
+		index[migration.ID] = i
+	}
+	overridden := make(map[string]bool)
+	for _, overlayPath := range s.Paths {
+		content, err := fs.ReadFile(s.FS, overlayPath)
+		if err != nil {
+			return nil, err
+		}
+		var overlay yamlOverlay
+		if err := yaml.Unmarshal(content, &overlay); err != nil {
+			return nil, fmt.Errorf(`xormigrate: Parsing overlay "%s": %w`, overlayPath, err)
+		}
+
+		dir := path.Dir(overlayPath)
+		seen := make(map[string]bool, len(overlay.Overrides))
+		for _, entry := range overlay.Overrides {
+			if entry.ID == "" {
+				return nil, ErrMissingID
+			}
+			i, ok := index[entry.ID]
+			if !ok {
+				return nil, fmt.Errorf(`xormigrate: Overlay "%s" overrides unknown migration "%s"`, overlayPath, entry.ID)
+			}
+			if seen[entry.ID] {
+				return nil, fmt.Errorf(`xormigrate: Overlay "%s" overrides migration "%s" more than once`, overlayPath, entry.ID)
+			}
+			seen[entry.ID] = true
+
+			m := migrations[i]
+			if !overridden[m.ID] {
+				// Copy the migration so that the source is not altered.
+				copied := *m
+				copied.Overrides = append([]string(nil), m.Overrides...)
+				m, migrations[i] = &copied, &copied
+				overridden[m.ID] = true
+			}
+
+			if entry.Skip != "" {
+				m.Skip = entry.Skip
+				m.Overrides = append(m.Overrides, overlayPath+": skip")
+			}
+			if entry.Up != "" {
+				up, err := fs.ReadFile(s.FS, path.Join(dir, entry.Up))
+				if err != nil {
+					return nil, err
+				}
+				m.UpSQL, m.UpSQLByDialect = string(up), nil
+				m.Migrate, m.MigrateContext, m.Steps, m.Checksum = nil, nil, nil, ""
+				m.Overrides = append(m.Overrides, overlayPath+": up")
+			}
+			if entry.Down != "" {
+				down, err := fs.ReadFile(s.FS, path.Join(dir, entry.Down))
+				if err != nil {
+					return nil, err
+				}
+				m.DownSQL, m.DownSQLByDialect = string(down), nil
+				m.Rollback, m.RollbackContext = nil, nil
+				m.Overrides = append(m.Overrides, overlayPath+": down")
+			}
+			if len(entry.Params) > 0 {
+				params := make(map[string]string, len(m.Params)+len(entry.Params))
+				for name, value := range m.Params {
+					params[name] = value
+				}
+				for _, name := range sortedKeys(entry.Params) {
+					params[name] = entry.Params[name]
+					m.Overrides = append(m.Overrides, overlayPath+": params."+name)
+				}
+				m.Params = params
+			}
+		}
+	}
+	return migrations, nil
+}
+
+func (s *OverlaySource) String() string {
+	return strings.Join(s.Paths, ", ")
+}
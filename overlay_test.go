@@ -0,0 +1,141 @@
+package xormigrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xorm.io/xorm"
+)
+
+var overlayFS = fstest.MapFS{
+	"overlays/base.yaml": {Data: []byte(`
+overrides:
+  - id: "201608301430"
+    skip: pets are managed by another service
+  - id: "201609011200"
+    params:
+      default_name: base
+      batch_size: "100"
+`)},
+	"overlays/fork.yaml": {Data: []byte(`
+overrides:
+  - id: "201609011200"
+    params:
+      default_name: fork
+  - id: "201609021200"
+    up: fork/201609021200.up.sql
+    down: fork/201609021200.down.sql
+`)},
+	"overlays/fork/201609021200.up.sql":   {Data: []byte("CREATE TABLE book (name VARCHAR(255));")},
+	"overlays/fork/201609021200.down.sql": {Data: []byte("DROP TABLE book;")},
+}
+
+func overlaidMigrations() []*Migration {
+	return []*Migration{
+		{
+			ID: "201608301400",
+			Migrate: func(tx *xorm.Session) error {
+				return tx.Sync2(&Person{})
+			},
+		},
+		{
+			ID: "201608301430",
+			Migrate: func(tx *xorm.Session) error {
+				return tx.Sync2(&Pet{})
+			},
+		},
+		{
+			ID:     "201609011200",
+			UpSQL:  "INSERT INTO person (name) VALUES ('{{ param \"default_name\" }}');",
+			Params: map[string]string{"batch_size": "1000"},
+		},
+		{
+			ID: "201609021200",
+			Migrate: func(tx *xorm.Session) error {
+				return tx.Sync2(&Pet{})
+			},
+		},
+	}
+}
+
+func TestOverlaySource(t *testing.T) {
+	base := overlaidMigrations()
+	loaded, err := (&OverlaySource{
+		Source: SliceSource(base),
+		FS:     overlayFS,
+		Paths:  []string{"overlays/base.yaml", "overlays/fork.yaml"},
+	}).Load(context.Background())
+	require.NoError(t, err)
+	require.Len(t, loaded, 4)
+
+	assert.Same(t, base[0], loaded[0])
+	assert.Equal(t, "pets are managed by another service", loaded[1].Skip)
+	assert.Equal(t, []string{"overlays/base.yaml: skip"}, loaded[1].Overrides)
+	assert.Equal(t, map[string]string{"default_name": "fork", "batch_size": "100"}, loaded[2].Params)
+	assert.Equal(t, []string{
+		"overlays/base.yaml: params.batch_size",
+		"overlays/base.yaml: params.default_name",
+		"overlays/fork.yaml: params.default_name",
+	}, loaded[2].Overrides)
+	assert.Nil(t, loaded[3].Migrate)
+	assert.Equal(t, "CREATE TABLE book (name VARCHAR(255));", loaded[3].UpSQL)
+	assert.Equal(t, []string{"overlays/fork.yaml: up", "overlays/fork.yaml: down"}, loaded[3].Overrides)
+
+	// The migrations of the source are left untouched.
+	assert.Empty(t, base[1].Skip)
+	assert.Equal(t, map[string]string{"batch_size": "1000"}, base[2].Params)
+	assert.NotNil(t, base[3].Migrate)
+
+	_, err = (&OverlaySource{
+		Source: SliceSource(base),
+		FS: fstest.MapFS{"typo.yaml": {Data: []byte(`
+overrides:
+  - id: "201608301499"
+    skip: typo
+`)}},
+		Paths: []string{"typo.yaml"},
+	}).Load(context.Background())
+	assert.EqualError(t, err, `xormigrate: Overlay "typo.yaml" overrides unknown migration "201608301499"`)
+}
+
+func TestOverlaySourceMigrate(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		loaded, err := (&OverlaySource{
+			Source: SliceSource(overlaidMigrations()),
+			FS:     overlayFS,
+			Paths:  []string{"overlays/base.yaml", "overlays/fork.yaml"},
+		}).Load(context.Background())
+		require.NoError(t, err)
+
+		m := New(db.NewSession(), &Options{TableName: "migration", RecordStatus: true}, loaded)
+		plan, err := m.Plan(Up, TargetLatest)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"201608301400", "201609011200", "201609021200"}, planIDs(plan))
+		assert.Equal(t, []string{"overlays/fork.yaml: up", "overlays/fork.yaml: down"}, plan[2].Overrides)
+
+		assert.NoError(t, m.Migrate())
+		exists, err := db.IsTableExist("pet")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+		exists, err = db.IsTableExist("book")
+		assert.NoError(t, err)
+		assert.True(t, exists)
+		var person Person
+		has, err := db.Get(&person)
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.Equal(t, "fork", person.Name)
+
+		status, err := m.Status()
+		require.NoError(t, err)
+		assert.Equal(t, "pets are managed by another service", status.Applied[1].SkipReason)
+
+		assert.NoError(t, m.RollbackTo("201609011200"))
+		exists, err = db.IsTableExist("book")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
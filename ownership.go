@@ -0,0 +1,116 @@
+package xormigrate
+
+import (
+	"sort"
+	"strings"
+)
+
+// touches returns the tables m touches, see Migration.Touches.
+func (m *Migration) touches() []string {
+	if len(m.Touches) > 0 {
+		return m.Touches
+	}
+	if len(m.Tables) > 0 {
+		return m.Tables
+	}
+	return touchedTables([]*Migration{m})
+}
+
+// MigrationsTouching returns the sorted IDs of the applied migrations that
+// touched table, e.g. "orders", compared case-insensitively. The tables
+// stored with Options.RecordTouches are used when available, the definitions
+// of the migrations otherwise. Like Pending, it never creates the migration
+// table: none are returned if it does not exist.
+func (x *Xormigrate) MigrationsTouching(table string) ([]string, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	initialized, err := x.initialized()
+	if err != nil || !initialized {
+		return nil, err
+	}
+	records, err := x.backend.listRecords()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*Migration, len(x.migrations))
+	for _, migration := range x.migrations {
+		byID[migration.ID] = migration
+	}
+	var ids []string
+	for _, record := range records {
+		var touches []string
+		if record.Touches != "" {
+			touches = strings.Split(record.Touches, ",")
+		} else if migration, ok := byID[record.ID]; ok {
+			touches = migration.touches()
+		}
+		if containsTable(touches, table) {
+			ids = append(ids, record.ID)
+		}
+	}
+	return ids, nil
+}
+
+// PendingOwners returns the IDs of the pending migrations, in order, by the
+// team owning the tables they touch according to Options.TableOwners, e.g.
+// to request the review of a release from these teams. The migrations
+// touching tables without an owner, or whose tables are unknown, e.g. Go
+// migrations without Touches, are listed under "". Like Pending, it is
+// strictly read-only.
+func (x *Xormigrate) PendingOwners() (map[string][]string, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	pending, err := x.pending()
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[string][]string)
+	for _, migration := range pending {
+		if x.skipReason(migration) != "" {
+			continue
+		}
+		teams := x.tableOwners(migration.touches())
+		if len(teams) == 0 {
+			teams = []string{""}
+		}
+		for _, team := range teams {
+			owners[team] = append(owners[team], migration.ID)
+		}
+	}
+	return owners, nil
+}
+
+// tableOwners returns the sorted teams owning tables, "" standing for the
+// tables without an owner.
+func (x *Xormigrate) tableOwners(tables []string) []string {
+	var teams []string
+	for _, table := range tables {
+		team := ""
+		for owned, owner := range x.options.TableOwners {
+			if strings.EqualFold(owned, table) {
+				team = owner
+				break
+			}
+		}
+		if !contains(teams, team) {
+			teams = append(teams, team)
+		}
+	}
+	sort.Strings(teams)
+	return teams
+}
+
+// containsTable reports whether tables contains table, compared
+// case-insensitively.
+func containsTable(tables []string, table string) bool {
+	for _, t := range tables {
+		if strings.EqualFold(t, table) {
+			return true
+		}
+	}
+	return false
+}
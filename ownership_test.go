@@ -0,0 +1,56 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func ownershipMigrations() []*Migration {
+	return []*Migration{
+		{ID: "201608301400", UpSQL: "CREATE TABLE person (id INT PRIMARY KEY, name VARCHAR(255));", DownSQL: "DROP TABLE person;"},
+		{ID: "201608301430", UpSQL: "CREATE TABLE pet (id INT PRIMARY KEY, person_id INT);", DownSQL: "DROP TABLE pet;"},
+		{ID: "201609011200", UpSQL: "INSERT INTO person (id, name) VALUES (1, 'Alice');\nINSERT INTO pet (id, person_id) VALUES (1, 1);", DownSQL: "DELETE FROM pet;\nDELETE FROM person;"},
+		{ID: "201609021200", Touches: []string{"Book"}, Migrate: func(tx *xorm.Session) error { return nil }},
+	}
+}
+
+func TestMigrationsTouching(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration", RecordTouches: true}, ownershipMigrations())
+		assert.NoError(t, m.Migrate())
+
+		ids, err := m.MigrationsTouching("PERSON")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"201608301400", "201609011200"}, ids)
+		ids, err = m.MigrationsTouching("book")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"201609021200"}, ids)
+
+		// The stored tables outlive the definitions.
+		m = New(db.NewSession(), &Options{TableName: "migration"}, ownershipMigrations()[3:])
+		ids, err = m.MigrationsTouching("pet")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"201608301430", "201609011200"}, ids)
+	})
+}
+
+func TestPendingOwners(t *testing.T) {
+	migrations := append(ownershipMigrations(), &Migration{ID: "201609031200", Migrate: func(tx *xorm.Session) error { return nil }})
+	m := NewFake(&FakeBackend{}, &Options{TableOwners: map[string]string{
+		"person": "identity",
+		"pet":    "pets",
+		"book":   "catalog",
+	}}, migrations)
+	assert.NoError(t, m.MigrateTo("201608301400"))
+
+	owners, err := m.PendingOwners()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"identity": {"201609011200"},
+		"pets":     {"201608301430", "201609011200"},
+		"catalog":  {"201609021200"},
+		"":         {"201609031200"},
+	}, owners)
+}
@@ -0,0 +1,135 @@
+package xormigrate
+
+// Plan returns, in order, the migrations Run would apply or roll back for the
+// direction and target, without running anything, e.g. to review a deploy
+// or gate it in CI. Like Pending, it is strictly read-only: when the
+// migration table does not exist, no migration is considered applied.
+//
+// Migrations skipped because of Skip or their Dialects are not part of the plan
+// going up, and neither are any when InitSchema would initialize the schema
+// instead. The Overrides of the planned migrations show how an OverlaySource
+// changed them.
+func (x *Xormigrate) Plan(direction Direction, target Target) ([]*Migration, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	if len(x.migrations) == 0 && x.initSchema == nil {
+		return nil, ErrNoMigrationDefined
+	}
+	if err := target.check(direction); err != nil {
+		return nil, err
+	}
+	if err := x.checkReservedID(); err != nil {
+		return nil, err
+	}
+	if err := x.checkDuplicatedID(); err != nil {
+		return nil, err
+	}
+	if err := x.checkDependencies(); err != nil {
+		return nil, err
+	}
+	if target.kind == targetID {
+		if err := x.checkIDExist(target.id); err != nil {
+			return nil, err
+		}
+	}
+	initialized, err := x.initialized()
+	if err != nil {
+		return nil, err
+	}
+	ran := x.migrationRan
+	if !initialized {
+		ran = func(*Migration) (bool, error) {
+			return false, nil
+		}
+	}
+	if direction == Down {
+		return x.planDown(target, ran)
+	}
+	return x.planUp(target, ran, initialized)
+}
+
+func (x *Xormigrate) planUp(target Target, ran func(m *Migration) (bool, error), initialized bool) ([]*Migration, error) {
+	if x.initSchema != nil {
+		canInitializeSchema := !initialized
+		if initialized {
+			var err error
+			if canInitializeSchema, err = x.canInitializeSchema(); err != nil {
+				return nil, err
+			}
+		}
+		if canInitializeSchema {
+			return nil, nil
+		}
+	}
+	migrationID := ""
+	if target.kind == targetID {
+		migrationID = target.id
+	}
+	plan, err := x.runOrder(migrationID)
+	if err != nil {
+		return nil, err
+	}
+	if selectPlan := selectUp(target, ran); selectPlan != nil {
+		if plan, err = selectPlan(plan); err != nil {
+			return nil, err
+		}
+	}
+	var pending []*Migration
+	for _, migration := range plan {
+		migrationRan, err := ran(migration)
+		if err != nil {
+			return nil, err
+		}
+		if !migrationRan && x.skipReason(migration) == "" {
+			pending = append(pending, migration)
+		}
+	}
+	if target.kind == targetLatest {
+		var outdated []*Migration
+		if initialized {
+			var err error
+			if outdated, err = x.outdatedRepeatables(); err != nil {
+				return nil, err
+			}
+		} else {
+			for _, migration := range x.migrations {
+				if migration.Repeatable {
+					outdated = append(outdated, migration)
+				}
+			}
+		}
+		for _, migration := range outdated {
+			if x.skipReason(migration) == "" {
+				pending = append(pending, migration)
+			}
+		}
+	}
+	return pending, nil
+}
+
+func (x *Xormigrate) planDown(target Target, ran func(m *Migration) (bool, error)) ([]*Migration, error) {
+	var applied []*Migration
+	for _, migration := range x.migrations {
+		if migration.Repeatable {
+			continue
+		}
+		migrationRan, err := ran(migration)
+		if err != nil {
+			return nil, err
+		}
+		if migrationRan {
+			applied = append(applied, migration)
+		}
+	}
+	selected, err := x.selectDown(target)(applied)
+	if err != nil {
+		return nil, err
+	}
+	rollbacks := make([]*Migration, 0, len(selected))
+	for i := len(selected) - 1; i >= 0; i-- {
+		rollbacks = append(rollbacks, selected[i])
+	}
+	return rollbacks, nil
+}
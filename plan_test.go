@@ -0,0 +1,88 @@
+package xormigrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func planIDs(plan []*Migration) []string {
+	var ids []string
+	for _, migration := range plan {
+		ids = append(ids, migration.ID)
+	}
+	return ids
+}
+
+func TestPlan(t *testing.T) {
+	for _, test := range []struct {
+		direction Direction
+		target    Target
+		plan      []string
+	}{
+		{Up, TargetLatest, []string{"201609011200", "201609021200"}},
+		{Up, TargetSteps(1), []string{"201609011200"}},
+		{Up, TargetTag("expand"), []string{"201609011200", "201609021200"}},
+		{Down, TargetSteps(1), []string{"201608301430"}},
+		{Down, TargetID("201608301400"), []string{"201608301430"}},
+		{Down, TargetTime(time.Date(2016, 8, 1, 0, 0, 0, 0, time.UTC)), []string{"201608301430", "201608301400"}},
+	} {
+		t.Run(test.direction.String()+" "+test.target.String(), func(t *testing.T) {
+			backend := &FakeBackend{}
+			m := NewFake(backend, &Options{}, targetMigrations())
+			assert.NoError(t, m.MigrateTo("201608301430"))
+			ops := backend.Ops()
+
+			plan, err := m.Plan(test.direction, test.target)
+			assert.NoError(t, err)
+			assert.Equal(t, test.plan, planIDs(plan))
+			assert.Equal(t, ops, backend.Ops())
+		})
+	}
+
+	m := NewFake(&FakeBackend{}, &Options{}, targetMigrations())
+	_, err := m.Plan(Down, TargetLatest)
+	assert.Equal(t, ErrInvalidTarget, err)
+	_, err = m.Plan(Up, TargetID("unknown"))
+	assert.Equal(t, ErrMigrationIDDoesNotExist, err)
+}
+
+func TestPlanDatabase(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+
+		plan, err := m.Plan(Up, TargetLatest)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"201608301400", "201608301430"}, planIDs(plan))
+		plan, err = m.Plan(Down, TargetSteps(1))
+		assert.NoError(t, err)
+		assert.Empty(t, plan)
+		exists, err := db.IsTableExist("migration")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+
+		assert.NoError(t, m.MigrateTo("201608301400"))
+		plan, err = m.Plan(Up, TargetLatest)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"201608301430"}, planIDs(plan))
+		assert.Equal(t, int64(1), tableCount(t, db))
+	})
+}
+
+func TestPlanInvalidDependencies(t *testing.T) {
+	m := NewFake(&FakeBackend{}, &Options{}, []*Migration{{ID: "a", DependsOn: []string{"a"}}})
+	_, err := m.Plan(Up, TargetLatest)
+	assert.Equal(t, &DependencyError{ID: "a", DependsOn: "a"}, err)
+
+	// Cycles are rejected by checkDependencies, and by runOrder anyway.
+	m = NewFake(&FakeBackend{}, &Options{}, []*Migration{
+		{ID: "a", DependsOn: []string{"b"}},
+		{ID: "b", DependsOn: []string{"a"}},
+	})
+	_, err = m.Plan(Up, TargetLatest)
+	assert.Equal(t, &DependencyError{ID: "a", DependsOn: "b"}, err)
+	_, err = m.runOrder("")
+	assert.Equal(t, &DependencyError{ID: "a", DependsOn: "b"}, err)
+}
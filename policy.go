@@ -0,0 +1,62 @@
+package xormigrate
+
+import (
+	"fmt"
+	"time"
+)
+
+// PolicyFunc decides whether a migration may run in the given environment.
+// Returning an error prevents it from running, and fails the run.
+type PolicyFunc func(m *Migration, env RunEnv) error
+
+// RunEnv describes the conditions a migration is about to run in.
+type RunEnv struct {
+	// Rollback is set when the migration is about to be rolled back.
+	Rollback bool
+	// Dialect is the database type, named after xorm's schemas.DBType.
+	Dialect string
+	// Time is when the migration is about to run.
+	Time time.Time
+
+	values map[interface{}]interface{}
+}
+
+// Value returns the value attached to the run with WithValue, or nil.
+func (env RunEnv) Value(key interface{}) interface{} {
+	return env.values[key]
+}
+
+// PolicyError is returned when Options.Policy prevents a migration from running
+type PolicyError struct {
+	ID       string
+	Rollback bool
+	Err      error
+}
+
+func (e *PolicyError) Error() string {
+	action := "Migration"
+	if e.Rollback {
+		action = "Rollback"
+	}
+	return fmt.Sprintf(`xormigrate: %s of "%s" denied by policy: %v`, action, e.ID, e.Err)
+}
+
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
+
+func (x *Xormigrate) checkPolicy(m *Migration, rollback bool) error {
+	if x.options.Policy == nil {
+		return nil
+	}
+	env := RunEnv{
+		Rollback: rollback,
+		Dialect:  x.backend.dialect(),
+		Time:     x.options.Clock.Now(),
+		values:   x.values,
+	}
+	if err := x.options.Policy(m, env); err != nil {
+		return &PolicyError{ID: m.ID, Rollback: rollback, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+type ticketKey struct{}
+
+func TestPolicy(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		errNoTicket := errors.New("rollbacks require a ticket")
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			Policy: func(m *Migration, env RunEnv) error {
+				if env.Rollback && env.Value(ticketKey{}) == nil {
+					return errNoTicket
+				}
+				return nil
+			},
+		}, migrations)
+
+		assert.NoError(t, m.Migrate())
+		err := m.RollbackLast()
+		var policyError *PolicyError
+		if assert.True(t, errors.As(err, &policyError)) {
+			assert.Equal(t, "201608301430", policyError.ID)
+			assert.True(t, policyError.Rollback)
+		}
+		assert.True(t, errors.Is(err, errNoTicket))
+		assert.Equal(t, int64(2), tableCount(t, db))
+
+		m.WithValue(ticketKey{}, "OPS-1234")
+		assert.NoError(t, m.RollbackLast())
+		assert.Equal(t, int64(1), tableCount(t, db))
+	})
+}
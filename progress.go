@@ -0,0 +1,95 @@
+package xormigrate
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DurationHistory provides how long migrations took when they were applied
+// before, e.g. in a staging environment, to estimate how long a run takes.
+type DurationHistory interface {
+	// Duration returns the historical duration of the migration, and
+	// whether it is known.
+	Duration(id string) (time.Duration, bool)
+}
+
+// Durations is a DurationHistory backed by a map of durations by migration
+// ID. Registered as a listener, it records the duration of the migrations
+// applied, so that it can be exported and imported as the history of the
+// next environment. Its JSON format is an object mapping the IDs to
+// durations, e.g. {"201608301400": "3m2.5s"}.
+type Durations map[string]time.Duration
+
+// Duration returns d[id].
+func (d Durations) Duration(id string) (time.Duration, bool) {
+	duration, ok := d[id]
+	return duration, ok
+}
+
+// OnEvent records the duration of the applied migrations.
+func (d Durations) OnEvent(event Event) {
+	if e, ok := event.(*MigrationApplied); ok {
+		d[e.ID] = e.Duration
+	}
+}
+
+// MarshalJSON encodes the durations as strings, e.g. "3m2.5s".
+func (d Durations) MarshalJSON() ([]byte, error) {
+	durations := make(map[string]string, len(d))
+	for id, duration := range d {
+		durations[id] = duration.String()
+	}
+	return json.Marshal(durations)
+}
+
+// UnmarshalJSON decodes durations encoded by MarshalJSON, adding them to d.
+func (d *Durations) UnmarshalJSON(data []byte) error {
+	var durations map[string]string
+	if err := json.Unmarshal(data, &durations); err != nil {
+		return err
+	}
+	if *d == nil {
+		*d = make(Durations, len(durations))
+	}
+	for id, value := range durations {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		(*d)[id] = duration
+	}
+	return nil
+}
+
+// pendingIn returns the migrations of plan that a run would apply.
+func (x *Xormigrate) pendingIn(plan []*Migration) ([]*Migration, error) {
+	var pending []*Migration
+	for _, migration := range plan {
+		migrationRan, err := x.migrationRan(migration)
+		if err != nil {
+			return nil, err
+		}
+		if !migrationRan && x.skipReason(migration) == "" {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// emitStarting emits the MigrationStarting event of pending[i].
+func (x *Xormigrate) emitStarting(pending []*Migration, i int) {
+	event := &MigrationStarting{ID: pending[i].ID, Description: pending[i].Description, Index: i + 1, Total: len(pending)}
+	if x.options.History != nil {
+		for j, migration := range pending[i:] {
+			duration, ok := x.options.History.Duration(migration.ID)
+			if !ok {
+				continue
+			}
+			if j == 0 {
+				event.Expected = duration
+			}
+			event.Remaining += duration
+		}
+	}
+	x.emit(event)
+}
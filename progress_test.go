@@ -0,0 +1,66 @@
+package xormigrate
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestProgress(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		noop := func(tx *xorm.Session) error { return nil }
+		logger := &recordingLogger{}
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			Logger:    logger,
+			LogLevel:  LogDebug,
+			History: Durations{
+				"201608301430": 3 * time.Minute,
+				"201608301500": 90 * time.Second,
+			},
+		}, []*Migration{
+			{ID: "201608301400", Migrate: noop},
+			{ID: "201608301430", Migrate: noop},
+			{ID: "201608301445", Dialects: []string{"none"}, Migrate: noop},
+			{ID: "201608301500", Migrate: noop},
+		})
+		assert.NoError(t, m.MigrateTo("201608301400"))
+
+		var events []MigrationStarting
+		durations := Durations{}
+		m.AddListener(durations)
+		m.AddListener(ListenerFunc(func(event Event) {
+			if e, ok := event.(*MigrationStarting); ok {
+				started := *e
+				started.RunInfo = RunInfo{}
+				events = append(events, started)
+			}
+		}))
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, []MigrationStarting{
+			{ID: "201608301430", Index: 1, Total: 2, Expected: 3 * time.Minute, Remaining: 270 * time.Second},
+			{ID: "201608301500", Index: 2, Total: 2, Expected: 90 * time.Second, Remaining: 90 * time.Second},
+		}, events)
+		assert.Equal(t, 1, logger.count("debug xormigrate: Applying 1/2: 201608301430 (historically ~3m0s, ~4m30s left)"))
+		assert.Equal(t, 1, logger.count("debug xormigrate: Applying 1/1: 201608301400"))
+
+		_, ok := durations.Duration("201608301500")
+		assert.True(t, ok)
+		_, ok = durations.Duration("201608301445")
+		assert.False(t, ok)
+	})
+}
+
+func TestDurationsJSON(t *testing.T) {
+	data, err := json.Marshal(Durations{"201608301400": 3*time.Minute + 2500*time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"201608301400":"3m2.5s"}`, string(data))
+
+	var durations Durations
+	assert.NoError(t, json.Unmarshal(data, &durations))
+	assert.Equal(t, Durations{"201608301400": 3*time.Minute + 2500*time.Millisecond}, durations)
+	assert.Error(t, json.Unmarshal([]byte(`{"201608301400":"soon"}`), &durations))
+}
@@ -0,0 +1,94 @@
+package xormigrate
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Protection identifies a protected database, on which the following
+// operations are refused unless Options.Force is set:
+//   - RollbackTo, RollbackGroup and Restore, which undo or rewrite the
+//     history in bulk, with a *ProtectedError;
+//   - rollbacks destroying data, see Migration.DestructiveRollback, with a
+//     *DestructiveRollbackError.
+type Protection struct {
+	// Enabled protects the database explicitly.
+	Enabled bool
+	// DSNPatterns protect the database when its data source name matches
+	// one of them, e.g. regexp.MustCompile(`prod`), so that scripts
+	// pointed at the wrong database are stopped.
+	DSNPatterns []*regexp.Regexp
+}
+
+// ProtectedError is returned when an operation is refused on a protected
+// database, see Options.Protection.
+type ProtectedError struct {
+	Operation string
+}
+
+func (e *ProtectedError) Error() string {
+	return fmt.Sprintf("xormigrate: %s refused on a protected database unless forced", e.Operation)
+}
+
+// DestructiveRollbackError is returned when a rollback destroying data is
+// refused on a protected database, see Options.Protection.
+type DestructiveRollbackError struct {
+	ID string
+}
+
+func (e *DestructiveRollbackError) Error() string {
+	return fmt.Sprintf(`xormigrate: Rollback of "%s" destroys data, refused on a protected database unless forced`, e.ID)
+}
+
+// destructiveRollback reports whether rolling back m destroys data: it is
+// declared with DestructiveRollback, or its DownSQL, or one of its
+// variants, has destructive statements, e.g. DROP COLUMN.
+func (m *Migration) destructiveRollback() bool {
+	if m.DestructiveRollback {
+		return true
+	}
+	if m.Rollback != nil || m.RollbackContext != nil {
+		return false
+	}
+	for _, script := range m.downSQLs() {
+		for _, statement := range splitStatements(script) {
+			if classifyStatement(statement) == Destructive {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// protected reports whether the operations guarded by Options.Protection
+// are refused.
+func (x *Xormigrate) protected() bool {
+	protection := x.options.Protection
+	if x.options.Force {
+		return false
+	}
+	if protection.Enabled {
+		return true
+	}
+	dsn := x.backend.dataSourceName()
+	for _, pattern := range protection.DSNPatterns {
+		if pattern.MatchString(dsn) {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *Xormigrate) checkProtection(operation string) error {
+	if x.protected() {
+		return &ProtectedError{Operation: operation}
+	}
+	return nil
+}
+
+func (x *Xormigrate) checkRollbackSafety(m *Migration) error {
+	if m.destructiveRollback() && x.protected() {
+		return &DestructiveRollbackError{ID: m.ID}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package xormigrate
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestDestructiveRollback(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName:  "migration",
+			Protection: Protection{Enabled: true},
+		}, []*Migration{
+			{
+				ID:      "201608301400",
+				UpSQL:   "CREATE TABLE book (id INTEGER);",
+				DownSQL: "DROP TABLE book;",
+			},
+			{
+				ID:    "201608301430",
+				UpSQL: "CREATE INDEX idx_book_id ON book (id);",
+				Rollback: func(tx *xorm.Session) error {
+					_, err := tx.Exec("DROP INDEX idx_book_id")
+					return err
+				},
+			},
+		})
+		assert.NoError(t, m.Migrate())
+
+		// The rollback of the index is a Go function, trusted unless
+		// declared destructive.
+		assert.NoError(t, m.RollbackLast())
+
+		err := m.RollbackLast()
+		var destructiveErr *DestructiveRollbackError
+		if assert.True(t, errors.As(err, &destructiveErr)) {
+			assert.Equal(t, "201608301400", destructiveErr.ID)
+		}
+		exists, err := db.IsTableExist("book")
+		assert.NoError(t, err)
+		assert.True(t, exists)
+
+		options := *m.options
+		options.Force = true
+		assert.NoError(t, m.Clone(WithOptions(&options)).RollbackLast())
+		exists, err = db.IsTableExist("book")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
+
+func TestDeclaredDestructiveRollback(t *testing.T) {
+	m := &Migration{ID: "201608301400", DestructiveRollback: true, Rollback: func(tx *xorm.Session) error { return nil }}
+	assert.True(t, m.destructiveRollback())
+	m = &Migration{ID: "201608301400", DownSQL: "ALTER TABLE book ADD COLUMN title VARCHAR(255);"}
+	assert.False(t, m.destructiveRollback())
+}
+
+func TestProtection(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			Protection: Protection{
+				DSNPatterns: []*regexp.Regexp{regexp.MustCompile(regexp.QuoteMeta(db.DataSourceName()))},
+			},
+		}, migrations)
+		assert.NoError(t, m.Migrate())
+		snapshot, err := m.Snapshot()
+		assert.NoError(t, err)
+
+		var protectedErr *ProtectedError
+		assert.True(t, errors.As(m.RollbackTo("201608301400"), &protectedErr))
+		assert.Equal(t, "RollbackTo", protectedErr.Operation)
+		assert.True(t, errors.As(m.RollbackGroup("none"), &protectedErr))
+		assert.True(t, errors.As(m.Restore(snapshot), &protectedErr))
+		assert.NoError(t, m.RollbackLast())
+
+		unprotected := m.Clone(WithOptions(&Options{
+			TableName: "migration",
+			Protection: Protection{
+				DSNPatterns: []*regexp.Regexp{regexp.MustCompile(`^prod-`)},
+			},
+		}))
+		assert.NoError(t, unprotected.Restore(snapshot))
+		assert.NoError(t, unprotected.RollbackTo("201608301400"))
+	})
+}
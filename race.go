@@ -0,0 +1,82 @@
+package xormigrate
+
+import (
+	"strings"
+)
+
+// Several instances starting on a brand-new database race to create the
+// migration table and to initialize the schema. The losers get driver errors
+// telling that the table or the record already exists: as the winner did the
+// work, they are treated as done.
+
+// isAlreadyExists reports whether err tells that a table or a unique key
+// already exists, from its message. On MySQL, only the errors 1050 (table
+// exists) and 1062 (duplicate entry) qualify, as the other duplicate errors,
+// e.g. of a column, are genuine failures.
+func isAlreadyExists(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, s := range []string{"already exists", "already an object", "Error 1050", "Error 1062", "duplicate key", "UNIQUE constraint failed", "23505", "42P07"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isolated runs fn in a savepoint when in the run transaction, so that the
+// transaction remains usable if it fails. Databases without transactional
+// DDL, e.g. MySQL, commit implicitly the DDL of fn, which destroys the
+// savepoint: fn runs directly there. As fn may have failed because of
+// another instance, the records are reloaded afterwards.
+func (x *Xormigrate) isolated(savepoint string, fn func() error) (err error) {
+	defer func() {
+		if err != nil {
+			x.forgetRan()
+		}
+	}()
+	if x.session != nil && inTransaction(x.session) && transactionalDDL(x.backend.dialect()) {
+		return withSavepoint(x.session, savepoint, fn)
+	}
+	return fn()
+}
+
+// createTableRacing creates the migration table, or upgrades it if another
+// instance created it concurrently.
+func (x *Xormigrate) createTableRacing() error {
+	err := x.isolated("xormigrate_create_table", x.backend.createTable)
+	if !isAlreadyExists(err) {
+		return err
+	}
+	exists, existsErr := x.backend.tableExists()
+	if existsErr != nil || !exists {
+		return err
+	}
+	return x.backend.upgradeTable()
+}
+
+// createStepTableRacing creates the step table, unless another instance
+// created it concurrently.
+func (x *Xormigrate) createStepTableRacing() error {
+	err := x.isolated("xormigrate_create_step_table", x.backend.createStepTable)
+	if isAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// initSchemaRacing initializes the schema, unless another instance did it
+// concurrently.
+func (x *Xormigrate) initSchemaRacing() error {
+	err := x.isolated("xormigrate_init_schema", x.runInitSchema)
+	if !isAlreadyExists(err) {
+		return err
+	}
+	initialized, ranErr := x.migrationRan(&Migration{ID: initSchemaMigrationID})
+	if ranErr != nil || !initialized {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestInitSchemaRace(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+		m.InitSchema(func(tx *xorm.Session) error {
+			// Another instance initializes the schema meanwhile.
+			_, err := db.Table("migration").Insert(&migrationRecord{ID: initSchemaMigrationID})
+			return err
+		})
+		assert.NoError(t, m.Migrate())
+
+		count, err := db.Table("migration").Count(&migrationRecord{})
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, count)
+	})
+}
+
+func TestCreateTableRace(t *testing.T) {
+	backend := &FakeBackend{}
+	backend.Fail = func(op string) error {
+		if op != "create table" {
+			return nil
+		}
+		// Another instance creates the table meanwhile.
+		backend.table, backend.records = true, make(map[string]migrationRecord)
+		return errors.New(`pq: relation "migrations" already exists`)
+	}
+	m := NewFake(backend, &Options{}, []*Migration{{ID: "201608301400", Migrate: func(tx *xorm.Session) error { return nil }}})
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, []string{"201608301400"}, backend.Applied())
+}
+
+func TestIsAlreadyExists(t *testing.T) {
+	assert.True(t, isAlreadyExists(errors.New("Error 1050: Table 'migrations' already exists")))
+	assert.True(t, isAlreadyExists(errors.New("Error 1062: Duplicate entry 'SCHEMA_INIT' for key 'PRIMARY'")))
+	assert.True(t, isAlreadyExists(errors.New("UNIQUE constraint failed: migration.id")))
+	assert.False(t, isAlreadyExists(errors.New("syntax error")))
+}
+
+func TestIsAlreadyExistsMySQL(t *testing.T) {
+	assert.False(t, isAlreadyExists(errors.New("Error 1060: Duplicate column name 'name'")))
+	assert.True(t, isAlreadyExists(errors.New("Error 1062 (23000): Duplicate entry 'SCHEMA_INIT' for key 'PRIMARY'")))
+}
@@ -0,0 +1,77 @@
+package xormigrate
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Registry collects migrations registered from the init functions of the
+// files defining them, so that each migration can live in its own file
+// instead of a single slice edited by everyone:
+//
+//	func init() {
+//		xormigrate.Register(&xormigrate.Migration{
+//			ID:      "201608301400",
+//			Migrate: createPersons,
+//		})
+//	}
+//
+// It is a Source providing the registered migrations.
+type Registry struct {
+	mu         sync.Mutex
+	migrations []*Migration
+	ids        map[string]bool
+}
+
+// DefaultRegistry is the registry of Register and Registered.
+var DefaultRegistry = &Registry{}
+
+// Register adds the migration to the registry. It panics if the migration
+// has no ID or if a migration with the same ID is already registered, which
+// is a programming error.
+func (r *Registry) Register(m *Migration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m.ID == "" {
+		panic(ErrMissingID)
+	}
+	if r.ids[m.ID] {
+		panic(&DuplicatedIDError{ID: m.ID})
+	}
+	if r.ids == nil {
+		r.ids = make(map[string]bool)
+	}
+	r.ids[m.ID] = true
+	r.migrations = append(r.migrations, m)
+}
+
+// Migrations returns the registered migrations, sorted by ID, as init
+// functions run in no meaningful order.
+func (r *Registry) Migrations() []*Migration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	migrations := append([]*Migration(nil), r.migrations...)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].ID < migrations[j].ID
+	})
+	return migrations
+}
+
+// Load returns the registered migrations, sorted by ID.
+func (r *Registry) Load(ctx context.Context) ([]*Migration, error) {
+	return r.Migrations(), nil
+}
+
+// Register adds the migration to DefaultRegistry, see Registry.Register.
+func Register(m *Migration) {
+	DefaultRegistry.Register(m)
+}
+
+// Registered returns the migrations of DefaultRegistry, sorted by ID, e.g.
+// to pass them to New.
+func Registered() []*Migration {
+	return DefaultRegistry.Migrations()
+}
@@ -0,0 +1,32 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestRegistry(t *testing.T) {
+	saved := DefaultRegistry
+	DefaultRegistry = &Registry{}
+	defer func() { DefaultRegistry = saved }()
+
+	// In the order of the files registering them.
+	Register(extendedMigrations[2])
+	Register(extendedMigrations[0])
+	Register(extendedMigrations[1])
+	assert.Equal(t, extendedMigrations, Registered())
+	assert.PanicsWithError(t, `xormigrate: Duplicated migration ID: "201608301400"`, func() {
+		Register(&Migration{ID: "201608301400"})
+	})
+	assert.PanicsWithValue(t, ErrMissingID, func() {
+		Register(&Migration{})
+	})
+
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, Registered())
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, int64(3), tableCount(t, db))
+	})
+}
@@ -0,0 +1,99 @@
+package xormigrate
+
+import (
+	"errors"
+	"fmt"
+
+	"xorm.io/xorm/schemas"
+)
+
+// ErrNoTransactionalDDL is returned when rehearsing migrations on a database
+// whose DDL statements can't be rolled back, e.g. MySQL
+var ErrNoTransactionalDDL = errors.New("xormigrate: Can't rehearse migrations without transactional DDL")
+
+// RehearsalError is returned when the rehearsal of Options.Rehearse failed,
+// in which case nothing was applied.
+type RehearsalError struct {
+	Err error
+}
+
+func (e *RehearsalError) Error() string {
+	return fmt.Sprintf("xormigrate: Rehearsal failed, nothing was applied: %v", e.Err)
+}
+
+func (e *RehearsalError) Unwrap() error {
+	return e.Err
+}
+
+// transactionalDDL reports whether the DDL statements of the dialect can be
+// rolled back.
+func transactionalDDL(dialect string) bool {
+	switch schemas.DBType(dialect) {
+	case schemas.POSTGRES, schemas.MSSQL, schemas.SQLITE:
+		return true
+	}
+	return false
+}
+
+// Rehearse runs the migrations Migrate would apply in a transaction which is
+// rolled back instead of committed, to check that they execute cleanly, e.g.
+// before the write window of a production deployment opens. The result lists
+// the rehearsed migrations: the rehearsal stops before the first
+// NoTransaction or LongRunning migration, which can't run in the
+// transaction. Listeners and the hooks of Options are not called.
+//
+// ErrNoTransactionalDDL is returned on databases that can't roll back DDL
+// statements, i.e. other than PostgreSQL, SQL Server and SQLite.
+func (x *Xormigrate) Rehearse() (*RunResult, error) {
+	return x.withResult(nil, func() error {
+		return x.rehearse("", nil)
+	})
+}
+
+// rehearse rehearses the migrations migrate would apply.
+func (x *Xormigrate) rehearse(migrationID string, selectPlan func(plan []*Migration) ([]*Migration, error)) error {
+	if !transactionalDDL(x.backend.dialect()) {
+		return ErrNoTransactionalDDL
+	}
+
+	saved, listeners := x.options, x.listeners
+	options := *saved
+	options.UseTransaction = true
+	options.BeforeAll, options.AfterAll = nil, nil
+	options.BeforeMigration, options.AfterMigration = nil, nil
+	options.Analyze, options.Vacuum = false, false
+	x.options, x.listeners, x.rehearsing = &options, nil, true
+	defer func() {
+		x.options, x.listeners, x.rehearsing = saved, listeners, false
+	}()
+	if x.session != nil {
+		// As in withoutTransaction, xorm keeps running some queries
+		// against the transaction of a session once it is rolled back.
+		session := x.session
+		x.session = session.Engine().NewSession()
+		defer func() {
+			x.session.Close()
+			x.session = session
+		}()
+	}
+
+	for _, migration := range x.migrations {
+		if migration.NoTransaction || migration.LongRunning {
+			return x.migrate(migrationID, func(plan []*Migration) ([]*Migration, error) {
+				if selectPlan != nil {
+					var err error
+					if plan, err = selectPlan(plan); err != nil {
+						return nil, err
+					}
+				}
+				for i, migration := range plan {
+					if migration.NoTransaction || migration.LongRunning {
+						return plan[:i], nil
+					}
+				}
+				return plan, nil
+			})
+		}
+	}
+	return x.migrate(migrationID, selectPlan)
+}
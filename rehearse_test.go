@@ -0,0 +1,76 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xorm.io/xorm"
+)
+
+func TestRehearse(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		if !transactionalDDL(string(db.Dialect().URI().DBType)) {
+			t.Skip("no transactional DDL")
+		}
+		broken := []*Migration{
+			{ID: "201608301400", UpSQL: "CREATE TABLE person (name VARCHAR(255));"},
+			{ID: "201608301430", UpSQL: "CREATE TABLE pet (name VARCHAR(255), person_id INTEGER;"},
+		}
+		m := New(db.NewSession(), &Options{TableName: "migration"}, broken)
+		_, err := m.Rehearse()
+		assert.Error(t, err)
+		for _, table := range []string{"migration", "person"} {
+			has, err := db.IsTableExist(table)
+			assert.NoError(t, err)
+			assert.False(t, has, table)
+		}
+
+		m = New(db.NewSession(), &Options{TableName: "migration", Rehearse: true}, broken)
+		var rehearsalErr *RehearsalError
+		assert.True(t, errors.As(m.Migrate(), &rehearsalErr))
+		has, err := db.IsTableExist("person")
+		assert.NoError(t, err)
+		assert.False(t, has)
+
+		broken[1].UpSQL = "CREATE TABLE pet (name VARCHAR(255), person_id INTEGER);"
+		m = New(db.NewSession(), &Options{TableName: "migration"}, broken)
+		result, err := m.Rehearse()
+		require.NoError(t, err)
+		assert.Len(t, result.Applied, 2)
+		has, err = db.IsTableExist("person")
+		assert.NoError(t, err)
+		assert.False(t, has)
+
+		m = New(db.NewSession(), &Options{TableName: "migration", Rehearse: true}, broken)
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, int64(2), tableCount(t, db))
+	})
+}
+
+func TestRehearseFake(t *testing.T) {
+	m := NewFake(&FakeBackend{Dialect: "mysql"}, &Options{}, targetMigrations())
+	_, err := m.Rehearse()
+	assert.Equal(t, ErrNoTransactionalDDL, err)
+
+	migrations := targetMigrations()
+	migrations[2].NoTransaction = true
+	backend := &FakeBackend{Dialect: "postgres"}
+	var hooks int
+	m = NewFake(backend, &Options{BeforeMigration: func(*Migration) { hooks++ }}, migrations)
+	var events []Event
+	m.AddListener(ListenerFunc(func(event Event) { events = append(events, event) }))
+	result, err := m.Rehearse()
+	require.NoError(t, err)
+	// The rehearsal stops before the NoTransaction migration.
+	assert.Len(t, result.Applied, 2)
+	assert.Empty(t, backend.Applied())
+	assert.Zero(t, hooks)
+	assert.Empty(t, events)
+	assert.False(t, m.options.UseTransaction)
+
+	assert.NoError(t, m.Migrate())
+	assert.Len(t, backend.Applied(), 4)
+	assert.Equal(t, 4, hooks)
+}
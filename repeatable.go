@@ -0,0 +1,71 @@
+package xormigrate
+
+import "strings"
+
+// hasRepeatables reports whether a migration is repeatable, requiring the
+// "checksum" column.
+func (x *Xormigrate) hasRepeatables() bool {
+	for _, migration := range x.migrations {
+		if migration.Repeatable {
+			return true
+		}
+	}
+	return false
+}
+
+// outdatedRepeatables returns, in order, the repeatable migrations that did
+// not run yet or whose checksum changed since they last ran.
+func (x *Xormigrate) outdatedRepeatables() ([]*Migration, error) {
+	if !x.hasRepeatables() {
+		return nil, nil
+	}
+	records, err := x.backend.listRecords()
+	if err != nil {
+		return nil, err
+	}
+	recorded := make(map[string]*migrationRecord, len(records))
+	for i := range records {
+		recorded[records[i].ID] = &records[i]
+	}
+	var outdated []*Migration
+	for _, m := range x.migrations {
+		if !m.Repeatable {
+			continue
+		}
+		if record, ok := recorded[m.ID]; !ok || !strings.EqualFold(record.Checksum, m.checksum()) {
+			outdated = append(outdated, m)
+		}
+	}
+	return outdated, nil
+}
+
+// runRepeatables applies the outdated repeatable migrations, replacing the
+// records of the ones which already ran.
+func (x *Xormigrate) runRepeatables() error {
+	outdated, err := x.outdatedRepeatables()
+	if err != nil {
+		return err
+	}
+	for _, migration := range outdated {
+		if err := x.context().Err(); err != nil {
+			return err
+		}
+		migrationRan, err := x.migrationRan(migration)
+		if err != nil {
+			return err
+		}
+		if migrationRan {
+			if err := x.backend.deleteRecord(migration.ID); err != nil {
+				return err
+			}
+			delete(x.ran, migration.ID)
+		}
+		if err := x.runMigration(migration); err != nil {
+			return x.compensate(err)
+		}
+		if err := x.checkTransactionAge(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package xormigrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xorm.io/xorm"
+)
+
+func TestRepeatable(t *testing.T) {
+	var ran []string
+	stub := func(id string) *Migration {
+		return &Migration{
+			ID: id,
+			Migrate: func(tx *xorm.Session) error {
+				ran = append(ran, id)
+				return nil
+			},
+			Rollback: func(tx *xorm.Session) error { return nil },
+		}
+	}
+	seed := stub("R__seed_countries")
+	seed.Repeatable, seed.Checksum = true, "v1"
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{}, []*Migration{seed, stub("201608301400"), stub("201608301430")})
+
+	assert.NoError(t, m.MigrateTo("201608301400"))
+	assert.Equal(t, []string{"201608301400"}, ran)
+	// Repeatable migrations run after the other ones.
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, []string{"201608301400", "201608301430", "R__seed_countries"}, ran)
+	assert.NoError(t, m.Migrate())
+	assert.Len(t, ran, 3)
+	pending, err := m.Pending()
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	seed.Checksum = "v2"
+	pending, err = m.Pending()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"R__seed_countries"}, planIDs(pending))
+	plan, err := m.Plan(Up, TargetLatest)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"R__seed_countries"}, planIDs(plan))
+	assert.NoError(t, m.Verify())
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, []string{"201608301400", "201608301430", "R__seed_countries", "R__seed_countries"}, ran)
+	assert.Equal(t, "v2", backend.records["R__seed_countries"].Checksum)
+
+	// Repeatable migrations are not rolled back with the other ones.
+	assert.NoError(t, m.RollbackLast())
+	assert.Equal(t, []string{"201608301400", "R__seed_countries"}, backend.Applied())
+}
+
+func TestRepeatableSQL(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		dir := fstest.MapFS{
+			"migrations/201608301400_create_person.up.sql": {Data: []byte("CREATE TABLE person (name VARCHAR(255), age INTEGER);")},
+			"migrations/R__person_names.up.sql":            {Data: []byte("DROP VIEW IF EXISTS person_names;\nCREATE VIEW person_names AS SELECT name FROM person;")},
+		}
+		defer db.Exec("DROP VIEW IF EXISTS person_names")
+
+		loaded, err := LoadFS(dir, "migrations")
+		require.NoError(t, err)
+		assert.True(t, loaded[1].Repeatable)
+		assert.Equal(t, "person names", loaded[1].Description)
+		m := New(db.NewSession(), &Options{TableName: "migration"}, loaded)
+		assert.NoError(t, m.Migrate())
+		_, err = db.QueryString("SELECT name FROM person_names")
+		assert.NoError(t, err)
+
+		dir["migrations/R__person_names.up.sql"] = &fstest.MapFile{Data: []byte("DROP VIEW IF EXISTS person_names;\nCREATE VIEW person_names AS SELECT name, age FROM person;")}
+		loaded, err = LoadFS(dir, "migrations")
+		require.NoError(t, err)
+		m = New(db.NewSession(), &Options{TableName: "migration"}, loaded)
+		assert.NoError(t, m.Migrate())
+		_, err = db.QueryString("SELECT name, age FROM person_names")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), tableCount(t, db))
+	})
+}
@@ -0,0 +1,232 @@
+package xormigrate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ChangeKind classifies the effect of a migration on the database, from the
+// safest to the one needing the closest review.
+type ChangeKind int
+
+const (
+	// Additive changes only create objects, e.g. tables, indexes built
+	// concurrently or nullable columns.
+	Additive ChangeKind = iota
+	// DataModifying changes insert, update or delete rows.
+	DataModifying
+	// Locking changes hold locks blocking writes while they run, e.g.
+	// building an index or altering a column.
+	Locking
+	// Destructive changes drop, truncate or rename objects.
+	Destructive
+	// UnknownChange is used for Go migrations and unrecognized statements,
+	// which need a manual review.
+	UnknownChange
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Additive:
+		return "additive"
+	case DataModifying:
+		return "data-modifying"
+	case Locking:
+		return "locking"
+	case Destructive:
+		return "destructive"
+	default:
+		return "unknown"
+	}
+}
+
+// StatementChange is a statement of a SQL migration and its classification.
+type StatementChange struct {
+	SQL  string
+	Kind ChangeKind
+}
+
+// MigrationChange is the classification of a pending migration. Kind is the
+// most severe kind of its statements, or UnknownChange for a Go migration.
+// Estimate is its historical duration according to Options.History, zero when
+// unknown.
+type MigrationChange struct {
+	ID          string
+	Description string
+	Kind        ChangeKind
+	Statements  []StatementChange
+	Estimate    time.Duration
+}
+
+// PendingChanges classifies the migrations that did not run yet and apply to
+// the dialect of the database. SQL migrations are classified by analysing
+// their statements: as this is a heuristic, it is meant to draw the attention
+// of reviewers rather than to replace the review. Like Pending, it is strictly
+// read-only.
+func (x *Xormigrate) PendingChanges() ([]MigrationChange, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	pending, err := x.pending()
+	if err != nil {
+		return nil, err
+	}
+	var changes []MigrationChange
+	for _, migration := range pending {
+		if x.skipReason(migration) != "" {
+			continue
+		}
+		change := classifyMigration(migration, x.backend.dialect())
+		if x.options.History != nil {
+			change.Estimate, _ = x.options.History.Duration(migration.ID)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// classifyMigration classifies the SQL of m executed on the dialect.
+func classifyMigration(m *Migration, dialect string) MigrationChange {
+	change := MigrationChange{ID: m.ID, Description: m.Description, Kind: UnknownChange}
+	if m.Migrate != nil || m.MigrateContext != nil || len(m.Steps) > 0 {
+		return change
+	}
+	change.Kind = Additive
+	for _, statement := range splitStatements(m.upSQL(dialect)) {
+		kind := classifyStatement(statement)
+		change.Statements = append(change.Statements, StatementChange{SQL: statement, Kind: kind})
+		if kind > change.Kind {
+			change.Kind = kind
+		}
+	}
+	return change
+}
+
+var (
+	leadingCommentsRegexp = regexp.MustCompile(`^(\s+|--[^\n]*(\n|$)|/\*(?s:.*?)\*/)*`)
+	createIndexRegexp     = regexp.MustCompile(`^CREATE\s+(UNIQUE\s+)?INDEX\b`)
+	alterAddRegexp        = regexp.MustCompile(`^ALTER\s+TABLE\s+\S+\s+ADD\s`)
+	alterRiskyRegexp      = regexp.MustCompile(`\b(ALTER|MODIFY|CHANGE|NOT\s+NULL|PRIMARY\s+KEY|UNIQUE|FOREIGN\s+KEY|CHECK)\b`)
+	dropOrRenameRegexp    = regexp.MustCompile(`\b(DROP|RENAME)\b`)
+)
+
+// classifyStatement classifies a statement from its leading keywords.
+func classifyStatement(statement string) ChangeKind {
+	s := strings.ToUpper(leadingCommentsRegexp.ReplaceAllString(statement, ""))
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return Additive
+	}
+	switch fields[0] {
+	case "DROP", "TRUNCATE", "RENAME":
+		return Destructive
+	case "INSERT", "UPDATE", "DELETE", "MERGE", "REPLACE", "COPY":
+		return DataModifying
+	case "LOCK", "VACUUM", "CLUSTER", "REINDEX":
+		return Locking
+	case "CREATE":
+		if createIndexRegexp.MatchString(s) && !strings.Contains(s, "CONCURRENTLY") {
+			return Locking
+		}
+		return Additive
+	case "ALTER":
+		switch {
+		case dropOrRenameRegexp.MatchString(s):
+			return Destructive
+		case alterAddRegexp.MatchString(s) && !alterRiskyRegexp.MatchString(s[len("ALTER"):]):
+			return Additive
+		default:
+			return Locking
+		}
+	}
+	return UnknownChange
+}
+
+// WriteChangeReport writes a Markdown summary of changes, as returned by
+// PendingChanges, for release reviews: the number of migrations of each kind,
+// a table of the migrations and the statements needing attention. When some
+// migrations have an estimate, the table lists them with their total, to size
+// the deployment window.
+func WriteChangeReport(w io.Writer, changes []MigrationChange) error {
+	var b bytes.Buffer
+	b.WriteString("# Pending migrations\n\n")
+	if len(changes) == 0 {
+		b.WriteString("No pending migrations.\n")
+		_, err := w.Write(b.Bytes())
+		return err
+	}
+
+	counts := make(map[ChangeKind]int)
+	for _, change := range changes {
+		counts[change.Kind]++
+	}
+	for kind := Additive; kind <= UnknownChange; kind++ {
+		if counts[kind] > 0 {
+			fmt.Fprintf(&b, "- %s: %d\n", kind, counts[kind])
+		}
+	}
+
+	var total time.Duration
+	unknown := 0
+	for _, change := range changes {
+		total += change.Estimate
+		if change.Estimate == 0 {
+			unknown++
+		}
+	}
+	if total > 0 {
+		fmt.Fprintf(&b, "- estimated duration: ~%s", roughly(total))
+		if unknown > 0 {
+			fmt.Fprintf(&b, " (%d migrations without history)", unknown)
+		}
+		b.WriteString("\n")
+	}
+
+	if total > 0 {
+		b.WriteString("\n| Migration | Description | Change | Estimate |\n")
+		b.WriteString("|-----------|-------------|--------|----------|\n")
+	} else {
+		b.WriteString("\n| Migration | Description | Change |\n")
+		b.WriteString("|-----------|-------------|--------|\n")
+	}
+	for _, change := range changes {
+		fmt.Fprintf(&b, "| %s | %s | %s |", change.ID, change.Description, change.Kind)
+		switch {
+		case change.Estimate > 0:
+			fmt.Fprintf(&b, " ~%s |", roughly(change.Estimate))
+		case total > 0:
+			b.WriteString(" ? |")
+		}
+		b.WriteString("\n")
+	}
+
+	var review bytes.Buffer
+	for _, change := range changes {
+		if change.Kind == UnknownChange && len(change.Statements) == 0 {
+			fmt.Fprintf(&review, "\n### %s\n\nGo migration, review its code.\n", change.ID)
+			continue
+		}
+		header := false
+		for _, statement := range change.Statements {
+			if statement.Kind < Locking {
+				continue
+			}
+			if !header {
+				fmt.Fprintf(&review, "\n### %s\n\n", change.ID)
+				header = true
+			}
+			fmt.Fprintf(&review, "- **%s**: `%s`\n", statement.Kind, strings.Join(strings.Fields(statement.SQL), " "))
+		}
+	}
+	if review.Len() > 0 {
+		b.WriteString("\n## Needs attention\n")
+		b.Write(review.Bytes())
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,74 @@
+package xormigrate
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestClassifyStatement(t *testing.T) {
+	for statement, kind := range map[string]ChangeKind{
+		"CREATE TABLE book (id INTEGER)":                        Additive,
+		"CREATE INDEX CONCURRENTLY idx ON book (id)":            Additive,
+		"ALTER TABLE book ADD COLUMN title VARCHAR(255)":        Additive,
+		"-- Titles\nUPDATE book SET title = 'drop'":             DataModifying,
+		"CREATE UNIQUE INDEX idx ON book (id)":                  Locking,
+		"ALTER TABLE book ADD COLUMN title VARCHAR(1) NOT NULL": Locking,
+		"ALTER TABLE book ALTER COLUMN title TYPE TEXT":         Locking,
+		"ALTER TABLE book DROP COLUMN title":                    Destructive,
+		"drop table book":                                       Destructive,
+		"GRANT SELECT ON book TO reader":                        UnknownChange,
+	} {
+		assert.Equal(t, kind, classifyStatement(statement), statement)
+	}
+}
+
+func TestPendingChanges(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			History:   Durations{"2": time.Minute},
+		}, []*Migration{
+			{ID: "1", UpSQL: "CREATE TABLE book (id INTEGER);"},
+			{ID: "2", Description: "Add titles", UpSQL: "ALTER TABLE book ADD title VARCHAR(255); UPDATE book SET title = '';"},
+			{ID: "3", UpSQL: "DROP TABLE book;"},
+			{ID: "4", Migrate: func(tx *xorm.Session) error { return nil }},
+			{ID: "5", Dialects: []string{"none"}, UpSQL: "DROP TABLE book;"},
+		})
+		assert.NoError(t, m.MigrateTo("1"))
+
+		changes, err := m.PendingChanges()
+		if !assert.NoError(t, err) || !assert.Len(t, changes, 3) {
+			return
+		}
+		assert.Equal(t, DataModifying, changes[0].Kind)
+		assert.Len(t, changes[0].Statements, 2)
+		assert.Equal(t, time.Minute, changes[0].Estimate)
+		assert.Zero(t, changes[1].Estimate)
+		assert.Equal(t, Destructive, changes[1].Kind)
+		assert.Equal(t, UnknownChange, changes[2].Kind)
+
+		var b bytes.Buffer
+		assert.NoError(t, WriteChangeReport(&b, changes))
+		assert.Contains(t, b.String(), "- destructive: 1\n")
+		assert.Contains(t, b.String(), "| 2 | Add titles | data-modifying | ~1m0s |\n")
+		assert.Contains(t, b.String(), "- **destructive**: `DROP TABLE book`\n")
+		assert.Contains(t, b.String(), "Go migration, review its code.")
+	})
+}
+
+func TestChangeReportEstimates(t *testing.T) {
+	changes := []MigrationChange{
+		{ID: "1", Kind: Additive, Estimate: 90 * time.Second},
+		{ID: "2", Kind: UnknownChange, Estimate: 3 * time.Minute},
+		{ID: "3", Kind: UnknownChange},
+	}
+	var b bytes.Buffer
+	assert.NoError(t, WriteChangeReport(&b, changes))
+	assert.Contains(t, b.String(), "- estimated duration: ~4m30s (1 migrations without history)\n")
+	assert.Contains(t, b.String(), "| 1 |  | additive | ~1m30s |\n")
+	assert.Contains(t, b.String(), "| 3 |  | unknown | ? |\n")
+}
@@ -0,0 +1,34 @@
+package xormigrate
+
+// RollbackAll rolls back all the applied migrations, in reverse order, e.g.
+// to tear the schema down between integration tests without dropping the
+// database. The schema initialization, see InitSchema, is not rolled back.
+func (x *Xormigrate) RollbackAll() error {
+	_, err := x.RollbackAllResult()
+	return err
+}
+
+// RollbackAllResult is like RollbackAll, also returning the result of the run.
+func (x *Xormigrate) RollbackAllResult() (*RunResult, error) {
+	return x.withResult(nil, x.rollbackAll)
+}
+
+func (x *Xormigrate) rollbackAll() error {
+	return x.rollbackSelected("RollbackAll", func(applied []*Migration) ([]*Migration, error) {
+		return applied, nil
+	})
+}
+
+// Reset rolls back all the applied migrations, see RollbackAll, then applies
+// all the migrations again, see Migrate, in two runs.
+func (x *Xormigrate) Reset() error {
+	_, err := x.withResult(nil, x.reset)
+	return err
+}
+
+func (x *Xormigrate) reset() error {
+	if err := x.rollbackAll(); err != nil {
+		return err
+	}
+	return x.migrateAll()
+}
@@ -0,0 +1,42 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestRollbackAll(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, int64(2), tableCount(t, db))
+
+		result, err := m.RollbackAllResult()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"201608301430", "201608301400"}, result.RolledBack)
+		assert.Equal(t, int64(0), tableCount(t, db))
+		has, err := db.IsTableExist(&Person{})
+		assert.NoError(t, err)
+		assert.False(t, has)
+
+		// Nothing left to roll back.
+		assert.NoError(t, m.RollbackAll())
+	})
+}
+
+func TestReset(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+		assert.NoError(t, m.Migrate())
+		_, err := db.Insert(&Person{Name: "Alice"})
+		assert.NoError(t, err)
+
+		assert.NoError(t, m.Reset())
+		assert.Equal(t, int64(2), tableCount(t, db))
+		count, err := db.Count(&Person{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+}
@@ -0,0 +1,79 @@
+package xormigrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RunResult is the outcome of a Migrate or Rollback call, assembled from its
+// events, so that callers can report it without querying the database.
+type RunResult struct {
+	RunID    string
+	Rollback bool
+	// Applied are the migrations applied by a migration run, in order.
+	Applied []MigrationResult
+	// RolledBack are the IDs of the migrations rolled back, in order.
+	RolledBack []string
+	// Skipped are the migrations recorded without running, in order.
+	Skipped []MigrationSkipped
+	// Failed is the migration that failed the run, if any.
+	Failed *MigrationFailed
+	// Warnings describe the non-fatal issues of the run, e.g. a migration
+	// over its budget.
+	Warnings []string
+	Duration time.Duration
+	Err      error
+}
+
+// MigrationResult is a migration applied by a run.
+type MigrationResult struct {
+	ID       string
+	Duration time.Duration
+}
+
+// withResult runs fn, a Migrate or Rollback call, with ctx if not nil, and
+// returns its result, nil if the run did not start.
+func (x *Xormigrate) withResult(ctx context.Context, fn func() error) (*RunResult, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	x.ctx, x.result = ctx, nil
+	err := fn()
+	result := x.result
+	x.ctx, x.result = nil, nil
+	return result, err
+}
+
+// collect adds an event to the result of the run.
+func (x *Xormigrate) collect(event Event) {
+	if e, ok := event.(*RunStarted); ok {
+		x.result = &RunResult{RunID: e.RunID, Rollback: e.Rollback}
+		return
+	}
+	r := x.result
+	if r == nil {
+		return
+	}
+	switch e := event.(type) {
+	case *MigrationApplied:
+		r.Applied = append(r.Applied, MigrationResult{ID: e.ID, Duration: e.Duration})
+	case *RolledBack:
+		r.RolledBack = append(r.RolledBack, e.ID)
+	case *MigrationSkipped:
+		r.Skipped = append(r.Skipped, *e)
+	case *MigrationFailed:
+		r.Failed = e
+	case *MigrationRetrying:
+		r.Warnings = append(r.Warnings, fmt.Sprintf("Migration %s failed on attempt %d and was retried: %v", e.ID, e.Attempt, e.Err))
+	case *BudgetExceeded:
+		r.Warnings = append(r.Warnings, fmt.Sprintf("Migration %s took %s, over its budget of %s", e.ID, e.Duration, e.Budget))
+	case *LongTransaction:
+		r.Warnings = append(r.Warnings, fmt.Sprintf("Run transaction open for %s", e.Elapsed))
+	case *Warning:
+		r.Warnings = append(r.Warnings, e.Message)
+	case *RunFinished:
+		r.Duration, r.Err = e.Duration, e.Err
+	}
+}
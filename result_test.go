@@ -0,0 +1,57 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestRunResult(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		errFailed := errors.New("failed")
+		noop := func(tx *xorm.Session) error { return nil }
+		m := New(db.NewSession(), &Options{TableName: "migration"}, []*Migration{
+			{ID: "201608301400", Budget: time.Nanosecond, Migrate: func(tx *xorm.Session) error {
+				time.Sleep(time.Millisecond)
+				return nil
+			}, Rollback: noop},
+			{ID: "201608301430", Dialects: []string{"nodb"}, Migrate: noop},
+			{ID: "201608301500", Migrate: func(tx *xorm.Session) error { return errFailed }},
+		})
+
+		result, err := m.MigrateResult()
+		assert.ErrorIs(t, err, errFailed)
+		if assert.NotNil(t, result) {
+			assert.Len(t, result.RunID, 16)
+			assert.False(t, result.Rollback)
+			if assert.Len(t, result.Applied, 1) {
+				assert.Equal(t, "201608301400", result.Applied[0].ID)
+				assert.NotZero(t, result.Applied[0].Duration)
+			}
+			if assert.Len(t, result.Skipped, 1) {
+				assert.Equal(t, "201608301430", result.Skipped[0].ID)
+			}
+			if assert.NotNil(t, result.Failed) {
+				assert.Equal(t, "201608301500", result.Failed.ID)
+			}
+			assert.Len(t, result.Warnings, 4)
+			assert.Contains(t, result.Warnings, "Migration 201608301500 can't be rolled back")
+			assert.ErrorIs(t, result.Err, errFailed)
+		}
+
+		result, err = m.RollbackToResult("201608301400")
+		assert.NoError(t, err)
+		if assert.NotNil(t, result) {
+			assert.True(t, result.Rollback)
+			assert.Equal(t, []string{"201608301430"}, result.RolledBack)
+			assert.Nil(t, result.Failed)
+		}
+
+		result, err = m.MigrateToResult("unknown")
+		assert.Equal(t, ErrMigrationIDDoesNotExist, err)
+		assert.Nil(t, result)
+	})
+}
@@ -0,0 +1,213 @@
+package xormigrate
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// LockRetry is the standard safe DDL loop: statements run with a short lock
+// timeout, so that they don't block the queries queued behind them, and are
+// retried after a pause when it expires, with an increasing timeout.
+type LockRetry struct {
+	// Timeout is the lock timeout of the first attempt.
+	Timeout time.Duration
+	// Factor multiplies the timeout after each failed attempt, up to
+	// MaxTimeout. Values below 1 are ignored.
+	Factor float64
+	// MaxTimeout caps the timeout. Zero means no limit.
+	MaxTimeout time.Duration
+	// Attempts is the maximum number of attempts. Defaults to 1.
+	Attempts int
+	// Backoff is the pause between attempts.
+	Backoff time.Duration
+	// IsLockTimeout reports whether an error is a lock timeout. Defaults
+	// to IsLockTimeout.
+	IsLockTimeout func(err error) bool
+	// OnRetry is called after each failed attempt that is retried. Can be
+	// nil.
+	OnRetry func(attempt int, timeout time.Duration, err error)
+}
+
+// IsLockTimeout reports whether err is a lock timeout error of PostgreSQL,
+// MySQL, SQL Server or SQLite, from its message.
+func IsLockTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, s := range []string{"lock timeout", "55P03", "Lock wait timeout exceeded", "Lock request time out", "database is locked"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithLockRetry wraps fn so that it runs according to the retry strategy.
+// In a run transaction, each attempt runs in a savepoint rolled back on
+// failure.
+func WithLockRetry(retry LockRetry, fn MigrateFunc) MigrateFunc {
+	return func(tx *xorm.Session) error {
+		isLockTimeout := retry.IsLockTimeout
+		if isLockTimeout == nil {
+			isLockTimeout = IsLockTimeout
+		}
+		timeout := retry.Timeout
+		for attempt := 1; ; attempt++ {
+			err := lockRetryAttempt(tx, attempt, timeout, fn)
+			if err == nil || !isLockTimeout(err) || attempt >= retry.Attempts {
+				return err
+			}
+			if retry.OnRetry != nil {
+				retry.OnRetry(attempt, timeout, err)
+			}
+			if retry.Factor > 1 {
+				timeout = time.Duration(float64(timeout) * retry.Factor)
+			}
+			if retry.MaxTimeout > 0 && timeout > retry.MaxTimeout {
+				timeout = retry.MaxTimeout
+			}
+			select {
+			case <-time.After(retry.Backoff):
+			case <-Context(tx).Done():
+				return Context(tx).Err()
+			}
+		}
+	}
+}
+
+// lockRetryAttempt runs fn with the lock timeout, in a savepoint if tx is in
+// a transaction.
+func lockRetryAttempt(tx *xorm.Session, attempt int, timeout time.Duration, fn MigrateFunc) error {
+	if inTransaction(tx) {
+		return withSavepoint(tx, fmt.Sprintf("xormigrate_retry_%d", attempt), func() error {
+			return withLockTimeout(tx, timeout, fn)
+		})
+	}
+	return withLockTimeout(tx, timeout, fn)
+}
+
+// withSavepoint runs fn in a savepoint, rolled back if fn fails so that the
+// transaction remains usable.
+func withSavepoint(tx *xorm.Session, savepoint string, fn func() error) (err error) {
+	save, rollback, release := "SAVEPOINT "+savepoint, "ROLLBACK TO SAVEPOINT "+savepoint, "RELEASE SAVEPOINT "+savepoint
+	if tx.Engine().Dialect().URI().DBType == schemas.MSSQL {
+		save, rollback, release = "SAVE TRANSACTION "+savepoint, "ROLLBACK TRANSACTION "+savepoint, ""
+	}
+	if _, err := tx.Exec(save); err != nil {
+		return err
+	}
+	defer func() {
+		statement := release
+		if err != nil {
+			statement = rollback
+		}
+		if statement == "" {
+			return
+		}
+		if _, spErr := tx.Exec(statement); spErr != nil && err == nil {
+			err = spErr
+		}
+	}()
+	return fn()
+}
+
+// withLockTimeout runs fn with the lock timeout.
+func withLockTimeout(tx *xorm.Session, timeout time.Duration, fn MigrateFunc) (err error) {
+	dbType := tx.Engine().Dialect().URI().DBType
+	for _, setting := range LockTimeout(timeout) {
+		if !strings.EqualFold(setting.Dialect, string(dbType)) {
+			continue
+		}
+		if _, err := tx.Exec(setting.Set); err != nil {
+			return err
+		}
+		defer func(reset string) {
+			// If fn failed in a PostgreSQL transaction, this fails
+			// too, and the rollback to the savepoint reverts it.
+			if _, resetErr := tx.Exec(reset); resetErr != nil && err == nil {
+				err = resetErr
+			}
+		}(setting.Reset)
+	}
+	return fn(tx)
+}
+
+// inTransaction reports whether tx is the session of a run transaction.
+func inTransaction(tx *xorm.Session) bool {
+	x := runOf(tx)
+	return x != nil && x.options.UseTransaction && !x.detached
+}
+
+// RetryPolicy re-executes the migrations marked Idempotent when they fail
+// with a transient error, see Options.Retry.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts of a migration.
+	Attempts int
+	// Backoff is the pause between attempts.
+	Backoff time.Duration
+	// IsTransient reports whether an error is worth retrying. Defaults
+	// to IsTransient.
+	IsTransient func(err error) bool
+	// ResumeSQL retries SQL migrations from the statement that failed,
+	// instead of from the start. It has no effect in a run transaction,
+	// where the statements of a failed attempt are rolled back.
+	ResumeSQL bool
+}
+
+// IsTransient reports whether err is a lock timeout, a deadlock, a
+// serialization failure or a lost connection, from its message.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if IsLockTimeout(err) || errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	msg := err.Error()
+	for _, s := range []string{"deadlock", "Deadlock", "40001", "40P01", "could not serialize", "connection reset", "broken pipe"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// migrateWithRetry runs the migration, retrying it according to
+// Options.Retry if it is idempotent. In a run transaction, each attempt runs
+// in a savepoint rolled back on failure.
+func (x *Xormigrate) migrateWithRetry(migration *Migration) error {
+	run := func() error {
+		return x.withSettings(migration, func() error {
+			return x.runMigrate(migration)
+		})
+	}
+	policy := x.options.Retry
+	if policy == nil || !migration.Idempotent {
+		return run()
+	}
+	isTransient := policy.IsTransient
+	if isTransient == nil {
+		isTransient = IsTransient
+	}
+	if policy.ResumeSQL && (!x.options.UseTransaction || x.detached) {
+		x.executed = make(map[string]int)
+		defer func() { x.executed = nil }()
+	}
+	for attempt := 1; ; attempt++ {
+		err := x.isolated(fmt.Sprintf("xormigrate_attempt_%d", attempt), run)
+		if err == nil || !isTransient(err) || attempt >= policy.Attempts {
+			return err
+		}
+		x.emit(&MigrationRetrying{ID: migration.ID, Attempt: attempt, Err: err})
+		if err := x.sleep(policy.Backoff); err != nil {
+			return err
+		}
+	}
+}
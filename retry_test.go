@@ -0,0 +1,173 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestWithLockRetry(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		assert.NoError(t, db.Sync2(&Book{}))
+		errLockTimeout := errors.New("pq: canceling statement due to lock timeout")
+		attempts := 0
+		var timeouts []time.Duration
+		m := New(db.NewSession(), &Options{
+			TableName:      "migration",
+			UseTransaction: true,
+		}, []*Migration{{
+			ID: "201608301400",
+			Migrate: WithLockRetry(LockRetry{
+				Timeout:    time.Second,
+				Factor:     2,
+				MaxTimeout: 3 * time.Second,
+				Attempts:   4,
+				OnRetry: func(attempt int, timeout time.Duration, err error) {
+					timeouts = append(timeouts, timeout)
+				},
+			}, func(tx *xorm.Session) error {
+				attempts++
+				if _, err := tx.Exec("INSERT INTO book (name) VALUES ('attempt')"); err != nil {
+					return err
+				}
+				if attempts < 4 {
+					return errLockTimeout
+				}
+				return nil
+			}),
+		}})
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, 4, attempts)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, timeouts)
+
+		// The failed attempts were rolled back to their savepoint.
+		count, err := db.Table("book").Count()
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, count)
+	})
+}
+
+func TestWithLockRetryOtherError(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		errSyntax := errors.New("syntax error")
+		attempts := 0
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, []*Migration{{
+			ID: "201608301400",
+			Migrate: WithLockRetry(LockRetry{Timeout: time.Second, Attempts: 3}, func(tx *xorm.Session) error {
+				attempts++
+				return errSyntax
+			}),
+		}})
+		assert.ErrorIs(t, m.Migrate(), errSyntax)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestIsLockTimeout(t *testing.T) {
+	assert.False(t, IsLockTimeout(nil))
+	assert.False(t, IsLockTimeout(errors.New("syntax error")))
+	assert.True(t, IsLockTimeout(errors.New("Error 1205: Lock wait timeout exceeded; try restarting transaction")))
+	assert.True(t, IsLockTimeout(errors.New("pq: canceling statement due to lock timeout")))
+}
+
+func TestRetryPolicy(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		assert.NoError(t, db.Sync2(&Book{}))
+		errDeadlock := errors.New("Error 1213: Deadlock found when trying to get lock")
+		attempts := map[string]int{}
+		migrate := func(id string, failures int) MigrateFunc {
+			return func(tx *xorm.Session) error {
+				attempts[id]++
+				if _, err := tx.Exec("INSERT INTO book (name) VALUES ('" + id + "')"); err != nil {
+					return err
+				}
+				if attempts[id] <= failures {
+					return errDeadlock
+				}
+				return nil
+			}
+		}
+		var retried []int
+		m := New(db.NewSession(), &Options{
+			TableName:      "migration",
+			UseTransaction: true,
+			Retry:          &RetryPolicy{Attempts: 3},
+		}, []*Migration{
+			{ID: "201608301400", Idempotent: true, Migrate: migrate("201608301400", 2)},
+			{ID: "201608301430", Migrate: migrate("201608301430", 1)},
+		})
+		m.AddListener(ListenerFunc(func(event Event) {
+			if e, ok := event.(*MigrationRetrying); ok {
+				retried = append(retried, e.Attempt)
+			}
+		}))
+		assert.ErrorIs(t, m.Migrate(), errDeadlock)
+		assert.Equal(t, 3, attempts["201608301400"])
+		assert.Equal(t, 1, attempts["201608301430"])
+		assert.Equal(t, []int{1, 2}, retried)
+
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, 4, attempts["201608301400"])
+		count, err := db.Table("book").Count()
+		assert.NoError(t, err)
+		assert.EqualValues(t, 2, count)
+	})
+}
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, IsTransient(errors.New("pq: deadlock detected (SQLSTATE 40P01)")))
+	assert.True(t, IsTransient(errors.New("pq: could not serialize access due to concurrent update")))
+	assert.True(t, IsTransient(errors.New("database is locked")))
+	assert.False(t, IsTransient(errors.New("syntax error")))
+	assert.False(t, IsTransient(nil))
+}
+
+func TestRetryResumeSQL(t *testing.T) {
+	for _, resume := range []bool{false, true} {
+		forEachDatabase(t, func(db *xorm.Engine) {
+			var executed []int
+			var statementErr *StatementError
+			m := New(db.NewSession(), &Options{
+				TableName: "migration",
+				Retry: &RetryPolicy{
+					Attempts:  2,
+					ResumeSQL: resume,
+					IsTransient: func(err error) bool {
+						return errors.As(err, &statementErr) && statementErr.Index == 2
+					},
+				},
+			}, []*Migration{{
+				ID:         "201608301400",
+				Idempotent: true,
+				UpSQL:      "CREATE TABLE person (id INTEGER); INSERT INTO pet (name) VALUES ('Rex'); INSERT INTO person (id) VALUES (1);",
+			}})
+			m.AddListener(ListenerFunc(func(event Event) {
+				switch e := event.(type) {
+				case *StatementExecuted:
+					assert.Equal(t, 3, e.Total)
+					executed = append(executed, e.Index)
+				case *MigrationRetrying:
+					// The second statement succeeds on the next attempt.
+					assert.NoError(t, db.Sync2(&Pet{}))
+				}
+			}))
+			err := m.Migrate()
+			if resume {
+				assert.NoError(t, err)
+				assert.Equal(t, []int{1, 2, 3}, executed)
+				return
+			}
+			// The first statement fails when it is executed again.
+			if assert.True(t, errors.As(err, &statementErr)) {
+				assert.Equal(t, "201608301400", statementErr.ID)
+				assert.Equal(t, 1, statementErr.Index)
+			}
+			assert.Equal(t, []int{1}, executed)
+		})
+	}
+}
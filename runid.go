@@ -0,0 +1,59 @@
+package xormigrate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"xorm.io/xorm"
+)
+
+// newRunID returns a random ID for a run.
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// RunID returns the unique ID of the Migrate or Rollback call running on tx,
+// or an empty string. It identifies the run in the logs, the events and, with
+// Options.RecordRunID, the migration table, so that deployments of several
+// instances can be told apart.
+func RunID(tx *xorm.Session) string {
+	x := runOf(tx)
+	if x == nil {
+		return ""
+	}
+	return x.runID
+}
+
+// runIDLogger appends the run ID to the messages.
+type runIDLogger struct {
+	Logger
+	runID string
+}
+
+// withRunID returns logger appending runID to the messages, if set.
+func withRunID(logger Logger, runID string) Logger {
+	if runID == "" {
+		return logger
+	}
+	return &runIDLogger{Logger: logger, runID: runID}
+}
+
+func (l *runIDLogger) Debugf(format string, v ...interface{}) {
+	l.Logger.Debugf(format+" [run %s]", append(v, l.runID)...)
+}
+
+func (l *runIDLogger) Infof(format string, v ...interface{}) {
+	l.Logger.Infof(format+" [run %s]", append(v, l.runID)...)
+}
+
+func (l *runIDLogger) Warnf(format string, v ...interface{}) {
+	l.Logger.Warnf(format+" [run %s]", append(v, l.runID)...)
+}
+
+func (l *runIDLogger) Errorf(format string, v ...interface{}) {
+	l.Logger.Errorf(format+" [run %s]", append(v, l.runID)...)
+}
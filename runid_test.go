@@ -0,0 +1,56 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestRunID(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		logger := &recordingLogger{}
+		var seen []string
+		m := New(db.NewSession(), &Options{
+			TableName:   "migration",
+			Logger:      logger,
+			RecordRunID: true,
+		}, []*Migration{
+			{
+				ID: "201608301400",
+				Migrate: func(tx *xorm.Session) error {
+					seen = append(seen, RunID(tx))
+					return nil
+				},
+				Rollback: func(tx *xorm.Session) error {
+					seen = append(seen, RunID(tx))
+					return nil
+				},
+			},
+		})
+		var events []string
+		m.AddListener(ListenerFunc(func(event Event) {
+			events = append(events, event.(interface{ info() *RunInfo }).info().RunID)
+		}))
+		assert.NoError(t, m.Migrate())
+		assert.NoError(t, m.RollbackLast())
+		assert.NoError(t, m.Migrate())
+
+		if !assert.Len(t, seen, 3) {
+			return
+		}
+		assert.Len(t, seen[0], 16)
+		assert.NotEqual(t, seen[0], seen[1])
+		assert.NotEqual(t, seen[1], seen[2])
+		assert.Equal(t, seen[0], events[0])
+		assert.Equal(t, 1, logger.count("info xormigrate: Migration started [run "+seen[0]+"]"))
+		assert.Equal(t, 1, logger.count("info xormigrate: Rollback started [run "+seen[1]+"]"))
+		assert.Empty(t, m.runID)
+
+		var records []migrationRecord
+		assert.NoError(t, db.Table("migration").Find(&records))
+		if assert.Len(t, records, 1) {
+			assert.Equal(t, seen[2], records[0].RunID)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package xormigrate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// SchemaAt returns the schema, as DDL statements, resulting from the
+// migrations up to and including the one matching migrationID. The
+// migrations are replayed on target, which must be an empty throwaway
+// database such as an in-memory SQLite, so the DDL uses its dialect.
+// The schema initialization function is not used.
+func (x *Xormigrate) SchemaAt(target *xorm.Engine, migrationID string) (string, error) {
+	replay := x.replay(target)
+	if err := replay.MigrateTo(migrationID); err != nil {
+		return "", err
+	}
+	return schemaDDL(target, replay.bookkeepingTables()...)
+}
+
+// ReplayResult is the outcome of Replay.
+type ReplayResult struct {
+	// Schema is the resulting schema, as DDL statements.
+	Schema string
+	// Skipped are the migrations restricted to other databases by their
+	// Dialects, which the schema lacks.
+	Skipped []MigrationSkipped
+	// Warnings are the warnings of the run, see RunResult.
+	Warnings []string
+}
+
+// Replay applies all the migrations to target, which must be an empty
+// throwaway database such as an in-memory SQLite, and returns the resulting
+// schema, so that new migrations can be checked locally without access to a
+// shared database. The migrations restricted to other databases are skipped
+// and reported. The database of x is untouched.
+func (x *Xormigrate) Replay(target *xorm.Engine) (*ReplayResult, error) {
+	replay := x.replay(target)
+	result, err := replay.MigrateResult()
+	if err != nil {
+		return nil, err
+	}
+	ddl, err := schemaDDL(target, replay.bookkeepingTables()...)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayResult{Schema: ddl, Skipped: result.Skipped, Warnings: result.Warnings}, nil
+}
+
+// replay returns a clone of x running on the throwaway target, without the
+// schema initialization function, listeners, and the options guarding the
+// runs on the real database: the gate, the policy, the lock, the read-only
+// mode and the approval of the migrations requiring one.
+func (x *Xormigrate) replay(target *xorm.Engine) *Xormigrate {
+	options := *x.options
+	options.Gate = nil
+	options.Policy = nil
+	options.UseLock = false
+	options.ReadOnly = false
+	options.ApprovalVerifier = func(m *Migration, token string) error { return nil }
+	replay := x.Clone(WithEngine(target), WithOptions(&options))
+	replay.initSchema = nil
+	replay.listeners = nil
+	for _, migration := range replay.migrations {
+		if migration.RequiresApproval {
+			replay.Approve(migration.ID, "")
+		}
+	}
+	return replay
+}
+
+// bookkeepingTables returns the tables of xormigrate, left out of the
+// replayed schemas.
+func (x *Xormigrate) bookkeepingTables() []string {
+	tableName := x.options.TableName
+	return []string{tableName, tableName + "_steps", tableName + "_lock"}
+}
+
+// schemaDDL returns the statements creating the tables of engine and their
+// indexes, ordered by table name. The excluded tables are left out.
+func schemaDDL(engine *xorm.Engine, exclude ...string) (string, error) {
+	tables, err := engine.DBMetas()
+	if err != nil {
+		return "", err
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+
+	dialect := engine.Dialect()
+	var b strings.Builder
+	for _, table := range tables {
+		if contains(exclude, table.Name) {
+			continue
+		}
+		sqls, _ := dialect.CreateTableSQL(table, table.Name)
+		for _, sql := range sqls {
+			b.WriteString(sql + ";\n")
+		}
+		names := make([]string, 0, len(table.Indexes))
+		for name := range table.Indexes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			b.WriteString(dialect.CreateIndexSQL(table.Name, table.Indexes[name]) + ";\n")
+		}
+	}
+	return b.String(), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaMismatchError is returned by AssertSchemaMatches when the database
+// doesn't match the models
+type SchemaMismatchError struct {
+	Diffs []string
+}
+
+func (e *SchemaMismatchError) Error() string {
+	return "xormigrate: Database schema does not match the models:\n  - " + strings.Join(e.Diffs, "\n  - ")
+}
+
+// AssertSchemaMatches compares the tables, columns and indexes defined by the
+// xorm models beans with the schema of the database. Call it after running
+// the migrations, e.g. in main(), to catch forgotten migrations before
+// serving traffic. Column types are compared by name, ignoring lengths.
+// It returns a *SchemaMismatchError listing the differences.
+func AssertSchemaMatches(engine *xorm.Engine, beans ...interface{}) error {
+	tables, err := engine.DBMetas()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*schemas.Table, len(tables))
+	for _, table := range tables {
+		byName[strings.ToLower(table.Name)] = table
+	}
+
+	dialect := engine.Dialect()
+	var diffs []string
+	for _, bean := range beans {
+		model, err := engine.TableInfo(bean)
+		if err != nil {
+			return err
+		}
+		name := engine.TableName(bean)
+		table, ok := byName[strings.ToLower(name)]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("table %s is missing", name))
+			continue
+		}
+		for _, col := range model.Columns() {
+			dbCol := table.GetColumn(col.Name)
+			if dbCol == nil {
+				diffs = append(diffs, fmt.Sprintf("column %s.%s is missing", name, col.Name))
+				continue
+			}
+			expected, actual := dialect.SQLType(col), dialect.SQLType(dbCol)
+			if !sameSQLType(dialect.Alias, expected, actual) {
+				diffs = append(diffs, fmt.Sprintf("column %s.%s has type %s, the model expects %s", name, col.Name, actual, expected))
+			}
+		}
+		for indexName, index := range model.Indexes {
+			if !hasIndex(table, index) {
+				diffs = append(diffs, fmt.Sprintf("index %s on %s(%s) is missing", indexName, name, strings.Join(index.Cols, ", ")))
+			}
+		}
+	}
+	if len(diffs) > 0 {
+		sort.Strings(diffs)
+		return &SchemaMismatchError{Diffs: diffs}
+	}
+	return nil
+}
+
+func sameSQLType(alias func(string) string, expected, actual string) bool {
+	expected, actual = schemas.SQLTypeName(expected), schemas.SQLTypeName(actual)
+	return strings.EqualFold(expected, actual) ||
+		strings.EqualFold(alias(expected), actual) ||
+		strings.EqualFold(expected, alias(actual))
+}
+
+func hasIndex(table *schemas.Table, index *schemas.Index) bool {
+	for _, dbIndex := range table.Indexes {
+		if dbIndex.Equal(index) {
+			return true
+		}
+	}
+	return false
+}
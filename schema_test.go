@@ -0,0 +1,133 @@
+package xormigrate
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+// newScratchEngine returns an empty in-memory SQLite database, or skips the
+// test when the SQLite driver is not built in.
+func newScratchEngine(t *testing.T) *xorm.Engine {
+	if !contains(sql.Drivers(), "sqlite3") {
+		t.Skip("SQLite driver not available")
+	}
+	engine, err := xorm.NewEngine("sqlite3", "file:"+t.Name()+"?mode=memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestSchemaAt(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		target := newScratchEngine(t)
+		defer target.Close()
+
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, extendedMigrations)
+		ddl, err := m.SchemaAt(target, "201608301400")
+		assert.NoError(t, err)
+		assert.Contains(t, ddl, "CREATE TABLE IF NOT EXISTS `person`")
+		assert.NotContains(t, ddl, "`pet`")
+		assert.NotContains(t, ddl, "`migration`")
+
+		// The database of m is untouched.
+		has, err := db.IsTableExist("migration")
+		assert.NoError(t, err)
+		assert.False(t, has)
+	})
+}
+
+func TestReplay(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		target := newScratchEngine(t)
+		defer target.Close()
+
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, append(extendedMigrations[:3:3], &Migration{
+			ID:       "201807230000",
+			Dialects: []string{"postgres"},
+			UpSQL:    "CREATE EXTENSION pg_trgm;",
+		}))
+		result, err := m.Replay(target)
+		assert.NoError(t, err)
+		if assert.NotNil(t, result) {
+			assert.Contains(t, result.Schema, "CREATE TABLE IF NOT EXISTS `book`")
+			assert.Contains(t, result.Schema, "CREATE TABLE IF NOT EXISTS `pet`")
+			assert.NotContains(t, result.Schema, "`migration`")
+			if assert.Len(t, result.Skipped, 1) {
+				assert.Equal(t, "201807230000", result.Skipped[0].ID)
+			}
+		}
+
+		has, err := db.IsTableExist("migration")
+		assert.NoError(t, err)
+		assert.False(t, has)
+	})
+}
+
+func TestAssertSchemaMatches(t *testing.T) {
+	type Pet struct {
+		Name     string `xorm:"name index"`
+		PersonID int    `xorm:"person_id"`
+		Age      int    `xorm:"age"`
+	}
+
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, migrations[:1])
+		assert.NoError(t, m.Migrate())
+		assert.NoError(t, AssertSchemaMatches(db, &Person{}))
+
+		err := AssertSchemaMatches(db, &Person{}, &Book{})
+		assert.EqualError(t, err, "xormigrate: Database schema does not match the models:\n  - table book is missing")
+
+		assert.NoError(t, db.Table("pet").Sync2(&struct {
+			Name     string `xorm:"name"`
+			PersonID string `xorm:"person_id"`
+		}{}))
+		err = AssertSchemaMatches(db, &Pet{})
+		var mismatchError *SchemaMismatchError
+		if assert.True(t, errors.As(err, &mismatchError)) {
+			assert.Equal(t, []string{
+				"column pet.age is missing",
+				"column pet.person_id has type TEXT, the model expects INTEGER",
+				"index name on pet(name) is missing",
+			}, mismatchError.Diffs)
+		}
+	})
+}
+
+func TestReplayIgnoresGuards(t *testing.T) {
+	target := newScratchEngine(t)
+	defer target.Close()
+
+	m := NewFake(&FakeBackend{}, &Options{
+		ReadOnly: true,
+		UseLock:  true,
+		Policy: func(m *Migration, env RunEnv) error {
+			return errors.New("denied")
+		},
+	}, []*Migration{{
+		ID:               "201608301400",
+		RequiresApproval: true,
+		UpSQL:            "CREATE TABLE book (id INTEGER);",
+	}})
+	result, err := m.Replay(target)
+	if assert.NoError(t, err) {
+		assert.Contains(t, result.Schema, "`book`")
+	}
+	schemaTarget := newScratchEngine(t)
+	defer schemaTarget.Close()
+	ddl, err := m.SchemaAt(schemaTarget, "201608301400")
+	if assert.NoError(t, err) {
+		assert.Contains(t, ddl, "`book`")
+	}
+}
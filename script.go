@@ -0,0 +1,219 @@
+package xormigrate
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"xorm.io/xorm/schemas"
+)
+
+// ScriptError is returned by Script when a pending migration can't be written
+// as SQL, e.g. because its Migrate is Go code.
+type ScriptError struct {
+	ID string
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf(`xormigrate: Migration "%s" is not SQL and can't be scripted`, e.ID)
+}
+
+// Script writes to w, instead of executing them, the statements the pending
+// migrations would execute, each one followed by the INSERT recording it, as
+// a single script in the order of a run, for a DBA to review and execute. The
+// script starts by creating the migration table if it does not exist. The
+// migrations restricted to other databases are only recorded, as a run would.
+//
+// Only SQL migrations, see UpSQL, can be scripted: a *ScriptError is returned
+// before writing anything if a pending migration is Go code or has Steps, or
+// if the schema would be initialized by InitSchema. Params are substituted,
+// while secrets, see Secret, are left as {{ secret "name" }} for the DBA to
+// substitute. ErrNoEngine is returned on a FakeBackend,
+// which has no SQL dialect to write the script in.
+func (x *Xormigrate) Script(w io.Writer) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	if !x.hasMigrations() {
+		return ErrNoMigrationDefined
+	}
+	if x.backend.engine() == nil {
+		return ErrNoEngine
+	}
+	if err := x.checkReservedID(); err != nil {
+		return err
+	}
+	if err := x.checkDuplicatedID(); err != nil {
+		return err
+	}
+	if err := x.checkDependencies(); err != nil {
+		return err
+	}
+	initialized, err := x.initialized()
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	dialect := x.backend.dialect()
+	fmt.Fprintf(&b, "-- Migrations of %s for %s, generated by xormigrate.\n", x.options.TableName, dialect)
+	if !initialized {
+		if x.initSchema != nil {
+			return &ScriptError{ID: initSchemaMigrationID}
+		}
+		statements, err := x.createTableSQL()
+		if err != nil {
+			return err
+		}
+		b.WriteString("\n")
+		for _, statement := range statements {
+			b.WriteString(statement + ";\n")
+		}
+	} else if x.initSchema != nil {
+		canInitializeSchema, err := x.canInitializeSchema()
+		if err != nil {
+			return err
+		}
+		if canInitializeSchema {
+			return &ScriptError{ID: initSchemaMigrationID}
+		}
+	}
+
+	plan, err := x.runOrder("")
+	if err != nil {
+		return err
+	}
+	for _, migration := range plan {
+		if initialized {
+			migrationRan, err := x.migrationRan(migration)
+			if err != nil {
+				return err
+			}
+			if migrationRan {
+				continue
+			}
+		}
+		b.WriteString("\n-- " + migration.ID + described(migration.Description) + "\n")
+		skipReason := x.skipReason(migration)
+		switch {
+		case skipReason != "":
+			b.WriteString("-- Skipped: " + skipReason + "\n")
+		case migration.RollbackOnly:
+		case migration.Migrate != nil || migration.MigrateContext != nil || len(migration.Steps) > 0:
+			return &ScriptError{ID: migration.ID}
+		default:
+			rendered, err := x.renderScriptParams([]byte(migration.upSQL(dialect)), migration.Params)
+			if err != nil {
+				return err
+			}
+			for _, statement := range splitStatements(string(rendered)) {
+				b.WriteString(statement + ";\n")
+			}
+		}
+		record, err := x.newRecord(migration, 0, skipReason)
+		if err != nil {
+			return err
+		}
+		b.WriteString(x.insertRecordSQL(record) + ";\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// renderScriptParams renders script like renderScript, leaving the secrets
+// as they are written in the script.
+func (x *Xormigrate) renderScriptParams(script []byte, params map[string]string) ([]byte, error) {
+	var secret func(name string) (string, error)
+	if x.options.Secrets != nil {
+		secret = func(name string) (string, error) {
+			return fmt.Sprintf("{{ secret %q }}", name), nil
+		}
+	}
+	return renderTemplate(script, params, secret)
+}
+
+// createTableSQL returns the statements creating the migration table, see
+// createTable.
+func (x *Xormigrate) createTableSQL() ([]string, error) {
+	if x.options.CreateTableSQL != nil {
+		return splitStatements(x.options.CreateTableSQL(x.backend.dialect(), x.options.TableName)), nil
+	}
+	engine := x.backend.engine()
+	table, err := engine.TableInfo(&migrationRecord{})
+	if err != nil {
+		return nil, err
+	}
+	table.Name = x.options.TableName
+	dialect := engine.Dialect()
+	statements, _ := dialect.CreateTableSQL(table, table.Name)
+	for _, cols := range x.options.TableOptions.Indexes {
+		index := schemas.NewIndex(strings.Join(cols, "_"), schemas.IndexType)
+		index.AddColumn(cols...)
+		statements = append(statements, dialect.CreateIndexSQL(table.Name, index))
+	}
+	return statements, nil
+}
+
+// insertRecordSQL returns the INSERT statement of the record, with the
+// columns written by insertMigration.
+func (x *Xormigrate) insertRecordSQL(record *migrationRecord) string {
+	engine := x.backend.engine()
+	values := map[string]interface{}{
+		"id":            record.ID,
+		"build_version": record.BuildVersion,
+		"host":          record.Host,
+		"approval":      record.Approval,
+		"checksum":      record.Checksum,
+		"over_budget":   record.OverBudget,
+		"status":        record.Status,
+		"skip_reason":   record.SkipReason,
+		"run_id":        record.RunID,
+		"applied_at":    record.AppliedAt,
+		"duration_ms":   record.DurationMS,
+		"touches":       record.Touches,
+		"out_of_order":  record.OutOfOrder,
+	}
+	cols := x.recordColumns()
+	quoted := make([]string, len(cols))
+	literals := make([]string, len(cols))
+	for i, col := range cols {
+		quoted[i] = engine.Quote(col)
+		literals[i] = x.sqlLiteral(values[col])
+		if col == "applied_at" {
+			// When the DBA executes it.
+			literals[i] = "CURRENT_TIMESTAMP"
+		}
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", engine.Quote(x.options.TableName), strings.Join(quoted, ", "), strings.Join(literals, ", "))
+}
+
+// sqlLiteral returns value as a SQL literal of the dialect.
+func (x *Xormigrate) sqlLiteral(value interface{}) string {
+	dbType := schemas.DBType(x.backend.dialect())
+	switch v := value.(type) {
+	case string:
+		if dbType == schemas.MYSQL {
+			v = strings.ReplaceAll(v, `\`, `\\`)
+		}
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case bool:
+		if dbType == schemas.POSTGRES {
+			return strings.ToUpper(strconv.FormatBool(v))
+		}
+		if v {
+			return "1"
+		}
+		return "0"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case *time.Time:
+		if v == nil {
+			return "NULL"
+		}
+		return "'" + v.UTC().Format("2006-01-02 15:04:05") + "'"
+	}
+	return "NULL"
+}
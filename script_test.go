@@ -0,0 +1,87 @@
+package xormigrate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestScript(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		sqlMigrations := []*Migration{
+			{ID: "201608301400", Description: "Create person", UpSQL: "CREATE TABLE person (id INTEGER);"},
+			{ID: "201608301430", UpSQL: "CREATE TABLE pet (name VARCHAR(255), person_id INTEGER);"},
+			{ID: "201608301500", Dialects: []string{"oracle"}, UpSQL: "CREATE TABLE book (id INTEGER);"},
+		}
+		options := &Options{TableName: "migration", RecordChecksum: true, RecordAppliedAt: true}
+		m := New(db.NewSession(), options, sqlMigrations)
+
+		var script strings.Builder
+		assert.NoError(t, m.Script(&script))
+		assert.Contains(t, script.String(), "-- 201608301400 - Create person\nCREATE TABLE person (id INTEGER);\nINSERT INTO ")
+		exists, err := db.IsTableExist("migration")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+
+		// Executed by the DBA.
+		for _, statement := range splitStatements(script.String()) {
+			_, err := db.Exec(statement)
+			assert.NoError(t, err, statement)
+		}
+		pending, err := m.Pending()
+		assert.NoError(t, err)
+		assert.Empty(t, pending)
+		assert.NoError(t, m.Verify())
+		exists, err = db.IsTableExist("pet")
+		assert.NoError(t, err)
+		assert.True(t, exists)
+
+		script.Reset()
+		assert.NoError(t, m.Script(&script))
+		assert.NotContains(t, script.String(), "INSERT")
+	})
+}
+
+func TestScriptParams(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			Secrets:   EnvSecrets{Prefix: "TEST_SECRET_"},
+		}, []*Migration{{
+			ID:     "201608301400",
+			UpSQL:  `CREATE TABLE {{ param "table" }} (id INTEGER, name VARCHAR(255)); INSERT INTO {{ param "table" }} (id, name) VALUES (1, {{ secret "admin_name" }});`,
+			Params: map[string]string{"table": "person"},
+		}})
+
+		var script strings.Builder
+		assert.NoError(t, m.Script(&script))
+		assert.Contains(t, script.String(), "CREATE TABLE person (id INTEGER, name VARCHAR(255));\n")
+		assert.Contains(t, script.String(), `INSERT INTO person (id, name) VALUES (1, {{ secret "admin_name" }});`)
+		assert.NotContains(t, script.String(), "param")
+	})
+}
+
+func TestScriptGoMigration(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+		var script strings.Builder
+		err := m.Script(&script)
+		var scriptErr *ScriptError
+		if assert.True(t, errors.As(err, &scriptErr)) {
+			assert.Equal(t, "201608301400", scriptErr.ID)
+		}
+		assert.Empty(t, script.String())
+	})
+}
+
+func TestScriptFake(t *testing.T) {
+	m := NewFake(&FakeBackend{Dialect: "postgres"}, &Options{}, []*Migration{
+		{ID: "201608301400", UpSQL: "CREATE TABLE person (id INTEGER);"},
+	})
+	var script strings.Builder
+	assert.Equal(t, ErrNoEngine, m.Script(&script))
+	assert.Empty(t, script.String())
+}
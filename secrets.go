@@ -0,0 +1,121 @@
+package xormigrate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"xorm.io/xorm"
+)
+
+// SecretResolver resolves secrets by name, e.g. from a secrets manager, so
+// that credentials don't have to be committed with seed migrations.
+type SecretResolver interface {
+	Secret(name string) (string, error)
+}
+
+// SecretResolverFunc adapts a function to the SecretResolver interface.
+type SecretResolverFunc func(name string) (string, error)
+
+// Secret calls f(name).
+func (f SecretResolverFunc) Secret(name string) (string, error) {
+	return f(name)
+}
+
+// EnvSecrets resolves secrets from environment variables, named after the
+// secret with Prefix prepended.
+type EnvSecrets struct {
+	Prefix string
+}
+
+// Secret returns the value of the environment variable of the secret.
+func (e EnvSecrets) Secret(name string) (string, error) {
+	value, ok := os.LookupEnv(e.Prefix + name)
+	if !ok {
+		return "", fmt.Errorf(`xormigrate: Secret "%s" not found: environment variable %s is not set`, name, e.Prefix+name)
+	}
+	return value, nil
+}
+
+// Secret resolves a secret with the Options.Secrets of the run tx belongs
+// to, for use in Go migrations.
+func Secret(tx *xorm.Session, name string) (string, error) {
+	x := runOf(tx)
+	if x == nil || x.options.Secrets == nil {
+		return "", ErrNoSecretResolver
+	}
+	return x.resolveSecret(name)
+}
+
+// resolveSecret resolves a secret with Options.Secrets, remembering its value
+// to redact it from the statements echoed by the run, see redact.
+func (x *Xormigrate) resolveSecret(name string) (string, error) {
+	value, err := x.options.Secrets.Secret(name)
+	if err != nil {
+		return "", err
+	}
+	if value != "" {
+		x.secrets = append(x.secrets, x.sqlLiteral(value), value)
+	}
+	return value, nil
+}
+
+// redact replaces the values of the secrets resolved by the run in s.
+func (x *Xormigrate) redact(s string) string {
+	for _, secret := range x.secrets {
+		s = strings.ReplaceAll(s, secret, "***")
+	}
+	return s
+}
+
+// renderScript executes script as a text/template providing the secret
+// function when Options.Secrets is set, and the param function
+// when the migration has Params. Secrets are inserted as quoted and escaped
+// string literals, params verbatim:
+//
+//	INSERT INTO settings (name, value) VALUES ('smtp', {{ secret "smtp_password" }});
+func (x *Xormigrate) renderScript(script []byte, params map[string]string) ([]byte, error) {
+	var secret func(name string) (string, error)
+	if x.options.Secrets != nil {
+		secret = func(name string) (string, error) {
+			value, err := x.resolveSecret(name)
+			if err != nil {
+				return "", err
+			}
+			return x.sqlLiteral(value), nil
+		}
+	}
+	return renderTemplate(script, params, secret)
+}
+
+// renderTemplate executes script as a text/template providing the secret
+// function, if not nil, and the param function when there are params.
+func renderTemplate(script []byte, params map[string]string, secret func(name string) (string, error)) ([]byte, error) {
+	funcs := template.FuncMap{}
+	if secret != nil {
+		funcs["secret"] = secret
+	}
+	if params != nil {
+		funcs["param"] = func(name string) (string, error) {
+			value, ok := params[name]
+			if !ok {
+				return "", fmt.Errorf(`xormigrate: Param "%s" is not set`, name)
+			}
+			return value, nil
+		}
+	}
+	if len(funcs) == 0 {
+		return script, nil
+	}
+	tmpl, err := template.New("sql").Funcs(funcs).Parse(string(script))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,87 @@
+package xormigrate
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestSecrets(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		os.Setenv("TEST_SECRET_admin_name", "O'Brien")
+		defer os.Unsetenv("TEST_SECRET_admin_name")
+
+		loaded, err := (&ManifestSource{FS: fstest.MapFS{
+			"migrations.yaml": {Data: []byte(`
+migrations:
+  - id: "201608301400"
+    up: seed.up.sql
+`)},
+			"seed.up.sql": {Data: []byte(`
+CREATE TABLE person (id INTEGER, name VARCHAR(255));
+INSERT INTO person (id, name) VALUES (1, {{ secret "admin_name" }});
+`)},
+		}, Path: "migrations.yaml"}).Load(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var fromGo string
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			Secrets:   EnvSecrets{Prefix: "TEST_SECRET_"},
+		}, append(loaded, &Migration{
+			ID: "201608301430",
+			Migrate: func(tx *xorm.Session) error {
+				fromGo, err = Secret(tx, "admin_name")
+				return err
+			},
+		}))
+		assert.NoError(t, m.Migrate())
+
+		var person Person
+		has, err := db.Where("id = ?", 1).Get(&person)
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.Equal(t, "O'Brien", person.Name)
+		assert.Equal(t, "O'Brien", fromGo)
+	})
+}
+
+func TestSecretsRedacted(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		os.Setenv("TEST_SECRET_admin_name", "O'Brien")
+		defer os.Unsetenv("TEST_SECRET_admin_name")
+
+		logger := &recordingLogger{}
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			Logger:    logger,
+			EchoSQL:   true,
+			Secrets:   EnvSecrets{Prefix: "TEST_SECRET_"},
+		}, []*Migration{{
+			ID:    "201608301400",
+			UpSQL: `CREATE TABLE person (id INTEGER, name VARCHAR(255)); INSERT INTO person (id, name) VALUES (1, {{ secret "admin_name" }});`,
+		}, {
+			ID: "201608301430",
+			Migrate: func(tx *xorm.Session) error {
+				name, err := Secret(tx, "admin_name")
+				if err != nil {
+					return err
+				}
+				_, err = tx.Exec("INSERT INTO person (id, name) VALUES (2, ?)", name)
+				return err
+			},
+		}})
+		assert.NoError(t, m.Migrate())
+
+		assert.Equal(t, 2, logger.count("info xormigrate: [SQL] INSERT INTO person"))
+		for _, message := range logger.messages {
+			assert.NotContains(t, message, "Brien")
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package xormigrate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Setting is a session setting applied while a migration runs, see
+// Migration.Settings.
+type Setting struct {
+	// Dialect restricts the setting to a database type, e.g. "mysql".
+	// The setting applies to all of them if empty.
+	Dialect string
+	// Set is the statement applying the setting.
+	Set string
+	// Reset is the statement reverting the setting. Can be empty, e.g.
+	// for a PostgreSQL "SET LOCAL".
+	Reset string
+}
+
+// DisableForeignKeyChecks returns the settings disabling foreign key checks,
+// e.g. for bulk loads. On PostgreSQL, it sets session_replication_role, which
+// requires superuser privileges, and it has no effect on SQLite in a
+// transaction.
+func DisableForeignKeyChecks() []Setting {
+	return []Setting{
+		{Dialect: "mysql", Set: "SET FOREIGN_KEY_CHECKS = 0", Reset: "SET FOREIGN_KEY_CHECKS = 1"},
+		{Dialect: "postgres", Set: "SET session_replication_role = replica", Reset: "SET session_replication_role = DEFAULT"},
+		{Dialect: "sqlite3", Set: "PRAGMA foreign_keys = OFF", Reset: "PRAGMA foreign_keys = ON"},
+	}
+}
+
+// LockTimeout returns the settings limiting the time a statement waits for a
+// lock. MySQL only supports whole seconds, d is rounded up.
+func LockTimeout(d time.Duration) []Setting {
+	seconds := (d + time.Second - 1) / time.Second
+	return []Setting{
+		{Dialect: "mysql", Set: fmt.Sprintf("SET SESSION lock_wait_timeout = %d", seconds), Reset: "SET SESSION lock_wait_timeout = DEFAULT"},
+		{Dialect: "postgres", Set: fmt.Sprintf("SET lock_timeout = %d", d.Milliseconds()), Reset: "RESET lock_timeout"},
+		{Dialect: "mssql", Set: fmt.Sprintf("SET LOCK_TIMEOUT %d", d.Milliseconds()), Reset: "SET LOCK_TIMEOUT -1"},
+	}
+}
+
+// withSettings runs fn with the settings of m applied on the session, and
+// reverts them afterwards, in reverse order, even if fn fails.
+func (x *Xormigrate) withSettings(m *Migration, fn func() error) (err error) {
+	if len(m.Settings) == 0 {
+		return fn()
+	}
+	dialect := x.backend.dialect()
+	var applied []Setting
+	defer func() {
+		for i := len(applied) - 1; i >= 0; i-- {
+			if applied[i].Reset == "" {
+				continue
+			}
+			// A failed statement may have aborted the transaction: the
+			// error of the migration prevails.
+			if resetErr := x.backend.exec(applied[i].Reset); resetErr != nil && err == nil {
+				err = resetErr
+			}
+		}
+	}()
+	for _, setting := range m.Settings {
+		if setting.Dialect != "" && !strings.EqualFold(setting.Dialect, dialect) {
+			continue
+		}
+		if err := x.backend.exec(setting.Set); err != nil {
+			return err
+		}
+		applied = append(applied, setting)
+	}
+	return fn()
+}
@@ -0,0 +1,54 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestSettings(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		assert.NoError(t, db.Sync2(&Book{}))
+		insert := func(name string) string {
+			return "INSERT INTO book (name) VALUES ('" + name + "')"
+		}
+		errFailed := errors.New("failed")
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, []*Migration{
+			{
+				ID: "201608301400",
+				Settings: []Setting{
+					{Set: insert("set 1"), Reset: insert("reset 1")},
+					{Dialect: "none", Set: insert("skipped")},
+					{Set: insert("set 2"), Reset: insert("reset 2")},
+				},
+				Migrate: func(tx *xorm.Session) error {
+					_, err := tx.Exec(insert("migrate"))
+					return err
+				},
+			},
+			{
+				ID:       "201608301430",
+				Settings: []Setting{{Set: insert("set 3"), Reset: insert("reset 3")}},
+				Migrate: func(tx *xorm.Session) error {
+					return errFailed
+				},
+			},
+		})
+		assert.ErrorIs(t, m.Migrate(), errFailed)
+
+		var names []string
+		assert.NoError(t, db.Table("book").Cols("name").Find(&names))
+		assert.Equal(t, []string{"set 1", "set 2", "migrate", "reset 2", "reset 1", "set 3", "reset 3"}, names)
+	})
+}
+
+func TestLockTimeout(t *testing.T) {
+	settings := LockTimeout(1500 * time.Millisecond)
+	assert.Equal(t, "SET SESSION lock_wait_timeout = 2", settings[0].Set)
+	assert.Equal(t, "SET lock_timeout = 1500", settings[1].Set)
+}
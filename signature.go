@@ -0,0 +1,31 @@
+package xormigrate
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Sign returns the hex-encoded HMAC-SHA256 of content with key. Publishers
+// of an HTTPSource bundle use it to produce the "manifest.sum.sig" file.
+//
+// Only the SQL migrations downloaded by an HTTPSource are signed. Go
+// migrations are compiled into the binary, and are as trusted as the
+// binary itself: signing them is out of scope.
+func Sign(key, content []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(content)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySignature checks signature, as returned by Sign, in constant time.
+func verifySignature(key, content []byte, signature string) bool {
+	expected, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(content)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
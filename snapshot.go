@@ -0,0 +1,75 @@
+package xormigrate
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// snapshotVersion is the version of the format written by Snapshot.
+const snapshotVersion = 1
+
+type snapshot struct {
+	Version int               `json:"version"`
+	Records []migrationRecord `json:"records"`
+}
+
+// Snapshot exports the content of the migration table, so that Restore can
+// later reset a database to the same state. Combined with a dump of the
+// application tables, it lets test suites start from a database migrated
+// through a given migration without running the migrations each time.
+func (x *Xormigrate) Snapshot() ([]byte, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	initialized, err := x.initialized()
+	if err != nil {
+		return nil, err
+	}
+	if !initialized {
+		return nil, ErrNotInitialized
+	}
+	records, err := x.backend.listRecords()
+	if err != nil {
+		return nil, err
+	}
+	s := snapshot{Version: snapshotVersion, Records: records}
+	return json.Marshal(&s)
+}
+
+// Restore replaces the content of the migration table with a snapshot
+// returned by Snapshot, creating the table if needed. Only the migration
+// table is modified: restoring the application tables is up to the caller.
+func (x *Xormigrate) Restore(data []byte) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	if err := x.checkWritable(); err != nil {
+		return err
+	}
+	if err := x.checkProtection("Restore"); err != nil {
+		return err
+	}
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("xormigrate: Unsupported snapshot version %d", s.Version)
+	}
+
+	defer x.end()
+	if err := x.begin(); err != nil {
+		return err
+	}
+
+	if err := x.createMigrationTableIfNotExists(); err != nil {
+		return err
+	}
+	x.forgetRan()
+	if err := x.backend.replaceRecords(s.Records); err != nil {
+		return err
+	}
+	return x.finish()
+}
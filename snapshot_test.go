@@ -0,0 +1,52 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestSnapshot(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName:      "migration",
+			UseTransaction: true,
+			RecordHost:     true,
+			HostResolver: func() (string, error) {
+				return "pod-1", nil
+			},
+		}, migrations)
+		assert.NoError(t, m.MigrateTo("201608301400"))
+		snapshot, err := m.Snapshot()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.NoError(t, m.Migrate())
+		assert.NoError(t, m.Restore(snapshot))
+
+		var records []migrationRecord
+		assert.NoError(t, db.Table("migration").Find(&records))
+		if assert.Len(t, records, 1) {
+			assert.Equal(t, "201608301400", records[0].ID)
+			assert.Equal(t, "pod-1", records[0].Host)
+		}
+		pending, err := m.Pending()
+		assert.NoError(t, err)
+		assert.Len(t, pending, 1)
+	})
+}
+
+func TestSnapshotNotInitialized(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+		_, err := m.Snapshot()
+		assert.Equal(t, ErrNotInitialized, err)
+
+		assert.NoError(t, m.Restore([]byte(`{"version":1,"records":[{"id":"201608301400"}]}`)))
+		pending, err := m.Pending()
+		assert.NoError(t, err)
+		assert.Len(t, pending, 1)
+	})
+}
@@ -0,0 +1,92 @@
+package xormigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"xorm.io/xorm"
+)
+
+// Source provides migrations. Implement it to discover migrations from
+// places xormigrate doesn't know about, e.g. a plugin system.
+type Source interface {
+	// Load returns the migrations of the source, in order.
+	Load(ctx context.Context) ([]*Migration, error)
+}
+
+// SliceSource is a Source returning a fixed list of migrations.
+type SliceSource []*Migration
+
+// Load returns the migrations of the slice.
+func (s SliceSource) Load(ctx context.Context) ([]*Migration, error) {
+	return s, nil
+}
+
+// SourceConflictError is returned when several sources provide a migration
+// with the same ID
+type SourceConflictError struct {
+	ID      string
+	Sources []string
+}
+
+func (e *SourceConflictError) Error() string {
+	return fmt.Sprintf(`xormigrate: Migration ID "%s" provided by several sources: %s`, e.ID, strings.Join(e.Sources, ", "))
+}
+
+type namedSource struct {
+	Source
+	name string
+}
+
+func (s *namedSource) String() string {
+	return s.name
+}
+
+// NamedSource names a source, for use in error messages. Sources
+// implementing fmt.Stringer are already named by their String method.
+func NamedSource(name string, source Source) Source {
+	return &namedSource{Source: source, name: name}
+}
+
+type mergedSource []Source
+
+// MergeSources returns a Source providing the migrations of all sources,
+// in the order of the sources. Loading it fails with a *SourceConflictError
+// if a migration ID is provided by more than one source.
+func MergeSources(sources ...Source) Source {
+	return mergedSource(sources)
+}
+
+func (s mergedSource) Load(ctx context.Context) ([]*Migration, error) {
+	var migrations []*Migration
+	origins := make(map[string]string)
+	for i, source := range s {
+		name := fmt.Sprintf("source #%d", i+1)
+		if stringer, ok := source.(fmt.Stringer); ok {
+			name = stringer.String()
+		}
+		loaded, err := source.Load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("xormigrate: Loading %s: %w", name, err)
+		}
+		for _, migration := range loaded {
+			if origin, ok := origins[migration.ID]; ok && origin != name {
+				return nil, &SourceConflictError{ID: migration.ID, Sources: []string{origin, name}}
+			}
+			origins[migration.ID] = name
+		}
+		migrations = append(migrations, loaded...)
+	}
+	return migrations, nil
+}
+
+// NewFromSources returns a new Xormigrate running the migrations of all
+// sources, merged with MergeSources.
+func NewFromSources(ctx context.Context, session *xorm.Session, options *Options, sources ...Source) (*Xormigrate, error) {
+	migrations, err := MergeSources(sources...).Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return New(session, options, migrations), nil
+}
@@ -0,0 +1,43 @@
+package xormigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+type failingSource struct{}
+
+func (failingSource) Load(ctx context.Context) ([]*Migration, error) {
+	return nil, errors.New("unreachable")
+}
+
+func TestNewFromSources(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m, err := NewFromSources(context.Background(), db.NewSession(), &Options{
+			TableName: "migration",
+		}, SliceSource(migrations[:1]), SliceSource(migrations[1:]))
+		assert.NoError(t, err)
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, int64(2), tableCount(t, db))
+
+		_, err = NewFromSources(context.Background(), db.NewSession(), &Options{}, failingSource{})
+		assert.EqualError(t, err, "xormigrate: Loading source #1: unreachable")
+	})
+}
+
+func TestMergeSourcesConflict(t *testing.T) {
+	_, err := MergeSources(
+		NamedSource("go", SliceSource(migrations)),
+		NamedSource("sql", SliceSource(extendedMigrations[1:])),
+	).Load(context.Background())
+
+	var conflictError *SourceConflictError
+	if assert.True(t, errors.As(err, &conflictError)) {
+		assert.Equal(t, "201608301430", conflictError.ID)
+		assert.Equal(t, []string{"go", "sql"}, conflictError.Sources)
+	}
+}
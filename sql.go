@@ -0,0 +1,387 @@
+package xormigrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// sqlFile is a SQL migration file, named "<id>_<description>.up.sql" or
+// "<id>_<description>.down.sql", or "<id>_<description>.<dialect>.up.sql"
+// and "<id>_<description>.<dialect>.down.sql" for the variants of a
+// dialect, e.g. "postgres".
+type sqlFile struct {
+	name    string
+	content []byte
+}
+
+// sqlFileDialects are the dialects recognized in SQL migration file names.
+var sqlFileDialects = []schemas.DBType{schemas.POSTGRES, schemas.MYSQL, schemas.SQLITE, schemas.MSSQL, schemas.ORACLE}
+
+// repeatablePrefix starts the names of the files of repeatable migrations,
+// e.g. "R__person_view.up.sql", see Migration.Repeatable.
+const repeatablePrefix = "R__"
+
+// parseSQLFileName splits the name of a SQL migration file. dialect is empty
+// unless it is a variant. ok is false if the name doesn't follow the
+// convention.
+func parseSQLFileName(name string) (id, description, dialect string, up bool, ok bool) {
+	switch {
+	case strings.HasSuffix(name, ".up.sql"):
+		name, up = strings.TrimSuffix(name, ".up.sql"), true
+	case strings.HasSuffix(name, ".down.sql"):
+		name = strings.TrimSuffix(name, ".down.sql")
+	default:
+		return "", "", "", false, false
+	}
+	for _, dbType := range sqlFileDialects {
+		if strings.HasSuffix(name, "."+string(dbType)) {
+			name, dialect = strings.TrimSuffix(name, "."+string(dbType)), string(dbType)
+			break
+		}
+	}
+	id = name
+	if strings.HasPrefix(name, repeatablePrefix) {
+		// The name of a repeatable migration is its ID.
+		description = strings.ReplaceAll(strings.TrimPrefix(name, repeatablePrefix), "_", " ")
+		return id, description, dialect, up, description != ""
+	}
+	if i := strings.IndexByte(name, '_'); i >= 0 {
+		id, description = name[:i], strings.ReplaceAll(name[i+1:], "_", " ")
+	}
+	return id, description, dialect, up, id != ""
+}
+
+// newSQLMigrations groups the up and down files of each migration, and their
+// variants, in the order of their first file. Each migration needs an up
+// file, or a variant of it, the down file is optional.
+func newSQLMigrations(files []sqlFile) ([]*Migration, error) {
+	var migrations []*Migration
+	byID := make(map[string]*Migration)
+	hasUp := make(map[string]bool)
+	for _, file := range files {
+		id, description, dialect, up, ok := parseSQLFileName(file.name)
+		if !ok {
+			return nil, fmt.Errorf(`xormigrate: Invalid SQL migration file name "%s"`, file.name)
+		}
+		migration, ok := byID[id]
+		if !ok {
+			migration = &Migration{ID: id, Description: description, Repeatable: strings.HasPrefix(id, repeatablePrefix)}
+			byID[id] = migration
+			migrations = append(migrations, migration)
+		}
+		migration.files = append(migration.files, file.name)
+		content := string(file.content)
+		if up {
+			if err := applySQLDirectives(migration, file.name, content); err != nil {
+				return nil, err
+			}
+		}
+		switch {
+		case up && dialect != "":
+			if migration.UpSQLByDialect == nil {
+				migration.UpSQLByDialect = make(map[string]string)
+			}
+			migration.UpSQLByDialect[dialect] = content
+		case up:
+			migration.UpSQL = content
+		case dialect != "":
+			if migration.DownSQLByDialect == nil {
+				migration.DownSQLByDialect = make(map[string]string)
+			}
+			migration.DownSQLByDialect[dialect] = content
+		default:
+			migration.DownSQL = content
+		}
+		hasUp[id] = hasUp[id] || up
+	}
+	for _, migration := range migrations {
+		if !hasUp[migration.ID] {
+			return nil, fmt.Errorf(`xormigrate: Missing up SQL file for migration "%s"`, migration.ID)
+		}
+	}
+	return migrations, nil
+}
+
+// applySQLDirectives sets the flags of migration requested by the directives
+// of an up file, the "-- xormigrate:<directive>" comments preceding its first
+// statement: "no-transaction" sets NoTransaction, "long-running"
+// LongRunning.
+func applySQLDirectives(migration *Migration, name, content string) error {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		comment := strings.TrimSpace(strings.TrimPrefix(line, "--"))
+		if !strings.HasPrefix(comment, "xormigrate:") {
+			continue
+		}
+		switch directive := strings.TrimPrefix(comment, "xormigrate:"); directive {
+		case "no-transaction":
+			migration.NoTransaction = true
+		case "long-running":
+			migration.LongRunning = true
+		default:
+			return fmt.Errorf(`xormigrate: Unknown directive "%s" in SQL migration file "%s"`, directive, name)
+		}
+	}
+	return nil
+}
+
+// migrateFunc returns Migrate, or a function calling MigrateContext if it is
+// nil, and nil for SQL migrations, see runSQL. It returns a function doing
+// nothing for RollbackOnly migrations.
+func (m *Migration) migrateFunc() MigrateFunc {
+	if m.RollbackOnly {
+		return func(tx *xorm.Session) error { return nil }
+	}
+	if m.Migrate != nil {
+		return m.Migrate
+	}
+	if m.MigrateContext != nil {
+		return func(tx *xorm.Session) error {
+			return m.MigrateContext(Context(tx), tx)
+		}
+	}
+	return nil
+}
+
+// rollbackFunc returns Rollback, or a function calling RollbackContext if it
+// is nil. It returns nil for SQL migrations, see runRollback.
+func (m *Migration) rollbackFunc() RollbackFunc {
+	if m.Rollback != nil {
+		return m.Rollback
+	}
+	if m.RollbackContext != nil {
+		return func(tx *xorm.Session) error {
+			return m.RollbackContext(Context(tx), tx)
+		}
+	}
+	return nil
+}
+
+// hasRollback reports whether the migration can be rolled back, by a
+// function or DownSQL. A migration with only DownSQLByDialect fails to roll
+// back on the other databases with ErrRollbackImpossible.
+func (m *Migration) hasRollback() bool {
+	return m.rollbackFunc() != nil || m.DownSQL != "" || len(m.DownSQLByDialect) > 0
+}
+
+// upSQL returns the variant of UpSQL for the dialect, or UpSQL.
+func (m *Migration) upSQL(dialect string) string {
+	return sqlVariant(m.UpSQLByDialect, dialect, m.UpSQL)
+}
+
+// downSQL returns the variant of DownSQL for the dialect, or DownSQL.
+func (m *Migration) downSQL(dialect string) string {
+	return sqlVariant(m.DownSQLByDialect, dialect, m.DownSQL)
+}
+
+func sqlVariant(variants map[string]string, dialect, script string) string {
+	for name, variant := range variants {
+		if strings.EqualFold(name, dialect) {
+			return variant
+		}
+	}
+	return script
+}
+
+// upSQLs returns UpSQL and its variants, sorted by dialect, to analyze them.
+func (m *Migration) upSQLs() []string {
+	return sqlVariants(m.UpSQL, m.UpSQLByDialect)
+}
+
+// downSQLs returns DownSQL and its variants, sorted by dialect.
+func (m *Migration) downSQLs() []string {
+	return sqlVariants(m.DownSQL, m.DownSQLByDialect)
+}
+
+func sqlVariants(script string, variants map[string]string) []string {
+	var scripts []string
+	if script != "" {
+		scripts = append(scripts, script)
+	}
+	for _, dialect := range sortedKeys(variants) {
+		scripts = append(scripts, variants[dialect])
+	}
+	return scripts
+}
+
+func sortedKeys(variants map[string]string) []string {
+	keys := make([]string, 0, len(variants))
+	for key := range variants {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// dialects returns Dialects or, for a SQL migration only defined by
+// variants, their dialects.
+func (m *Migration) dialects() []string {
+	if len(m.Dialects) > 0 || m.Migrate != nil || m.MigrateContext != nil || len(m.Steps) > 0 || m.UpSQL != "" {
+		return m.Dialects
+	}
+	return sortedKeys(m.UpSQLByDialect)
+}
+
+// checksum returns Checksum, or the SHA-256 of UpSQL, followed by its
+// variants if any, if it is empty.
+func (m *Migration) checksum() string {
+	if m.Checksum != "" || (m.UpSQL == "" && len(m.UpSQLByDialect) == 0) {
+		return m.Checksum
+	}
+	h := sha256.New()
+	h.Write([]byte(m.UpSQL))
+	for _, dialect := range sortedKeys(m.UpSQLByDialect) {
+		fmt.Fprintf(h, "\x00%s\x00%s", dialect, m.UpSQLByDialect[dialect])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// StatementError is returned when a statement of the UpSQL of a migration
+// fails. Index is its position, from 1, among the Total statements of the
+// script.
+type StatementError struct {
+	ID    string
+	Index int
+	Total int
+	Err   error
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("xormigrate: Migration %s failed at statement %d/%d: %v", e.ID, e.Index, e.Total, e.Err)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// runSQL executes the statements of UpSQL, emitting a StatementExecuted
+// event after each one. The statements already executed by a previous
+// attempt are skipped when it is resumed, see RetryPolicy.ResumeSQL.
+func (x *Xormigrate) runSQL(m *Migration) error {
+	rendered, err := x.renderScript([]byte(m.upSQL(x.backend.dialect())), m.Params)
+	if err != nil {
+		return err
+	}
+	statements := splitStatements(string(rendered))
+	for i := x.executed[m.ID]; i < len(statements); i++ {
+		start := time.Now()
+		if err := x.backend.exec(statements[i]); err != nil {
+			return &StatementError{ID: m.ID, Index: i + 1, Total: len(statements), Err: err}
+		}
+		if x.executed != nil {
+			x.executed[m.ID] = i + 1
+		}
+		x.emit(&StatementExecuted{ID: m.ID, Index: i + 1, Total: len(statements), Duration: time.Since(start)})
+	}
+	return nil
+}
+
+// runRollback runs the rollback function of m, or executes the statements of
+// its DownSQL.
+func (x *Xormigrate) runRollback(m *Migration) error {
+	if rollback := m.rollbackFunc(); rollback != nil {
+		return rollback(x.session)
+	}
+	script := m.downSQL(x.backend.dialect())
+	if script == "" {
+		return ErrRollbackImpossible
+	}
+	rendered, err := x.renderScript([]byte(script), m.Params)
+	if err != nil {
+		return err
+	}
+	for _, statement := range splitStatements(string(rendered)) {
+		if err := x.backend.exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a SQL script on semicolons, ignoring the ones in
+// quoted strings and identifiers, comments and PostgreSQL dollar-quoted
+// strings. Statements made only of comments are left out.
+func splitStatements(script string) []string {
+	var statements []string
+	start, hasCode := 0, false
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			// Doubled quotes are escapes, they are handled as two strings.
+			end := strings.IndexByte(script[i+1:], c)
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 1
+			}
+			hasCode = true
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 3
+			}
+		case c == '$':
+			tag := dollarQuoteTag(script[i:])
+			if tag == "" {
+				hasCode = true
+				continue
+			}
+			end := strings.Index(script[i+len(tag):], tag)
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += len(tag) + end + len(tag) - 1
+			}
+			hasCode = true
+		case c == ';':
+			if hasCode {
+				statements = append(statements, strings.TrimSpace(script[start:i]))
+			}
+			start, hasCode = i+1, false
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			hasCode = true
+		}
+	}
+	if hasCode {
+		statements = append(statements, strings.TrimSpace(script[start:]))
+	}
+	return statements
+}
+
+// dollarQuoteTag returns the tag, e.g. "$$" or "$body$", starting s, or ""
+// if s doesn't start with one.
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
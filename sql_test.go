@@ -0,0 +1,79 @@
+package xormigrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- Persons
+CREATE TABLE person (name VARCHAR(255) DEFAULT 'a;b');
+/* comment; */
+INSERT INTO person VALUES ('it''s; fine');
+CREATE FUNCTION f() RETURNS trigger AS $body$ BEGIN; END; $body$ LANGUAGE plpgsql;
+-- trailing comment;
+`
+	assert.Equal(t, []string{
+		"-- Persons\nCREATE TABLE person (name VARCHAR(255) DEFAULT 'a;b')",
+		"/* comment; */\nINSERT INTO person VALUES ('it''s; fine')",
+		"CREATE FUNCTION f() RETURNS trigger AS $body$ BEGIN; END; $body$ LANGUAGE plpgsql",
+	}, splitStatements(script))
+}
+
+func TestSQLByDialect(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		dialect := string(db.Dialect().URI().DBType)
+		m := New(db.NewSession(), &Options{TableName: "migration", RecordStatus: true}, []*Migration{
+			{
+				ID:               "201608301400",
+				UpSQL:            "CREATE TABLE pet (name VARCHAR(255));",
+				UpSQLByDialect:   map[string]string{dialect: "CREATE TABLE person (id INTEGER);"},
+				DownSQLByDialect: map[string]string{dialect: "DROP TABLE person;"},
+			},
+			{
+				ID:             "201608301430",
+				UpSQLByDialect: map[string]string{"nodb": "CREATE TABLE book (name VARCHAR(255));"},
+			},
+		})
+		assert.NoError(t, m.Migrate())
+		for table, exists := range map[string]bool{"person": true, "pet": false, "book": false} {
+			has, err := db.IsTableExist(table)
+			assert.NoError(t, err)
+			assert.Equal(t, exists, has, table)
+		}
+
+		var skipped migrationRecord
+		_, err := db.Table("migration").ID("201608301430").Get(&skipped)
+		assert.NoError(t, err)
+		assert.Equal(t, "dialect "+dialect+" is not one of nodb", skipped.SkipReason)
+
+		assert.NoError(t, m.RollbackTo("201608301400"))
+		assert.NoError(t, m.RollbackLast())
+		has, err := db.IsTableExist("person")
+		assert.NoError(t, err)
+		assert.False(t, has)
+	})
+}
+
+func TestLoadFSByDialect(t *testing.T) {
+	loaded, err := LoadFS(fstest.MapFS{
+		"migrations/201608301400_create_person.up.sql":            {Data: []byte("CREATE TABLE person (id INTEGER);")},
+		"migrations/201608301400_create_person.postgres.up.sql":   {Data: []byte("CREATE TABLE person (id SERIAL);")},
+		"migrations/201608301400_create_person.down.sql":          {Data: []byte("DROP TABLE person;")},
+		"migrations/201608301430_trigram_index.postgres.up.sql":   {Data: []byte("CREATE EXTENSION pg_trgm;")},
+		"migrations/201608301430_trigram_index.postgres.down.sql": {Data: []byte("DROP EXTENSION pg_trgm;")},
+	}, "migrations")
+	assert.NoError(t, err)
+	if assert.Len(t, loaded, 2) {
+		assert.Equal(t, "create person", loaded[0].Description)
+		assert.Equal(t, "CREATE TABLE person (id INTEGER);", loaded[0].UpSQL)
+		assert.Equal(t, map[string]string{"postgres": "CREATE TABLE person (id SERIAL);"}, loaded[0].UpSQLByDialect)
+		assert.Equal(t, "trigram index", loaded[1].Description)
+		assert.Empty(t, loaded[1].UpSQL)
+		assert.Equal(t, map[string]string{"postgres": "DROP EXTENSION pg_trgm;"}, loaded[1].DownSQLByDialect)
+		assert.Equal(t, []string{"postgres"}, loaded[1].dialects())
+	}
+}
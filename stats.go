@@ -0,0 +1,111 @@
+package xormigrate
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// StatsSink receives metrics about migration runs. Tags are "key:value"
+// strings, e.g. "migration:201608301400".
+//
+// The following metrics are reported:
+//   - xormigrate.run (counter, tagged with status:success or status:failure
+//     and direction:up or direction:down)
+//   - xormigrate.run.duration (timing)
+//   - xormigrate.migration.applied (counter)
+//   - xormigrate.migration.failed (counter)
+//   - xormigrate.migration.retried (counter)
+//   - xormigrate.migration.duration (timing)
+//   - xormigrate.migration.over_budget (counter), see Migration.Budget
+//   - xormigrate.migration.rolled_back (counter)
+type StatsSink interface {
+	Incr(name string, tags ...string)
+	Timing(name string, d time.Duration, tags ...string)
+	Gauge(name string, value float64, tags ...string)
+}
+
+// StatsdSink is a StatsSink sending metrics over UDP to a StatsD server,
+// or to a DogStatsD agent when Tags is set.
+type StatsdSink struct {
+	// Tags appends tags to the metrics using the DogStatsD format, which
+	// plain StatsD servers don't support.
+	Tags bool
+
+	conn net.Conn
+}
+
+// NewStatsdSink returns a StatsdSink sending metrics to the given address,
+// e.g. "127.0.0.1:8125".
+func NewStatsdSink(addr string) (*StatsdSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsdSink{conn: conn}, nil
+}
+
+// Incr increments a counter.
+func (s *StatsdSink) Incr(name string, tags ...string) {
+	s.send(name, "1", "c", tags)
+}
+
+// Timing records a duration in milliseconds.
+func (s *StatsdSink) Timing(name string, d time.Duration, tags ...string) {
+	s.send(name, fmt.Sprint(d.Milliseconds()), "ms", tags)
+}
+
+// Gauge sets a gauge.
+func (s *StatsdSink) Gauge(name string, value float64, tags ...string) {
+	s.send(name, fmt.Sprint(value), "g", tags)
+}
+
+// Close closes the underlying connection.
+func (s *StatsdSink) Close() error {
+	return s.conn.Close()
+}
+
+// send writes a single metric. Errors are ignored, metrics are best effort.
+func (s *StatsdSink) send(name, value, kind string, tags []string) {
+	line := name + ":" + value + "|" + kind
+	if s.Tags && len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	_, _ = s.conn.Write([]byte(line))
+}
+
+type statsListener struct {
+	sink StatsSink
+}
+
+// NewStatsListener returns a Listener reporting events as metrics to sink.
+func NewStatsListener(sink StatsSink) Listener {
+	return &statsListener{sink: sink}
+}
+
+func (l *statsListener) OnEvent(event Event) {
+	switch e := event.(type) {
+	case *MigrationApplied:
+		l.sink.Incr("xormigrate.migration.applied", "migration:"+e.ID)
+		l.sink.Timing("xormigrate.migration.duration", e.Duration, "migration:"+e.ID)
+	case *MigrationRetrying:
+		l.sink.Incr("xormigrate.migration.retried", "migration:"+e.ID)
+	case *MigrationFailed:
+		l.sink.Incr("xormigrate.migration.failed", "migration:"+e.ID)
+	case *BudgetExceeded:
+		l.sink.Incr("xormigrate.migration.over_budget", "migration:"+e.ID)
+	case *RolledBack:
+		l.sink.Incr("xormigrate.migration.rolled_back", "migration:"+e.ID)
+	case *RunFinished:
+		tags := []string{"status:success", "direction:up"}
+		if e.Err != nil {
+			tags[0] = "status:failure"
+		}
+		if e.Rollback {
+			tags[1] = "direction:down"
+		}
+		l.sink.Incr("xormigrate.run", tags...)
+		l.sink.Timing("xormigrate.run.duration", e.Duration, tags...)
+	}
+}
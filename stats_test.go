@@ -0,0 +1,66 @@
+package xormigrate
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+type recordingStats struct {
+	counters map[string]int
+}
+
+func (s *recordingStats) Incr(name string, tags ...string) {
+	s.counters[name]++
+}
+
+func (s *recordingStats) Timing(name string, d time.Duration, tags ...string) {}
+
+func (s *recordingStats) Gauge(name string, value float64, tags ...string) {}
+
+func TestStats(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		stats := &recordingStats{counters: map[string]int{}}
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			Stats:     stats,
+		}, migrations)
+
+		assert.NoError(t, m.Migrate())
+		assert.NoError(t, m.RollbackLast())
+		assert.Equal(t, map[string]int{
+			"xormigrate.run":                   2,
+			"xormigrate.migration.applied":     2,
+			"xormigrate.migration.rolled_back": 1,
+		}, stats.counters)
+	})
+}
+
+func TestStatsdSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	sink, err := NewStatsdSink(conn.LocalAddr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sink.Close()
+	sink.Tags = true
+
+	buf := make([]byte, 512)
+	sink.Incr("xormigrate.run", "status:success")
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "xormigrate.run:1|c|#status:success", string(buf[:n]))
+
+	sink.Timing("xormigrate.run.duration", 1500*time.Millisecond)
+	n, _, err = conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "xormigrate.run.duration:1500|ms", string(buf[:n]))
+}
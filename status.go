@@ -0,0 +1,230 @@
+package xormigrate
+
+import "time"
+
+// Initialized reports whether the migration table exists. It never creates
+// the table, so it is safe to use in readiness checks running with read-only
+// credentials. When Options.AssumeTableExists is set, the table is queried
+// directly and a *MissingTableError is returned if that fails.
+func (x *Xormigrate) Initialized() (bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	return x.initialized()
+}
+
+func (x *Xormigrate) initialized() (bool, error) {
+	if x.options.AssumeTableExists {
+		if err := x.backend.probeTable(); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return x.backend.tableExists()
+}
+
+// Pending returns, in order, the migrations that did not run yet, followed by
+// the repeatable migrations to apply again, see Migration.Repeatable.
+// It is strictly read-only: ErrNotInitialized is returned instead of creating
+// the migration table when it does not exist.
+func (x *Xormigrate) Pending() ([]*Migration, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	return x.pending()
+}
+
+func (x *Xormigrate) pending() ([]*Migration, error) {
+	initialized, err := x.initialized()
+	if err != nil {
+		return nil, err
+	}
+	if !initialized {
+		return nil, ErrNotInitialized
+	}
+
+	var pending []*Migration
+	for _, migration := range x.migrations {
+		if migration.Repeatable {
+			continue
+		}
+		migrationRan, err := x.migrationRan(migration)
+		if err != nil {
+			return nil, err
+		}
+		if !migrationRan {
+			pending = append(pending, migration)
+		}
+	}
+	outdated, err := x.outdatedRepeatables()
+	if err != nil {
+		return nil, err
+	}
+	return append(pending, outdated...), nil
+}
+
+// MigrationRan reports whether the migration with the given ID was applied,
+// e.g. to enable features depending on it. Like Pending, it never creates
+// the migration table: false is returned if it does not exist.
+func (x *Xormigrate) MigrationRan(id string) (bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	initialized, err := x.initialized()
+	if err != nil || !initialized {
+		return false, err
+	}
+	return x.migrationRan(&Migration{ID: id})
+}
+
+// AppliedIDs returns the sorted IDs of the applied migrations, or none if
+// the migration table does not exist.
+func (x *Xormigrate) AppliedIDs() ([]string, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	initialized, err := x.initialized()
+	if err != nil || !initialized {
+		return nil, err
+	}
+	records, err := x.backend.listRecords()
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, record := range records {
+		if record.ID != initSchemaMigrationID {
+			ids = append(ids, record.ID)
+		}
+	}
+	return ids, nil
+}
+
+// UnknownMigrations returns the sorted IDs of the applied migrations which are
+// not defined, e.g. because they were applied by a newer version of the
+// application, or none if the migration table does not exist. They make runs
+// fail with Options.ValidateUnknownMigrations.
+func (x *Xormigrate) UnknownMigrations() ([]string, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	initialized, err := x.initialized()
+	if err != nil || !initialized {
+		return nil, err
+	}
+	return x.unknownMigrations()
+}
+
+// Status describes the migrations of the database, see Xormigrate.Status.
+type Status struct {
+	// Applied are the applied migrations, in order.
+	Applied []MigrationStatus
+	// Pending are the migrations that did not run yet, in order.
+	Pending []MigrationStatus
+	// Unknown are the migrations found in the migration table that are
+	// not defined, sorted by ID.
+	Unknown []MigrationStatus
+}
+
+// MigrationStatus describes a migration of the database.
+type MigrationStatus struct {
+	ID string
+	// Migration is the definition of the migration, nil if it is unknown.
+	Migration *Migration
+	// AppliedAt is when the migration was applied, if
+	// Options.RecordAppliedAt was set then.
+	AppliedAt time.Time
+	// Duration is how long the migration took to apply, if
+	// Options.RecordDuration was set then.
+	Duration time.Duration
+	// Skipped reports whether the migration was recorded without running,
+	// e.g. because of its Dialects, and SkipReason why, if
+	// Options.RecordStatus was set then.
+	Skipped    bool
+	SkipReason string
+	// OutOfOrder reports whether the migration comes before an applied
+	// one while pending, or was applied so, if Options.AllowOutOfOrder was
+	// set then.
+	OutOfOrder bool
+	// CompletedSteps are the steps of a pending migration completed by an
+	// interrupted run, see Migration.Steps.
+	CompletedSteps []string
+}
+
+// Status returns the applied, pending and unknown migrations. Like Pending,
+// it is strictly read-only: ErrNotInitialized is returned if the migration
+// table does not exist.
+func (x *Xormigrate) Status() (*Status, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	initialized, err := x.initialized()
+	if err != nil {
+		return nil, err
+	}
+	if !initialized {
+		return nil, ErrNotInitialized
+	}
+	records, err := x.backend.listRecords()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]migrationRecord, len(records))
+	for _, record := range records {
+		byID[record.ID] = record
+	}
+
+	outOfOrder, err := x.outOfOrderMigrations(func(m *Migration) (bool, error) {
+		_, applied := byID[m.ID]
+		return applied, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{}
+	for _, migration := range x.migrations {
+		record, applied := byID[migration.ID]
+		delete(byID, migration.ID)
+		if !applied {
+			steps, err := x.backend.completedSteps(migration.ID)
+			if err != nil {
+				return nil, err
+			}
+			status.Pending = append(status.Pending, MigrationStatus{
+				ID:             migration.ID,
+				Migration:      migration,
+				OutOfOrder:     outOfOrder[migration.ID] != nil,
+				CompletedSteps: steps,
+			})
+			continue
+		}
+		status.Applied = append(status.Applied, recordStatus(record, migration))
+	}
+	for _, record := range records {
+		if _, unknown := byID[record.ID]; unknown && record.ID != initSchemaMigrationID {
+			status.Unknown = append(status.Unknown, recordStatus(record, nil))
+		}
+	}
+	return status, nil
+}
+
+func recordStatus(record migrationRecord, migration *Migration) MigrationStatus {
+	status := MigrationStatus{
+		ID:         record.ID,
+		Migration:  migration,
+		Skipped:    record.Status == statusSkipped,
+		SkipReason: record.SkipReason,
+		OutOfOrder: record.OutOfOrder,
+	}
+	if record.AppliedAt != nil {
+		status.AppliedAt = *record.AppliedAt
+	}
+	status.Duration = time.Duration(record.DurationMS) * time.Millisecond
+	return status
+}
@@ -0,0 +1,169 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestReadOnly(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		ro := New(db.NewSession(), &Options{
+			TableName: "migration",
+			ReadOnly:  true,
+		}, migrations)
+
+		initialized, err := ro.Initialized()
+		assert.NoError(t, err)
+		assert.False(t, initialized)
+		_, err = ro.Pending()
+		assert.Equal(t, ErrNotInitialized, err)
+		assert.Equal(t, ErrReadOnly, ro.Migrate())
+		assert.Equal(t, ErrReadOnly, ro.RollbackLast())
+
+		has, err := db.IsTableExist("migration")
+		assert.NoError(t, err)
+		assert.False(t, has)
+
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, migrations)
+		assert.NoError(t, m.MigrateTo("201608301400"))
+
+		initialized, err = ro.Initialized()
+		assert.NoError(t, err)
+		assert.True(t, initialized)
+		pending, err := ro.Pending()
+		assert.NoError(t, err)
+		if assert.Len(t, pending, 1) {
+			assert.Equal(t, "201608301430", pending[0].ID)
+		}
+	})
+}
+
+func TestConcurrentUse(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName:      "migration",
+			UseTransaction: true,
+		}, migrations)
+		assert.NoError(t, m.MigrateTo("201608301400"))
+
+		done := make(chan error)
+		go func() {
+			done <- m.Migrate()
+		}()
+		for i := 0; i < 10; i++ {
+			_, err := m.Pending()
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, <-done)
+
+		pending, err := m.Pending()
+		assert.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+}
+
+func TestMigrationRan(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+
+		ran, err := m.MigrationRan("201608301400")
+		assert.NoError(t, err)
+		assert.False(t, ran)
+		ids, err := m.AppliedIDs()
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+
+		assert.NoError(t, m.MigrateTo("201608301400"))
+
+		ran, err = m.MigrationRan("201608301400")
+		assert.NoError(t, err)
+		assert.True(t, ran)
+		ran, err = m.MigrationRan("201608301430")
+		assert.NoError(t, err)
+		assert.False(t, ran)
+		ids, err = m.AppliedIDs()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"201608301400"}, ids)
+	})
+}
+
+func TestStatus(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		appliedAt := time.Date(2016, 8, 30, 14, 0, 0, 0, time.Local)
+		options := &Options{
+			TableName:       "migration",
+			RecordAppliedAt: true,
+			RecordStatus:    true,
+			Clock:           ClockFunc(func() time.Time { return appliedAt }),
+		}
+		m := New(db.NewSession(), options, migrations)
+		_, err := m.Status()
+		assert.Equal(t, ErrNotInitialized, err)
+
+		assert.NoError(t, m.MigrateTo("201608301400"))
+		_, err = db.Table("migration").Insert(&migrationRecord{ID: "201601010000"})
+		assert.NoError(t, err)
+
+		status, err := m.Status()
+		assert.NoError(t, err)
+		if assert.Len(t, status.Applied, 1) {
+			applied := status.Applied[0]
+			assert.Equal(t, "201608301400", applied.ID)
+			assert.Same(t, migrations[0], applied.Migration)
+			assert.False(t, applied.Skipped)
+			assert.True(t, applied.AppliedAt.Equal(appliedAt), applied.AppliedAt)
+		}
+		if assert.Len(t, status.Pending, 1) {
+			assert.Equal(t, "201608301430", status.Pending[0].ID)
+			assert.True(t, status.Pending[0].AppliedAt.IsZero())
+		}
+		if assert.Len(t, status.Unknown, 1) {
+			assert.Equal(t, "201601010000", status.Unknown[0].ID)
+			assert.Nil(t, status.Unknown[0].Migration)
+		}
+	})
+}
+
+func TestStatusSkipped(t *testing.T) {
+	backend := &FakeBackend{Dialect: "postgres"}
+	m := NewFake(backend, &Options{RecordStatus: true}, []*Migration{
+		{ID: "201608301400", Dialects: []string{"mysql"}},
+	})
+	assert.NoError(t, m.Migrate())
+
+	status, err := m.Status()
+	assert.NoError(t, err)
+	if assert.Len(t, status.Applied, 1) {
+		assert.True(t, status.Applied[0].Skipped)
+		assert.Equal(t, "dialect postgres is not one of mysql", status.Applied[0].SkipReason)
+	}
+	assert.Empty(t, status.Pending)
+}
+
+func TestUnknownMigrations(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		newer := New(db.NewSession(), &Options{TableName: "migration"}, extendedMigrations)
+		unknown, err := newer.UnknownMigrations()
+		assert.NoError(t, err)
+		assert.Empty(t, unknown)
+		assert.NoError(t, newer.Migrate())
+
+		older := New(db.NewSession(), &Options{
+			TableName:                 "migration",
+			ValidateUnknownMigrations: true,
+		}, migrations[:1])
+		unknown, err = older.UnknownMigrations()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"201608301430", "201807221927"}, unknown)
+
+		err = older.Migrate()
+		assert.True(t, errors.Is(err, ErrUnknownPastMigration))
+		assert.EqualError(t, err, "xormigrate: Found migrations in DB that do not exist in code: 201608301430, 201807221927")
+	})
+}
@@ -0,0 +1,96 @@
+package xormigrate
+
+import (
+	"fmt"
+	"time"
+)
+
+// Step is a named part of a migration, see Migration.Steps.
+type Step struct {
+	Name    string
+	Migrate MigrateFunc
+}
+
+// stepRecord is a row of the step table, recording that a step of a
+// migration being applied completed.
+type stepRecord struct {
+	MigrationID string `xorm:"VARCHAR(50) notnull pk 'migration_id'"`
+	Step        string `xorm:"VARCHAR(255) notnull pk 'step'"`
+}
+
+// DuplicatedStepError is returned when a migration has several steps with
+// the same name
+type DuplicatedStepError struct {
+	ID   string
+	Step string
+}
+
+func (e *DuplicatedStepError) Error() string {
+	return fmt.Sprintf(`xormigrate: Duplicated step "%s" in migration "%s"`, e.Step, e.ID)
+}
+
+// CompletedSteps returns the names of the completed steps of a migration
+// that was interrupted, which the next run skips. It is empty once the
+// migration is applied.
+func (x *Xormigrate) CompletedSteps(migrationID string) ([]string, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	defer x.openSession()()
+
+	return x.backend.completedSteps(migrationID)
+}
+
+func (x *Xormigrate) checkSteps() error {
+	for _, m := range x.migrations {
+		names := make(map[string]bool, len(m.Steps))
+		for _, step := range m.Steps {
+			if names[step.Name] {
+				return &DuplicatedStepError{ID: m.ID, Step: step.Name}
+			}
+			names[step.Name] = true
+		}
+	}
+	return nil
+}
+
+// hasSteps reports whether a migration has steps, requiring the step table.
+func (x *Xormigrate) hasSteps() bool {
+	for _, migration := range x.migrations {
+		if len(migration.Steps) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// runMigrate runs the migration function of m, or its steps that did not
+// complete yet, recording each one as it completes.
+func (x *Xormigrate) runMigrate(m *Migration) error {
+	if m.RollbackOnly {
+		return nil
+	}
+	if len(m.Steps) == 0 {
+		if m.Migrate == nil && m.MigrateContext == nil {
+			return x.runSQL(m)
+		}
+		return m.migrateFunc()(x.session)
+	}
+	completed, err := x.backend.completedSteps(m.ID)
+	if err != nil {
+		return err
+	}
+	for _, step := range m.Steps {
+		if contains(completed, step.Name) {
+			continue
+		}
+		start := time.Now()
+		if err := step.Migrate(x.session); err != nil {
+			return err
+		}
+		if err := x.backend.insertStep(m.ID, step.Name); err != nil {
+			return err
+		}
+		x.emit(&StepApplied{ID: m.ID, Step: step.Name, Duration: time.Since(start)})
+	}
+	return nil
+}
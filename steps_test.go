@@ -0,0 +1,51 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestSteps(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		assert.NoError(t, db.DropTables("migration_steps"))
+		errFailed := errors.New("failed")
+		runs := map[string]int{}
+		step := func(name string, failures int) Step {
+			return Step{Name: name, Migrate: func(tx *xorm.Session) error {
+				runs[name]++
+				if runs[name] <= failures {
+					return errFailed
+				}
+				return nil
+			}}
+		}
+		m := New(db.NewSession(), &Options{TableName: "migration"}, []*Migration{{
+			ID:    "201608301400",
+			Steps: []Step{step("create", 0), step("backfill", 1), step("index", 0)},
+		}})
+
+		assert.ErrorIs(t, m.Migrate(), errFailed)
+		steps, err := m.CompletedSteps("201608301400")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"create"}, steps)
+
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, map[string]int{"create": 1, "backfill": 2, "index": 1}, runs)
+		steps, err = m.CompletedSteps("201608301400")
+		assert.NoError(t, err)
+		assert.Empty(t, steps)
+	})
+}
+
+func TestDuplicatedStep(t *testing.T) {
+	noop := func(tx *xorm.Session) error { return nil }
+	m := NewFake(&FakeBackend{}, &Options{}, []*Migration{{
+		ID:    "201608301400",
+		Steps: []Step{{Name: "backfill", Migrate: noop}, {Name: "backfill", Migrate: noop}},
+	}})
+	var duplicatedErr *DuplicatedStepError
+	assert.True(t, errors.As(m.Migrate(), &duplicatedErr))
+}
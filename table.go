@@ -0,0 +1,175 @@
+package xormigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"xorm.io/xorm/schemas"
+)
+
+// migrationRecord is a row of the migration table. Only the ID column is
+// mandatory, the other ones are written when the matching option is set.
+type migrationRecord struct {
+	ID           string     `xorm:"VARCHAR(50) notnull pk 'id'" json:"id"`
+	BuildVersion string     `xorm:"VARCHAR(255) 'build_version'" json:"build_version,omitempty"`
+	Host         string     `xorm:"VARCHAR(255) 'host'" json:"host,omitempty"`
+	Approval     string     `xorm:"TEXT 'approval'" json:"approval,omitempty"`
+	Checksum     string     `xorm:"VARCHAR(64) 'checksum'" json:"checksum,omitempty"`
+	OverBudget   bool       `xorm:"'over_budget'" json:"over_budget,omitempty"`
+	Status       string     `xorm:"VARCHAR(20) 'status'" json:"status,omitempty"`
+	SkipReason   string     `xorm:"VARCHAR(255) 'skip_reason'" json:"skip_reason,omitempty"`
+	RunID        string     `xorm:"VARCHAR(32) 'run_id'" json:"run_id,omitempty"`
+	AppliedAt    *time.Time `xorm:"'applied_at'" json:"applied_at,omitempty"`
+	DurationMS   int64      `xorm:"'duration_ms'" json:"duration_ms,omitempty"`
+	Touches      string     `xorm:"TEXT 'touches'" json:"touches,omitempty"`
+	OutOfOrder   bool       `xorm:"'out_of_order'" json:"out_of_order,omitempty"`
+}
+
+// The statuses of the migration records, see Options.RecordStatus.
+const (
+	statusApplied = "applied"
+	statusSkipped = "skipped"
+)
+
+// TableOptions customize the creation of the migration table. They have no
+// effect once the table exists or when Options.CreateTableSQL is set, and
+// options specific to a database are ignored on the others.
+type TableOptions struct {
+	// StoreEngine is the MySQL storage engine, e.g. "InnoDB".
+	StoreEngine string
+	// Charset is the MySQL default character set, e.g. "utf8mb4".
+	Charset string
+	// RowFormat is the MySQL row format, e.g. "DYNAMIC".
+	RowFormat string
+	// Tablespace is the PostgreSQL tablespace the table is moved to.
+	Tablespace string
+	// Indexes are additional indexes to create, each one listing the
+	// columns it covers.
+	Indexes [][]string
+}
+
+func (b *sessionBackend) createTable() error {
+	x := b.x
+	if x.options.CreateTableSQL != nil {
+		script := x.options.CreateTableSQL(b.dialect(), x.options.TableName)
+		for _, statement := range splitStatements(script) {
+			if _, err := x.session.Exec(statement); err != nil {
+				return err
+			}
+		}
+		return b.upgradeTable()
+	}
+	opts := x.options.TableOptions
+	session := x.session.Table(x.options.TableName)
+	if opts.StoreEngine != "" {
+		session = session.StoreEngine(opts.StoreEngine)
+	}
+	if opts.Charset != "" {
+		session = session.Charset(opts.Charset)
+	}
+	if err := session.Sync2(&migrationRecord{}); err != nil {
+		return err
+	}
+
+	dialect := x.session.Engine().Dialect()
+	quote := dialect.Quoter().Quote
+	var sqls []string
+	switch dialect.URI().DBType {
+	case schemas.MYSQL:
+		if opts.RowFormat != "" {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s ROW_FORMAT=%s", quote(x.options.TableName), opts.RowFormat))
+		}
+	case schemas.POSTGRES:
+		if opts.Tablespace != "" {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s SET TABLESPACE %s", quote(x.options.TableName), quote(opts.Tablespace)))
+		}
+	}
+	for _, cols := range opts.Indexes {
+		index := schemas.NewIndex(strings.Join(cols, "_"), schemas.IndexType)
+		index.AddColumn(cols...)
+		sqls = append(sqls, dialect.CreateIndexSQL(x.options.TableName, index))
+	}
+	for _, sql := range sqls {
+		if _, err := x.session.Exec(sql); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordColumns returns the columns written when inserting a migration record.
+func (x *Xormigrate) recordColumns() []string {
+	cols := []string{"id"}
+	if x.options.RecordBuildVersion {
+		cols = append(cols, "build_version")
+	}
+	if x.options.RecordHost {
+		cols = append(cols, "host")
+	}
+	if x.options.ApprovalVerifier != nil {
+		cols = append(cols, "approval")
+	}
+	if x.options.RecordChecksum || x.hasRepeatables() {
+		cols = append(cols, "checksum")
+	}
+	if x.hasBudgets() {
+		cols = append(cols, "over_budget")
+	}
+	if x.options.RecordStatus {
+		cols = append(cols, "status", "skip_reason")
+	}
+	if x.options.RecordRunID {
+		cols = append(cols, "run_id")
+	}
+	if x.options.RecordAppliedAt {
+		cols = append(cols, "applied_at")
+	}
+	if x.options.RecordDuration {
+		cols = append(cols, "duration_ms")
+	}
+	if x.options.RecordTouches {
+		cols = append(cols, "touches")
+	}
+	if x.options.AllowOutOfOrder {
+		cols = append(cols, "out_of_order")
+	}
+	return cols
+}
+
+// hasBudgets reports whether a migration has a Budget, requiring the
+// "over_budget" column.
+func (x *Xormigrate) hasBudgets() bool {
+	for _, migration := range x.migrations {
+		if migration.Budget > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// upgradeTable adds the columns required by the enabled options to a
+// migration table created before they were introduced.
+func (b *sessionBackend) upgradeTable() error {
+	x := b.x
+	engine := x.session.Engine()
+	table, err := engine.TableInfo(&migrationRecord{})
+	if err != nil {
+		return err
+	}
+	dialect := engine.Dialect()
+	for _, name := range x.recordColumns()[1:] {
+		exist, err := dialect.IsColumnExist(x.session.DB(), context.Background(), x.options.TableName, name)
+		if err != nil {
+			return err
+		}
+		if exist {
+			continue
+		}
+		if _, err := x.session.Exec(dialect.AddColumnSQL(x.options.TableName, table.GetColumn(name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
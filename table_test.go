@@ -0,0 +1,164 @@
+package xormigrate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestTableOptionsIndexes(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+			TableOptions: TableOptions{
+				StoreEngine: "InnoDB",
+				Indexes:     [][]string{{"id"}},
+			},
+		}, migrations)
+		assert.NoError(t, m.Migrate())
+
+		indexes, err := db.Dialect().GetIndexes(db.DB(), context.Background(), "migration")
+		assert.NoError(t, err)
+		assert.Contains(t, indexes, "id")
+		assert.Equal(t, int64(2), tableCount(t, db))
+	})
+}
+
+func TestRecordBuildVersion(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		// Start with a migration table lacking the build_version column.
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, migrations)
+		assert.NoError(t, m.MigrateTo("201608301400"))
+		assert.NoError(t, db.DropTables("migration"))
+		assert.NoError(t, db.Table("migration").Sync2(&Migration{}))
+
+		m = New(db.NewSession(), &Options{
+			TableName:          "migration",
+			RecordBuildVersion: true,
+		}, migrations)
+		assert.NoError(t, m.Migrate())
+
+		var record migrationRecord
+		has, err := db.Table("migration").Cols("id", "build_version").ID("201608301430").Get(&record)
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.Equal(t, BuildVersion(), record.BuildVersion)
+	})
+}
+
+func TestRecordHost(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName:  "migration",
+			RecordHost: true,
+			HostResolver: func() (string, error) {
+				return "pod-1", nil
+			},
+		}, migrations)
+		assert.NoError(t, m.Migrate())
+
+		var records []migrationRecord
+		assert.NoError(t, db.Table("migration").Find(&records))
+		if assert.Len(t, records, 2) {
+			assert.Equal(t, "pod-1", records[0].Host)
+			assert.Equal(t, "pod-1", records[1].Host)
+		}
+	})
+}
+
+func TestRecordStatus(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		var skipped []*MigrationSkipped
+		m := New(db.NewSession(), &Options{
+			TableName:    "migration",
+			RecordStatus: true,
+		}, append(migrations, &Migration{
+			ID:       "201807221927",
+			Dialects: []string{"nodb"},
+			Migrate: func(tx *xorm.Session) error {
+				return nil
+			},
+		}))
+		m.AddListener(ListenerFunc(func(event Event) {
+			if e, ok := event.(*MigrationSkipped); ok {
+				skipped = append(skipped, e)
+			}
+		}))
+		assert.NoError(t, m.Migrate())
+
+		var records []migrationRecord
+		assert.NoError(t, db.Table("migration").Asc("id").Find(&records))
+		if assert.Len(t, records, 3) {
+			assert.Equal(t, "applied", records[0].Status)
+			assert.Empty(t, records[0].SkipReason)
+			assert.Equal(t, "skipped", records[2].Status)
+			assert.Equal(t, "dialect "+string(db.Dialect().URI().DBType)+" is not one of nodb", records[2].SkipReason)
+		}
+		if assert.Len(t, skipped, 1) {
+			assert.Equal(t, records[2].SkipReason, skipped[0].Reason)
+		}
+	})
+}
+
+func TestRecordTiming(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+		assert.NoError(t, m.MigrateTo("201608301400"))
+
+		// The columns are added to the existing table.
+		before := time.Now().Add(-time.Second)
+		m = New(db.NewSession(), &Options{
+			TableName:       "migration",
+			RecordAppliedAt: true,
+			RecordDuration:  true,
+		}, append(migrations[:2:2], &Migration{
+			ID: "201807221927",
+			Migrate: func(tx *xorm.Session) error {
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			},
+		}))
+		assert.NoError(t, m.Migrate())
+
+		var records []migrationRecord
+		assert.NoError(t, db.Table("migration").Asc("id").Find(&records))
+		if assert.Len(t, records, 3) {
+			assert.Nil(t, records[0].AppliedAt)
+			assert.Zero(t, records[0].DurationMS)
+			if assert.NotNil(t, records[1].AppliedAt) {
+				assert.True(t, records[1].AppliedAt.After(before))
+			}
+			assert.GreaterOrEqual(t, records[2].DurationMS, int64(20))
+		}
+	})
+}
+
+func TestCreateTableSQL(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		var dialect string
+		m := New(db.NewSession(), &Options{
+			TableName:      "migration",
+			RecordChecksum: true,
+			CreateTableSQL: func(d, tableName string) string {
+				dialect = d
+				return "CREATE TABLE " + tableName + " (id VARCHAR(50) NOT NULL PRIMARY KEY, created_by VARCHAR(50) NULL)"
+			},
+		}, migrations)
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, string(db.Dialect().URI().DBType), dialect)
+
+		tx := db.NewSession()
+		defer tx.Close()
+		for _, column := range []string{"created_by", "checksum"} {
+			exists, err := HasColumn(tx, "migration", column)
+			assert.NoError(t, err)
+			assert.True(t, exists, column)
+		}
+		assert.Equal(t, int64(2), tableCount(t, db))
+	})
+}
@@ -0,0 +1,491 @@
+package xormigrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Direction is the direction of a run: Up applies migrations, Down rolls
+// them back.
+type Direction int
+
+// The directions of Run.
+const (
+	Up Direction = iota
+	Down
+)
+
+func (d Direction) String() string {
+	if d == Down {
+		return "down"
+	}
+	return "up"
+}
+
+type targetKind int
+
+const (
+	targetLatest targetKind = iota
+	targetID
+	targetSteps
+	targetTime
+	targetTag
+)
+
+// Target selects the migrations of a run, see Run.
+type Target struct {
+	kind  targetKind
+	id    string
+	steps int
+	time  time.Time
+	tag   string
+}
+
+// TargetLatest targets all the migrations.
+var TargetLatest = Target{}
+
+// TargetID targets the migration with the given ID: going up, the migrations
+// up to it are applied, going down, those after it are rolled back.
+func TargetID(id string) Target {
+	return Target{kind: targetID, id: id}
+}
+
+// TargetSteps targets n migrations: going up, the next n pending ones are
+// applied, going down, the last n applied ones are rolled back.
+func TargetSteps(n int) Target {
+	return Target{kind: targetSteps, steps: n}
+}
+
+// TargetTime targets the migrations by the timestamp their ID starts with,
+// e.g. "201608301400": going up, those up to t are applied, going down,
+// those after t are rolled back.
+func TargetTime(t time.Time) Target {
+	return Target{kind: targetTime, time: t}
+}
+
+// TargetTag targets the migrations with the tag: going up, they are applied
+// along with the migrations they depend on, going down, they are rolled back.
+func TargetTag(tag string) Target {
+	return Target{kind: targetTag, tag: tag}
+}
+
+func (t Target) String() string {
+	switch t.kind {
+	case targetID:
+		return "id " + t.id
+	case targetSteps:
+		return fmt.Sprintf("%d steps", t.steps)
+	case targetTime:
+		return "time " + t.time.Format(time.RFC3339)
+	case targetTag:
+		return "tag " + t.tag
+	}
+	return "latest"
+}
+
+// ErrInvalidTarget is returned by Run when the target makes no sense in the
+// direction, e.g. TargetLatest going down or a negative number of steps.
+var ErrInvalidTarget = errors.New("xormigrate: Invalid target for this direction")
+
+// TimestampError is returned when a migration is targeted by time but its ID
+// doesn't start with a timestamp.
+type TimestampError struct {
+	ID string
+}
+
+func (e *TimestampError) Error() string {
+	return fmt.Sprintf(`xormigrate: Migration ID "%s" does not start with a timestamp`, e.ID)
+}
+
+// Run applies or rolls back the migrations selected by target, e.g.
+// Run(Up, TargetLatest) is Migrate and Run(Down, TargetSteps(1)) is
+// RollbackLast.
+func (x *Xormigrate) Run(direction Direction, target Target) error {
+	_, err := x.withResult(nil, func() error {
+		return x.run(direction, target)
+	})
+	return err
+}
+
+// RunContext is like Run, with a context, see MigrateContext.
+func (x *Xormigrate) RunContext(ctx context.Context, direction Direction, target Target) error {
+	_, err := x.withResult(ctx, func() error {
+		return x.run(direction, target)
+	})
+	return err
+}
+
+// StepsError is returned by Up and Down when fewer than the requested number
+// of migrations can be applied or rolled back. Nothing is run then.
+type StepsError struct {
+	Direction Direction
+	Steps     int
+	Available int
+}
+
+func (e *StepsError) Error() string {
+	state := "pending"
+	if e.Direction == Down {
+		state = "applied"
+	}
+	return fmt.Sprintf("xormigrate: Can't go %s %d steps, only %d migrations are %s", e.Direction, e.Steps, e.Available, state)
+}
+
+// Up applies exactly the next n pending migrations. Unlike
+// Run(Up, TargetSteps(n)), a *StepsError is returned if fewer are pending.
+func (x *Xormigrate) Up(n int) error {
+	_, err := x.withResult(nil, func() error {
+		return x.steps(Up, n)
+	})
+	return err
+}
+
+// Down rolls back exactly the last n applied migrations. Unlike
+// Run(Down, TargetSteps(n)), a *StepsError is returned if fewer are applied.
+func (x *Xormigrate) Down(n int) error {
+	_, err := x.withResult(nil, func() error {
+		return x.steps(Down, n)
+	})
+	return err
+}
+
+func (x *Xormigrate) steps(direction Direction, n int) error {
+	target := TargetSteps(n)
+	if err := target.check(direction); err != nil {
+		return err
+	}
+	if direction == Down {
+		return x.rollbackSelected("Down", func(applied []*Migration) ([]*Migration, error) {
+			if len(applied) < n {
+				return nil, &StepsError{Direction: Down, Steps: n, Available: len(applied)}
+			}
+			return applied[len(applied)-n:], nil
+		})
+	}
+	selectPlan := selectUp(target, x.migrationRan)
+	return x.migrate("", func(plan []*Migration) ([]*Migration, error) {
+		selected, err := selectPlan(plan)
+		if err != nil {
+			return nil, err
+		}
+		if len(selected) < n {
+			return nil, &StepsError{Direction: Up, Steps: n, Available: len(selected)}
+		}
+		return selected, nil
+	})
+}
+
+// Goto brings the database to the migration matching migrationID, like a
+// "go to version" command: the migrations after it are rolled back, see
+// RollbackTo, then the pending ones up to it are applied, see MigrateTo.
+func (x *Xormigrate) Goto(migrationID string) error {
+	_, err := x.withResult(nil, func() error {
+		return x.goTo(migrationID)
+	})
+	return err
+}
+
+func (x *Xormigrate) goTo(migrationID string) error {
+	if err := x.checkIDExist(migrationID); err != nil {
+		return err
+	}
+	ahead, err := x.appliedAfter(migrationID)
+	if err != nil {
+		return err
+	}
+	if ahead {
+		if err := x.rollbackTo(migrationID); err != nil {
+			return err
+		}
+	}
+	return x.migrateTo(migrationID)
+}
+
+// appliedAfter reports whether a migration defined after the one matching
+// migrationID is applied.
+func (x *Xormigrate) appliedAfter(migrationID string) (bool, error) {
+	initialized, err := x.initialized()
+	if err != nil || !initialized {
+		return false, err
+	}
+	for i := len(x.migrations) - 1; i >= 0 && x.migrations[i].ID != migrationID; i-- {
+		if x.migrations[i].Repeatable {
+			continue
+		}
+		migrationRan, err := x.migrationRan(x.migrations[i])
+		if err != nil || migrationRan {
+			return migrationRan, err
+		}
+	}
+	return false, nil
+}
+
+func (x *Xormigrate) run(direction Direction, target Target) error {
+	if direction == Down {
+		return x.runDown(target)
+	}
+	return x.runUp(target)
+}
+
+func (x *Xormigrate) runUp(target Target) error {
+	if err := target.check(Up); err != nil {
+		return err
+	}
+	switch target.kind {
+	case targetLatest:
+		return x.migrateAll()
+	case targetID:
+		return x.migrateTo(target.id)
+	}
+	return x.migrate("", selectUp(target, x.migrationRan))
+}
+
+func (x *Xormigrate) runDown(target Target) error {
+	if err := target.check(Down); err != nil {
+		return err
+	}
+	if target.kind == targetID {
+		return x.rollbackTo(target.id)
+	}
+	return x.rollbackSelected("Run", x.selectDown(target))
+}
+
+// check returns ErrInvalidTarget if the target makes no sense in the
+// direction.
+func (t Target) check(direction Direction) error {
+	if t.kind == targetSteps && t.steps < 0 {
+		return ErrInvalidTarget
+	}
+	if t.kind == targetLatest && direction == Down {
+		return ErrInvalidTarget
+	}
+	return nil
+}
+
+// selectUp returns the selection of the migrations of a run up to target
+// among the run order, or nil if they all are. ran reports whether a
+// migration was applied.
+func selectUp(target Target, ran func(m *Migration) (bool, error)) func(plan []*Migration) ([]*Migration, error) {
+	switch target.kind {
+	case targetSteps:
+		return func(plan []*Migration) ([]*Migration, error) {
+			var selected []*Migration
+			for _, migration := range plan {
+				if len(selected) == target.steps {
+					break
+				}
+				migrationRan, err := ran(migration)
+				if err != nil {
+					return nil, err
+				}
+				if !migrationRan {
+					selected = append(selected, migration)
+				}
+			}
+			return selected, nil
+		}
+	case targetTime:
+		return func(plan []*Migration) ([]*Migration, error) {
+			var selected []*Migration
+			for _, migration := range plan {
+				before, err := migrationBefore(migration, target.time)
+				if err != nil {
+					return nil, err
+				}
+				if before {
+					selected = append(selected, migration)
+				}
+			}
+			return selected, nil
+		}
+	case targetTag:
+		return func(plan []*Migration) ([]*Migration, error) {
+			return withDependencies(plan, func(m *Migration) bool {
+				return contains(m.Tags, target.tag)
+			}), nil
+		}
+	}
+	return nil
+}
+
+// selectDown returns the selection of the migrations of a run down to target
+// among the applied ones, in definition order, or nil for TargetLatest.
+func (x *Xormigrate) selectDown(target Target) func(applied []*Migration) ([]*Migration, error) {
+	switch target.kind {
+	case targetID:
+		return func(applied []*Migration) ([]*Migration, error) {
+			after := make(map[string]bool, len(x.migrations))
+			for i := len(x.migrations) - 1; i >= 0 && x.migrations[i].ID != target.id; i-- {
+				after[x.migrations[i].ID] = true
+			}
+			var selected []*Migration
+			for _, migration := range applied {
+				if after[migration.ID] {
+					selected = append(selected, migration)
+				}
+			}
+			return selected, nil
+		}
+	case targetSteps:
+		return func(applied []*Migration) ([]*Migration, error) {
+			if len(applied) > target.steps {
+				applied = applied[len(applied)-target.steps:]
+			}
+			return applied, nil
+		}
+	case targetTime:
+		return func(applied []*Migration) ([]*Migration, error) {
+			var selected []*Migration
+			for _, migration := range applied {
+				before, err := migrationBefore(migration, target.time)
+				if err != nil {
+					return nil, err
+				}
+				if !before {
+					selected = append(selected, migration)
+				}
+			}
+			return selected, nil
+		}
+	case targetTag:
+		return func(applied []*Migration) ([]*Migration, error) {
+			var selected []*Migration
+			for _, migration := range applied {
+				if contains(migration.Tags, target.tag) {
+					selected = append(selected, migration)
+				}
+			}
+			return selected, nil
+		}
+	}
+	return nil
+}
+
+// rollbackSelected rolls back, in reverse order, the migrations selected
+// among the applied ones, checking the protection of operation. A
+// *DependentError is returned, before rolling back anything, if an applied
+// migration not selected depends on one of them.
+func (x *Xormigrate) rollbackSelected(operation string, selectApplied func(applied []*Migration) ([]*Migration, error)) (err error) {
+	if len(x.migrations) == 0 {
+		return ErrNoMigrationDefined
+	}
+	if err := x.checkWritable(); err != nil {
+		return err
+	}
+	if err := x.checkProtection(operation); err != nil {
+		return err
+	}
+	x.emitRunStarted(true)
+	defer x.emitRunFinished(true, time.Now(), &err)
+
+	defer x.end()
+	if err := x.begin(); err != nil {
+		return err
+	}
+
+	var applied []*Migration
+	for _, migration := range x.migrations {
+		if migration.Repeatable {
+			continue
+		}
+		migrationRan, err := x.migrationRan(migration)
+		if err != nil {
+			return err
+		}
+		if migrationRan {
+			applied = append(applied, migration)
+		}
+	}
+	selected, err := selectApplied(applied)
+	if err != nil {
+		return err
+	}
+	rolledBack := make(map[string]bool, len(selected))
+	for _, migration := range selected {
+		rolledBack[migration.ID] = true
+	}
+	for _, migration := range applied {
+		if rolledBack[migration.ID] {
+			continue
+		}
+		for _, id := range migration.DependsOn {
+			if rolledBack[id] {
+				return &DependentError{ID: id, Dependent: migration.ID}
+			}
+		}
+	}
+	for i := len(selected) - 1; i >= 0; i-- {
+		if err := x.context().Err(); err != nil {
+			return err
+		}
+		if err := x.rollbackMigration(selected[i]); err != nil {
+			return err
+		}
+		if err := x.checkTransactionAge(); err != nil {
+			return err
+		}
+	}
+	return x.finish()
+}
+
+// withDependencies returns the migrations of plan matching include, along
+// with the migrations they depend on, in the order of plan.
+func withDependencies(plan []*Migration, include func(*Migration) bool) []*Migration {
+	byID := make(map[string]*Migration, len(plan))
+	for _, migration := range plan {
+		byID[migration.ID] = migration
+	}
+	included := make(map[string]bool)
+	var add func(m *Migration)
+	add = func(m *Migration) {
+		if included[m.ID] {
+			return
+		}
+		included[m.ID] = true
+		for _, id := range m.DependsOn {
+			if dependency, ok := byID[id]; ok {
+				add(dependency)
+			}
+		}
+	}
+	for _, migration := range plan {
+		if include(migration) {
+			add(migration)
+		}
+	}
+	var selected []*Migration
+	for _, migration := range plan {
+		if included[migration.ID] {
+			selected = append(selected, migration)
+		}
+	}
+	return selected
+}
+
+// idTimestampLayouts are the layouts of the timestamps starting migration
+// IDs, by length.
+var idTimestampLayouts = map[int]string{
+	8:  "20060102",
+	12: "200601021504",
+	14: "20060102150405",
+}
+
+// migrationBefore reports whether the timestamp starting the ID of the
+// migration, in UTC, is not after t.
+func migrationBefore(m *Migration, t time.Time) (bool, error) {
+	digits := 0
+	for digits < len(m.ID) && m.ID[digits] >= '0' && m.ID[digits] <= '9' {
+		digits++
+	}
+	layout, ok := idTimestampLayouts[digits]
+	if !ok {
+		return false, &TimestampError{ID: m.ID}
+	}
+	timestamp, err := time.Parse(layout, m.ID[:digits])
+	if err != nil {
+		return false, &TimestampError{ID: m.ID}
+	}
+	return !timestamp.After(t), nil
+}
@@ -0,0 +1,148 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func targetMigrations() []*Migration {
+	stub := func(id string, tags ...string) *Migration {
+		return &Migration{
+			ID:       id,
+			Tags:     tags,
+			Migrate:  func(tx *xorm.Session) error { return nil },
+			Rollback: func(tx *xorm.Session) error { return nil },
+		}
+	}
+	migrations := []*Migration{
+		stub("201608301400"),
+		stub("201608301430", "expand"),
+		stub("201609011200"),
+		stub("201609021200", "expand"),
+	}
+	migrations[3].DependsOn = []string{"201609011200"}
+	return migrations
+}
+
+func TestRunUp(t *testing.T) {
+	for _, test := range []struct {
+		target  Target
+		applied []string
+	}{
+		{TargetLatest, []string{"201608301400", "201608301430", "201609011200", "201609021200"}},
+		{TargetID("201608301430"), []string{"201608301400", "201608301430"}},
+		{TargetSteps(3), []string{"201608301400", "201608301430", "201609011200"}},
+		{TargetTime(time.Date(2016, 8, 31, 0, 0, 0, 0, time.UTC)), []string{"201608301400", "201608301430"}},
+		{TargetTag("expand"), []string{"201608301430", "201609011200", "201609021200"}},
+	} {
+		t.Run(test.target.String(), func(t *testing.T) {
+			backend := &FakeBackend{}
+			m := NewFake(backend, &Options{}, targetMigrations())
+			assert.NoError(t, m.Run(Up, test.target))
+			assert.Equal(t, test.applied, backend.Applied())
+		})
+	}
+
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{}, targetMigrations())
+	assert.NoError(t, m.Run(Up, TargetSteps(1)))
+	assert.NoError(t, m.Run(Up, TargetSteps(1)))
+	assert.Equal(t, []string{"201608301400", "201608301430"}, backend.Applied())
+	assert.Equal(t, ErrInvalidTarget, m.Run(Up, TargetSteps(-1)))
+}
+
+func TestRunDown(t *testing.T) {
+	for _, test := range []struct {
+		target  Target
+		applied []string
+	}{
+		{TargetID("201608301430"), []string{"201608301400", "201608301430"}},
+		{TargetSteps(1), []string{"201608301400", "201608301430", "201609011200"}},
+		{TargetTime(time.Date(2016, 8, 31, 0, 0, 0, 0, time.UTC)), []string{"201608301400", "201608301430"}},
+		{TargetTag("expand"), []string{"201608301400", "201609011200"}},
+	} {
+		t.Run(test.target.String(), func(t *testing.T) {
+			backend := &FakeBackend{}
+			m := NewFake(backend, &Options{}, targetMigrations())
+			assert.NoError(t, m.Migrate())
+			assert.NoError(t, m.Run(Down, test.target))
+			assert.Equal(t, test.applied, backend.Applied())
+		})
+	}
+
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{}, targetMigrations())
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, ErrInvalidTarget, m.Run(Down, TargetLatest))
+
+	// 201609021200 depends on 201609011200.
+	migrations := targetMigrations()
+	migrations[2].Tags = []string{"contract"}
+	err := NewFake(backend, &Options{}, migrations).Run(Down, TargetTag("contract"))
+	var dependentErr *DependentError
+	if assert.True(t, errors.As(err, &dependentErr)) {
+		assert.Equal(t, &DependentError{ID: "201609011200", Dependent: "201609021200"}, dependentErr)
+	}
+}
+
+func TestRunTimestamp(t *testing.T) {
+	m := NewFake(&FakeBackend{}, &Options{}, []*Migration{
+		{ID: "add_users", Migrate: func(tx *xorm.Session) error { return nil }},
+	})
+	err := m.Run(Up, TargetTime(time.Now()))
+	var timestampErr *TimestampError
+	if assert.True(t, errors.As(err, &timestampErr)) {
+		assert.Equal(t, "add_users", timestampErr.ID)
+	}
+}
+
+func TestRunDatabase(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+		assert.NoError(t, m.Run(Up, TargetSteps(1)))
+		assert.Equal(t, int64(1), tableCount(t, db))
+		assert.NoError(t, m.Run(Up, TargetLatest))
+		assert.Equal(t, int64(2), tableCount(t, db))
+		assert.NoError(t, m.Run(Down, TargetSteps(2)))
+		assert.Equal(t, int64(0), tableCount(t, db))
+	})
+}
+
+func TestUpDown(t *testing.T) {
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{}, targetMigrations())
+	assert.NoError(t, m.Up(3))
+	assert.Equal(t, []string{"201608301400", "201608301430", "201609011200"}, backend.Applied())
+	assert.NoError(t, m.Down(2))
+	assert.Equal(t, []string{"201608301400"}, backend.Applied())
+
+	err := m.Up(4)
+	var stepsErr *StepsError
+	if assert.True(t, errors.As(err, &stepsErr)) {
+		assert.Equal(t, &StepsError{Direction: Up, Steps: 4, Available: 3}, stepsErr)
+	}
+	err = m.Down(2)
+	if assert.True(t, errors.As(err, &stepsErr)) {
+		assert.Equal(t, "xormigrate: Can't go down 2 steps, only 1 migrations are applied", err.Error())
+	}
+	assert.Equal(t, []string{"201608301400"}, backend.Applied())
+	assert.Equal(t, ErrInvalidTarget, m.Down(-1))
+}
+
+func TestGoto(t *testing.T) {
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{}, targetMigrations())
+	assert.NoError(t, m.Goto("201608301430"))
+	assert.Equal(t, []string{"201608301400", "201608301430"}, backend.Applied())
+	assert.NoError(t, m.Migrate())
+
+	assert.NoError(t, m.Goto("201608301400"))
+	assert.Equal(t, []string{"201608301400"}, backend.Applied())
+	assert.NoError(t, m.Goto("201609011200"))
+	assert.Equal(t, []string{"201608301400", "201608301430", "201609011200"}, backend.Applied())
+	assert.Equal(t, ErrMigrationIDDoesNotExist, m.Goto("unknown"))
+}
@@ -0,0 +1,35 @@
+package xormigrate
+
+import (
+	"fmt"
+	"time"
+)
+
+// LongTransactionError is returned when the run transaction has been open
+// for longer than Options.AbortTransactionAfter.
+type LongTransactionError struct {
+	Elapsed time.Duration
+	Limit   time.Duration
+}
+
+func (e *LongTransactionError) Error() string {
+	return fmt.Sprintf("xormigrate: Run transaction open for %s, over the limit of %s: consider running without UseTransaction to commit each migration", e.Elapsed, e.Limit)
+}
+
+// checkTransactionAge warns about or aborts a run whose transaction has been
+// open too long. It is called between migrations, as a running statement
+// can't be interrupted.
+func (x *Xormigrate) checkTransactionAge() error {
+	if !x.options.UseTransaction {
+		return nil
+	}
+	elapsed := time.Since(x.txStart)
+	if limit := x.options.AbortTransactionAfter; limit > 0 && elapsed > limit {
+		return &LongTransactionError{Elapsed: elapsed, Limit: limit}
+	}
+	if warn := x.options.WarnTransactionAfter; warn > 0 && elapsed > warn && !x.txWarned {
+		x.txWarned = true
+		x.emit(&LongTransaction{Elapsed: elapsed})
+	}
+	return nil
+}
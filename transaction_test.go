@@ -0,0 +1,46 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestLongTransaction(t *testing.T) {
+	slow := func(id string) *Migration {
+		return &Migration{
+			ID: id,
+			Migrate: func(tx *xorm.Session) error {
+				time.Sleep(5 * time.Millisecond)
+				return nil
+			},
+		}
+	}
+
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{
+		UseTransaction:       true,
+		WarnTransactionAfter: time.Millisecond,
+	}, []*Migration{slow("1"), slow("2")})
+	warnings := 0
+	m.AddListener(ListenerFunc(func(event Event) {
+		if _, ok := event.(*LongTransaction); ok {
+			warnings++
+		}
+	}))
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, 1, warnings)
+
+	backend = &FakeBackend{}
+	m = NewFake(backend, &Options{
+		UseTransaction:        true,
+		AbortTransactionAfter: time.Millisecond,
+	}, []*Migration{slow("1"), slow("2")})
+	var longErr *LongTransactionError
+	assert.True(t, errors.As(m.Migrate(), &longErr))
+	assert.Equal(t, time.Millisecond, longErr.Limit)
+	assert.Empty(t, backend.Applied())
+}
@@ -0,0 +1,58 @@
+package xormigrate
+
+import (
+	"errors"
+	"fmt"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// MySQLTriggersDisabledVar is the session variable set by WithoutTriggers on
+// MySQL, which can't disable triggers: the triggers to skip must check it,
+// e.g. "IF @xormigrate_triggers_disabled IS NULL THEN ... END IF".
+const MySQLTriggersDisabledVar = "@xormigrate_triggers_disabled"
+
+// ErrTriggersUnsupported is returned by WithoutTriggers on databases whose
+// triggers can't be disabled.
+var ErrTriggersUnsupported = errors.New("xormigrate: Disabling triggers is not supported by this database")
+
+// WithoutTriggers wraps fn, e.g. a bulk data migration, so that it runs with
+// the user triggers of table disabled. They are enabled again afterwards,
+// even if fn fails. See MySQLTriggersDisabledVar for MySQL, SQLite is not
+// supported.
+func WithoutTriggers(table string, fn MigrateFunc) MigrateFunc {
+	return func(tx *xorm.Session) (err error) {
+		disable, enable, err := triggerStatements(tx.Engine(), table)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(disable); err != nil {
+			return err
+		}
+		defer func() {
+			if _, enableErr := tx.Exec(enable); enableErr != nil && err == nil {
+				err = enableErr
+			}
+		}()
+		return fn(tx)
+	}
+}
+
+// triggerStatements returns the statements disabling and enabling the
+// triggers of table.
+func triggerStatements(engine *xorm.Engine, table string) (disable, enable string, err error) {
+	quoted := engine.Quote(table)
+	switch engine.Dialect().URI().DBType {
+	case schemas.POSTGRES:
+		return fmt.Sprintf("ALTER TABLE %s DISABLE TRIGGER USER", quoted),
+			fmt.Sprintf("ALTER TABLE %s ENABLE TRIGGER USER", quoted), nil
+	case schemas.MSSQL:
+		return fmt.Sprintf("DISABLE TRIGGER ALL ON %s", quoted),
+			fmt.Sprintf("ENABLE TRIGGER ALL ON %s", quoted), nil
+	case schemas.MYSQL:
+		return fmt.Sprintf("SET %s = 1", MySQLTriggersDisabledVar),
+			fmt.Sprintf("SET %s = NULL", MySQLTriggersDisabledVar), nil
+	}
+	return "", "", ErrTriggersUnsupported
+}
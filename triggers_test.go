@@ -0,0 +1,34 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+func TestWithoutTriggers(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		assert.NoError(t, db.Sync2(&Book{}))
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, []*Migration{{
+			ID: "201608301400",
+			Migrate: WithoutTriggers("book", func(tx *xorm.Session) error {
+				_, err := tx.Exec("INSERT INTO book (name) VALUES ('bulk')")
+				return err
+			}),
+		}})
+
+		err := m.Migrate()
+		if db.Dialect().URI().DBType == schemas.SQLITE {
+			assert.ErrorIs(t, err, ErrTriggersUnsupported)
+			return
+		}
+		assert.NoError(t, err)
+		count, err := db.Table("book").Count()
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, count)
+	})
+}
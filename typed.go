@@ -0,0 +1,37 @@
+//go:build go1.21
+// +build go1.21
+
+package xormigrate
+
+import (
+	"xorm.io/xorm"
+)
+
+// depsKey is the WithValue key of the dependencies of type T.
+type depsKey[T any] struct{}
+
+// SetDeps attaches the dependencies of type T, usually a struct of
+// application services, to the runs of x. Functions adapted with Typed
+// receive them.
+func SetDeps[T any](x *Xormigrate, deps T) {
+	x.WithValue(depsKey[T]{}, deps)
+}
+
+// Typed adapts a function receiving the dependencies of type T into a
+// function usable as Migrate, Rollback or InitSchema:
+//
+//	Migrate: xormigrate.Typed(func(tx *xorm.Session, deps *Deps) error {
+//		return deps.Crypto.ReencryptAll(tx)
+//	}),
+//
+// The adapted function fails with ErrMissingDeps if SetDeps wasn't called
+// with the same type T.
+func Typed[T any](fn func(*xorm.Session, T) error) func(*xorm.Session) error {
+	return func(tx *xorm.Session) error {
+		deps, ok := Value(tx, depsKey[T]{}).(T)
+		if !ok {
+			return ErrMissingDeps
+		}
+		return fn(tx, deps)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build go1.21
+// +build go1.21
+
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+type testDeps struct {
+	Names []string
+}
+
+func TestTyped(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		typedMigrations := []*Migration{
+			{
+				ID: "201608301400",
+				Migrate: Typed(func(tx *xorm.Session, deps *testDeps) error {
+					if err := tx.Sync2(&Person{}); err != nil {
+						return err
+					}
+					for _, name := range deps.Names {
+						if _, err := tx.Insert(&Person{Name: name}); err != nil {
+							return err
+						}
+					}
+					return nil
+				}),
+				Rollback: Typed(func(tx *xorm.Session, deps *testDeps) error {
+					return tx.DropTable(&Person{})
+				}),
+			},
+		}
+
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, typedMigrations)
+		assert.ErrorIs(t, m.Migrate(), ErrMissingDeps)
+
+		SetDeps(m, &testDeps{Names: []string{"Alice", "Bob"}})
+		assert.NoError(t, m.Migrate())
+		count, err := db.Count(&Person{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+		assert.NoError(t, m.RollbackLast())
+	})
+}
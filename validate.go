@@ -0,0 +1,71 @@
+package xormigrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MissingRollbackError is returned when migrations have no rollback while
+// Options.RequireRollback is set.
+type MissingRollbackError struct {
+	IDs []string
+}
+
+func (e *MissingRollbackError) Error() string {
+	return fmt.Sprintf("xormigrate: Migrations without rollback: %s", strings.Join(e.IDs, ", "))
+}
+
+// Validate checks the definition of the migrations as runs applying them do
+// before touching the database, e.g. in CI: reserved or duplicated IDs,
+// dependencies, steps, and the requirements of Options.Naming,
+// Options.StrictOrder, Options.ValidateID and Options.RequireRollback. The
+// database is not accessed.
+func (x *Xormigrate) Validate() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if !x.hasMigrations() {
+		return ErrNoMigrationDefined
+	}
+	return x.validate()
+}
+
+// validate checks the definition of the migrations, see Validate.
+func (x *Xormigrate) validate() error {
+	if err := x.checkReservedID(); err != nil {
+		return err
+	}
+	if err := x.checkDuplicatedID(); err != nil {
+		return err
+	}
+	if err := x.checkDependencies(); err != nil {
+		return err
+	}
+	if err := x.checkSteps(); err != nil {
+		return err
+	}
+	if err := x.checkNaming(); err != nil {
+		return err
+	}
+	if err := x.checkOrder(); err != nil {
+		return err
+	}
+	return x.checkRollbacks()
+}
+
+// checkRollbacks enforces Options.RequireRollback.
+func (x *Xormigrate) checkRollbacks() error {
+	if !x.options.RequireRollback {
+		return nil
+	}
+	var ids []string
+	for _, migration := range x.migrations {
+		if !migration.Repeatable && !migration.hasRollback() {
+			ids = append(ids, migration.ID)
+		}
+	}
+	if len(ids) > 0 {
+		return &MissingRollbackError{IDs: ids}
+	}
+	return nil
+}
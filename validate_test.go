@@ -0,0 +1,41 @@
+package xormigrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestRequireRollback(t *testing.T) {
+	migrations := targetMigrations()
+	migrations[1].Rollback = nil
+	migrations[2].Rollback, migrations[2].DownSQL = nil, "DROP TABLE pet;"
+	migrations = append(migrations, &Migration{
+		ID:         "R__views",
+		Repeatable: true,
+		UpSQL:      "CREATE VIEW adults AS SELECT * FROM person;",
+	})
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{RequireRollback: true}, migrations)
+
+	err := m.Validate()
+	var missingErr *MissingRollbackError
+	if assert.True(t, errors.As(err, &missingErr)) {
+		assert.Equal(t, []string{"201608301430"}, missingErr.IDs)
+	}
+	assert.EqualError(t, err, "xormigrate: Migrations without rollback: 201608301430")
+	assert.True(t, errors.As(m.Migrate(), &missingErr))
+	assert.Empty(t, backend.Ops())
+
+	migrations[1].Rollback = func(tx *xorm.Session) error { return nil }
+	assert.NoError(t, m.Validate())
+	assert.NoError(t, m.Migrate())
+}
+
+func TestValidate(t *testing.T) {
+	m := NewFake(&FakeBackend{}, &Options{}, append(targetMigrations(), &Migration{ID: "201608301400"}))
+	assert.Equal(t, &DuplicatedIDError{ID: "201608301400"}, m.Validate())
+	assert.Equal(t, ErrNoMigrationDefined, NewFake(&FakeBackend{}, &Options{}, nil).Validate())
+}
@@ -0,0 +1,43 @@
+package xormigrate
+
+import (
+	"sync"
+
+	"xorm.io/xorm"
+)
+
+// sessionRuns maps the sessions of ongoing runs to the Xormigrate running
+// them.
+var sessionRuns sync.Map
+
+// runOf returns the Xormigrate running on tx, or nil.
+func runOf(tx *xorm.Session) *Xormigrate {
+	x, ok := sessionRuns.Load(tx)
+	if !ok {
+		return nil
+	}
+	return x.(*Xormigrate)
+}
+
+// WithValue attaches a value to the runs of x. Migration, rollback and
+// schema initialization functions retrieve it with Value, which allows them
+// to use application services without resorting to package-level globals.
+func (x *Xormigrate) WithValue(key, value interface{}) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.values == nil {
+		x.values = make(map[interface{}]interface{})
+	}
+	x.values[key] = value
+}
+
+// Value returns the value attached to key with WithValue, for the run tx
+// belongs to. It returns nil if there's no such value.
+func Value(tx *xorm.Session, key interface{}) interface{} {
+	x := runOf(tx)
+	if x == nil {
+		return nil
+	}
+	return x.values[key]
+}
@@ -0,0 +1,32 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+type idGeneratorKey struct{}
+
+func TestValue(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		var got interface{}
+		m := New(db.NewSession(), &Options{
+			TableName: "migration",
+		}, []*Migration{
+			{
+				ID: "201608301400",
+				Migrate: func(tx *xorm.Session) error {
+					got = Value(tx, idGeneratorKey{})
+					return nil
+				},
+			},
+		})
+		m.WithValue(idGeneratorKey{}, "generator")
+
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, "generator", got)
+		assert.Nil(t, Value(db.NewSession(), idGeneratorKey{}))
+	})
+}
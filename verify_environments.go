@@ -0,0 +1,150 @@
+package xormigrate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"xorm.io/xorm"
+)
+
+// Environment is a database checked by a ConsistencyCheck. Name identifies it
+// in reports, so that DSNs and their credentials are never printed.
+type Environment struct {
+	Name string
+	DSN  string
+}
+
+// ConsistencyCheck verifies that several databases share the same migration
+// history, e.g. for a periodic infrastructure audit.
+type ConsistencyCheck struct {
+	// Driver is the database driver name, e.g. "postgres".
+	Driver string
+	// TableName is the name of the migration table, DefaultOptions.TableName
+	// if empty.
+	TableName string
+	// Environments are the databases to check. The first one is the
+	// reference the other ones are compared with.
+	Environments []Environment
+	// Scoped reports whether a migration only applies to some
+	// environments, it is then ignored. Can be nil.
+	Scoped func(id string) bool
+}
+
+// EnvironmentResult is the result of the comparison of an environment with
+// the reference environment. Err is set if the environment couldn't be read.
+type EnvironmentResult struct {
+	Name string
+	Diff *EnvironmentDiff
+	Err  error
+}
+
+// Passed reports whether the environment has the same history as the
+// reference environment.
+func (r *EnvironmentResult) Passed() bool {
+	return r.Err == nil && r.Diff.Equal()
+}
+
+// ConsistencyReport is the result of a ConsistencyCheck.
+type ConsistencyReport struct {
+	// Reference is the name of the reference environment.
+	Reference string
+	// Results are the results of the other environments, in order.
+	Results []EnvironmentResult
+}
+
+// Passed reports whether all the environments share the same history.
+func (r *ConsistencyReport) Passed() bool {
+	for i := range r.Results {
+		if !r.Results[i].Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Run connects to each environment and compares its migration table with the
+// one of the reference environment. An error is returned only if the
+// reference environment can't be read, the errors of the other environments
+// make them fail in the report.
+func (c *ConsistencyCheck) Run() (*ConsistencyReport, error) {
+	if len(c.Environments) == 0 {
+		return nil, fmt.Errorf("xormigrate: No environment to check")
+	}
+	reference, err := c.read(c.Environments[0])
+	if err != nil {
+		return nil, fmt.Errorf("xormigrate: Reading %s: %w", c.Environments[0].Name, err)
+	}
+	report := &ConsistencyReport{Reference: c.Environments[0].Name}
+	for _, env := range c.Environments[1:] {
+		result := EnvironmentResult{Name: env.Name}
+		records, err := c.read(env)
+		if err != nil {
+			result.Err = err
+		} else {
+			result.Diff = diffMigrationRecords(reference, records)
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// read returns the checksums of the migrations applied in the environment,
+// leaving out the scoped ones.
+func (c *ConsistencyCheck) read(env Environment) (map[string]string, error) {
+	engine, err := xorm.NewEngine(c.Driver, env.DSN)
+	if err != nil {
+		return nil, err
+	}
+	defer engine.Close()
+	tableName := c.TableName
+	if tableName == "" {
+		tableName = DefaultOptions.TableName
+	}
+	records, err := readMigrationRecords(engine, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if c.Scoped != nil {
+		for id := range records {
+			if c.Scoped(id) {
+				delete(records, id)
+			}
+		}
+	}
+	return records, nil
+}
+
+// WriteTo writes the report as plain text, one line per environment followed
+// by its differences, and a final PASS or FAIL line.
+func (r *ConsistencyReport) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "reference: %s\n", r.Reference)
+	for _, result := range r.Results {
+		switch {
+		case result.Err != nil:
+			fmt.Fprintf(&b, "FAIL %s: %v\n", result.Name, result.Err)
+			continue
+		case result.Passed():
+			fmt.Fprintf(&b, "ok   %s\n", result.Name)
+			continue
+		}
+		fmt.Fprintf(&b, "FAIL %s\n", result.Name)
+		for _, id := range result.Diff.OnlyInA {
+			fmt.Fprintf(&b, "     missing %s\n", id)
+		}
+		for _, id := range result.Diff.OnlyInB {
+			fmt.Fprintf(&b, "     extra %s\n", id)
+		}
+		for _, mismatch := range result.Diff.ChecksumMismatches {
+			fmt.Fprintf(&b, "     checksum %s: %s != %s\n", mismatch.ID, mismatch.A, mismatch.B)
+		}
+	}
+	if r.Passed() {
+		b.WriteString("PASS\n")
+	} else {
+		b.WriteString("FAIL\n")
+	}
+	n, err := w.Write(b.Bytes())
+	return int64(n), err
+}
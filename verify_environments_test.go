@@ -0,0 +1,66 @@
+package xormigrate
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestConsistencyCheck(t *testing.T) {
+	newScratchEngine(t).Close()
+
+	envs := make(map[string]Environment)
+	for _, env := range []struct {
+		name string
+		ids  []string
+	}{
+		{"staging", []string{"1", "2"}},
+		{"production", []string{"1", "2", "prod-only"}},
+		{"qa", []string{"1"}},
+	} {
+		dsn := "file:" + t.Name() + "-" + env.name + "?mode=memory&cache=shared"
+		engine, err := xorm.NewEngine("sqlite3", dsn)
+		if !assert.NoError(t, err) {
+			return
+		}
+		// Keeps the in-memory database alive during the test.
+		defer engine.Close()
+
+		var migrations []*Migration
+		for _, id := range env.ids {
+			migrations = append(migrations, &Migration{ID: id, Migrate: func(tx *xorm.Session) error { return nil }})
+		}
+		assert.NoError(t, New(engine.NewSession(), DefaultOptions, migrations).Migrate())
+		envs[env.name] = Environment{Name: env.name, DSN: dsn}
+	}
+
+	check := &ConsistencyCheck{
+		Driver:       "sqlite3",
+		Environments: []Environment{envs["staging"], envs["production"]},
+		Scoped: func(id string) bool {
+			return id == "prod-only"
+		},
+	}
+	report, err := check.Run()
+	if assert.NoError(t, err) {
+		assert.True(t, report.Passed())
+	}
+
+	check.Scoped = nil
+	check.Environments = append(check.Environments, envs["qa"])
+	report, err = check.Run()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, report.Passed())
+	var b bytes.Buffer
+	_, err = report.WriteTo(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, "reference: staging\nFAIL production\n     extra prod-only\nFAIL qa\n     missing 2\nFAIL\n", b.String())
+
+	check.TableName = "schema_history"
+	_, err = check.Run()
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package xormigrate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"xorm.io/xorm/schemas"
+)
+
+// ddlRegexp matches the statements that MySQL commits implicitly.
+var ddlRegexp = regexp.MustCompile(`(?i)^(CREATE|ALTER|DROP|RENAME|TRUNCATE)\s`)
+
+// warn emits a Warning about the migration with the given ID, if any.
+func (x *Xormigrate) warn(id, format string, v ...interface{}) {
+	x.emit(&Warning{ID: id, Message: fmt.Sprintf(format, v...)})
+}
+
+// lint warns about the issues of a migration about to be applied.
+func (x *Xormigrate) lint(m *Migration) {
+	if m.Description == "" {
+		x.warn(m.ID, "Migration %s has no Description", m.ID)
+	}
+	if !m.hasRollback() {
+		x.warn(m.ID, "Migration %s can't be rolled back", m.ID)
+	}
+	if x.options.UseTransaction && !m.NoTransaction && x.backend.dialect() == string(schemas.MYSQL) {
+		for _, statement := range splitStatements(m.upSQL(string(schemas.MYSQL))) {
+			statement = leadingCommentsRegexp.ReplaceAllString(statement, "")
+			if ddlRegexp.MatchString(statement) {
+				x.warn(m.ID, "Migration %s runs DDL in the run transaction, which MySQL commits implicitly: it won't be rolled back on failure", m.ID)
+				break
+			}
+		}
+	}
+}
+
+// backfillChecksums records the checksums of the applied migrations recorded
+// without one, e.g. before Options.RecordChecksum was set.
+func (x *Xormigrate) backfillChecksums() error {
+	if !x.options.RecordChecksum {
+		return nil
+	}
+	records, err := x.backend.listRecords()
+	if err != nil {
+		return err
+	}
+	missing := make(map[string]bool)
+	for _, record := range records {
+		if record.Checksum == "" {
+			missing[record.ID] = true
+		}
+	}
+	for _, m := range x.migrations {
+		checksum := m.checksum()
+		if !missing[m.ID] || checksum == "" || m.Repeatable {
+			continue
+		}
+		if err := x.backend.setChecksum(m.ID, checksum); err != nil {
+			return err
+		}
+		if len(checksum) > 12 {
+			checksum = checksum[:12]
+		}
+		x.warn(m.ID, "Checksum of migration %s computed for the first time, %s", m.ID, strings.ToLower(checksum))
+	}
+	return nil
+}
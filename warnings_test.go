@@ -0,0 +1,67 @@
+package xormigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestWarnings(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		logger := &recordingLogger{}
+		migrations := []*Migration{
+			{ID: "201608301400", Description: "Create books", UpSQL: "CREATE TABLE book (id INTEGER);", DownSQL: "DROP TABLE book;"},
+			{ID: "201608301430", UpSQL: "INSERT INTO book (id) VALUES (1);"},
+		}
+		m := New(db.NewSession(), &Options{TableName: "migration"}, migrations)
+		assert.NoError(t, m.MigrateTo("201608301400"))
+
+		// Checksums are recorded from now on.
+		m = New(db.NewSession(), &Options{TableName: "migration", RecordChecksum: true, Logger: logger}, migrations)
+		result, err := m.MigrateResult()
+		assert.NoError(t, err)
+		if assert.NotNil(t, result) {
+			assert.Equal(t, []string{
+				"Checksum of migration 201608301400 computed for the first time, " + m.migrations[0].checksum()[:12],
+				"Migration 201608301430 has no Description",
+				"Migration 201608301430 can't be rolled back",
+			}, result.Warnings)
+		}
+		assert.Equal(t, 3, logger.count("warn xormigrate: "))
+
+		var records []migrationRecord
+		assert.NoError(t, db.Table("migration").Asc("id").Find(&records))
+		if assert.Len(t, records, 2) {
+			assert.Equal(t, m.migrations[0].checksum(), records[0].Checksum)
+		}
+	})
+}
+
+func TestMySQLDDLWarning(t *testing.T) {
+	var warnings []string
+	m := NewFake(&FakeBackend{Dialect: "mysql"}, &Options{UseTransaction: true}, []*Migration{
+		{ID: "201608301400", Description: "Create books", UpSQL: "-- Books\nCREATE TABLE book (id INTEGER);", DownSQL: "DROP TABLE book;"},
+		{ID: "201608301430", Description: "Create authors", NoTransaction: true, UpSQL: "CREATE TABLE author (id INTEGER);", DownSQL: "DROP TABLE author;"},
+	})
+	m.AddListener(ListenerFunc(func(event Event) {
+		if e, ok := event.(*Warning); ok {
+			warnings = append(warnings, e.ID)
+		}
+	}))
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, []string{"201608301400"}, warnings)
+}
+
+func TestWarningsShortChecksum(t *testing.T) {
+	backend := &FakeBackend{}
+	migrations := targetMigrations()
+	assert.NoError(t, NewFake(backend, &Options{}, migrations[:1]).Migrate())
+
+	migrations[0].Checksum = "v2"
+	result, err := NewFake(backend, &Options{RecordChecksum: true}, migrations).MigrateResult()
+	assert.NoError(t, err)
+	if assert.NotNil(t, result) {
+		assert.Contains(t, result.Warnings, "Checksum of migration 201608301400 computed for the first time, v2")
+	}
+}
@@ -1,8 +1,15 @@
 package xormigrate
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"xorm.io/xorm"
 )
@@ -26,26 +33,407 @@ type Options struct {
 	// Keep in mind that not all databases support DDL commands inside transactions.
 	UseTransaction bool
 	// ValidateUnknownMigrations will cause migrate to fail if there's unknown migration
-	// IDs in the database
+	// IDs in the database, with an *UnknownMigrationError listing them
 	ValidateUnknownMigrations bool
+	// ReadOnly guarantees that no statement modifying the database is issued,
+	// not even the creation of the migration table. Migrations and rollbacks
+	// fail with ErrReadOnly, so only inspection methods remain usable.
+	ReadOnly bool
+	// AssumeTableExists skips checking for and creating the migration table.
+	// Use it when the table is provisioned beforehand and the database user
+	// lacks the CREATE privilege.
+	AssumeTableExists bool
+	// TableOptions customize how the migration table is created.
+	TableOptions TableOptions
+	// CreateTableSQL returns the script creating the migration table for
+	// the dialect, named after xorm's schemas.DBType, replacing the
+	// creation from TableOptions, e.g. to comply with the constraints,
+	// comments or auditing columns required on every table. The table
+	// needs at least a VARCHAR(50) "id" primary key column, the columns
+	// required by the other options being added afterwards.
+	CreateTableSQL func(dialect, tableName string) string
+	// RecordBuildVersion stores the version of the running binary, as
+	// returned by BuildVersion, with every applied migration. A
+	// "build_version" column is added to existing migration tables.
+	RecordBuildVersion bool
+	// RecordHost stores the identity of the instance running the migrations,
+	// as returned by HostResolver, with every applied migration. A "host"
+	// column is added to existing migration tables.
+	RecordHost bool
+	// HostResolver returns the identity of the running instance, e.g. a
+	// Kubernetes pod name. Defaults to os.Hostname.
+	HostResolver func() (string, error)
+	// RecordChecksum stores the checksum of every applied migration, see
+	// Migration.Checksum. A "checksum" column is added to existing
+	// migration tables.
+	RecordChecksum bool
+	// VerifyChecksums fails runs with a *ChecksumError, before applying
+	// anything, when applied migrations were modified, see Verify.
+	VerifyChecksums bool
+	// RecordRunID stores the ID of the run applying every migration, see
+	// RunID. A "run_id" column is added to existing migration tables.
+	RecordRunID bool
+	// RecordAppliedAt stores when every migration was applied, reported by
+	// Status. An "applied_at" column is added to existing migration
+	// tables.
+	RecordAppliedAt bool
+	// RecordDuration stores how long every migration took to apply, in
+	// milliseconds. A "duration_ms" column is added to existing migration
+	// tables.
+	RecordDuration bool
+	// Clock tells the time recorded with RecordAppliedAt and passed to
+	// Policy, and the one of the IDs returned by NewMigrationID. Defaults
+	// to the system clock.
+	Clock Clock
+	// IDGenerator generates the IDs of the runs, see RunID, and the ones
+	// returned by NewMigrationID. Defaults to random run IDs and
+	// timestamp migration IDs.
+	IDGenerator IDGenerator
+	// RecordStatus stores whether every migration was applied or skipped,
+	// e.g. because of its Dialects, and why, so that skipped migrations
+	// are visibly deliberate. "status" and "skip_reason" columns are added
+	// to existing migration tables.
+	RecordStatus bool
+	// RecordTouches stores the tables every migration touches, see
+	// Migration.Touches, so that MigrationsTouching still knows them once
+	// the migration is removed or squashed. A "touches" column is added to
+	// existing migration tables.
+	RecordTouches bool
+	// TableOwners are the teams owning the tables, by table name, used by
+	// PendingOwners to route the review of a release.
+	TableOwners map[string]string
+	// Rehearse makes runs applying migrations rehearse them first, see
+	// Rehearse, and apply them only if the rehearsal succeeded, failing
+	// with a *RehearsalError otherwise.
+	Rehearse bool
+	// Naming is the naming convention the migrations must follow, runs
+	// failing with a *NamingError otherwise. Can be nil.
+	Naming *NamingConvention
+	// SortByID sorts the migrations by ID, instead of running them in the
+	// order they are given, e.g. when they are collected from several
+	// places.
+	SortByID bool
+	// StrictOrder requires the IDs of the migrations, except repeatable
+	// ones, to be strictly increasing in the order they are given, runs
+	// failing with an *OrderError otherwise.
+	StrictOrder bool
+	// AllowOutOfOrder applies the pending migrations coming before an
+	// applied one, e.g. merged from a branch after a newer migration was
+	// applied, flagging them in the "out_of_order" column of the migration
+	// table, see MigrationStatus.OutOfOrder. Otherwise, runs fail with an
+	// *OutOfOrderError listing them, which includes the migrations left
+	// behind by runs of a selection of the migrations, e.g. TargetTag.
+	AllowOutOfOrder bool
+	// RequireRollback requires every migration, except repeatable ones, to
+	// have a rollback, runs failing with a *MissingRollbackError before
+	// anything is applied otherwise.
+	RequireRollback bool
+	// ValidateID, if set, validates the format of the ID of each migration,
+	// except repeatable ones, runs failing with an *InvalidIDError if it
+	// returns an error, see IDLayout.
+	ValidateID func(id string) error
+	// UseLock makes runs hold a database-level lock, so that instances
+	// started together, e.g. replicas of a deployment, don't apply the
+	// same migrations concurrently: an advisory lock on PostgreSQL and
+	// MySQL, a row of a "<TableName>_lock" table locked with SELECT ...
+	// FOR UPDATE on other databases. SQLite serializes writers itself and
+	// is not locked.
+	UseLock bool
+	// LockTimeout is how long a run waits for the lock held by another
+	// one before failing with a *LockTimeoutError. Zero waits
+	// indefinitely.
+	LockTimeout time.Duration
+	// Protection identifies the databases holding data that must not be
+	// lost, e.g. production, on which destructive operations are refused
+	// unless Force is set.
+	Protection Protection
+	// Force allows the operations refused on protected databases. Set it
+	// on a clone for a single deliberate operation, see WithOptions.
+	Force bool
+	// Policy is consulted before running or rolling back each migration,
+	// to enforce organization rules. Can be nil.
+	Policy PolicyFunc
+	// ApprovalVerifier validates the approval tokens of the migrations
+	// requiring approval. When set, the tokens are stored in an "approval"
+	// column, added to existing migration tables.
+	ApprovalVerifier ApprovalVerifier
+	// Secrets resolves the secrets referenced as {{ secret "name" }} in SQL
+	// migrations, which are then rendered as text/template templates, each
+	// secret as a string literal. SQL migrations are executed verbatim when
+	// nil.
+	Secrets SecretResolver
+	// Stats receives metrics about runs and migrations. Can be nil.
+	// It is registered as a listener, see NewStatsListener.
+	Stats StatsSink
+	// Logger receives messages about runs and migrations. Can be nil.
+	// It is registered as a listener, see NewLogListener.
+	Logger Logger
+	// EchoSQL logs every statement executed by the runs to Logger,
+	// regardless of the ShowSQL setting of the engine. The values of the
+	// secrets resolved by the run, see Secrets, are replaced by "***".
+	EchoSQL bool
+	// Quiet only logs the start and the end of runs, and errors. It is
+	// also set by the XORMIGRATE_QUIET environment variable.
+	Quiet bool
+	// PreRunSQL are statements executed on the session at the beginning
+	// of each run, e.g. "SET ROLE migrator". Without UseTransaction,
+	// statements may run on different pooled connections, which makes
+	// session settings unreliable.
+	PreRunSQL []string
+	// PostRunSQL are statements executed on the session at the end of
+	// each successful run, before committing it.
+	PostRunSQL []string
+	// RollbackOnFailure rolls back, in reverse order, the migrations
+	// applied by a run when one of them fails, to return the database to
+	// its state before the run. A *CompensationError reports what was
+	// rolled back. It has no effect with UseTransaction, where the run
+	// transaction is rolled back instead.
+	RollbackOnFailure bool
+	// WarnTransactionAfter emits a LongTransaction event once the run
+	// transaction has been open longer, when UseTransaction is set. Long
+	// transactions bloat PostgreSQL tables and grow the MySQL history
+	// list. Zero disables the warning.
+	WarnTransactionAfter time.Duration
+	// AbortTransactionAfter fails the run with a *LongTransactionError
+	// once the run transaction has been open longer, when UseTransaction
+	// is set. Zero disables the limit.
+	AbortTransactionAfter time.Duration
+	// LockPreflight checks that no long-running session holds locks on
+	// the tables of the migrations with its tag before running them. Can
+	// be nil.
+	LockPreflight *LockPreflight
+	// Gate checks that the migrations of other services listed by
+	// Migration.Requires are applied. Can be nil if no migration requires
+	// any.
+	Gate Gate
+	// GateWait is how long to wait for the migrations of other services
+	// before failing the run. Zero fails it immediately.
+	GateWait time.Duration
+	// GatePollInterval is the delay between checks while waiting. Defaults
+	// to five seconds.
+	GatePollInterval time.Duration
+	// Analyze refreshes the statistics of the tables touched by the SQL
+	// migrations of a run once it is committed, as query plans are poor
+	// after large backfills until they are.
+	Analyze bool
+	// Vacuum also reclaims the space of these tables, e.g. with VACUUM
+	// on PostgreSQL or OPTIMIZE TABLE on MySQL.
+	Vacuum bool
+	// Retry re-executes the migrations marked Idempotent when they fail
+	// with a transient error, e.g. a deadlock. Can be nil.
+	Retry *RetryPolicy
+	// History provides the durations of the migrations in previous runs,
+	// to report how long each migration and the rest of the run should
+	// take, see MigrationStarting. Can be nil.
+	History DurationHistory
+	// BeforeMigration is called before applying each migration. Can be
+	// nil.
+	BeforeMigration func(m *Migration)
+	// AfterMigration is called after applying each migration, with the
+	// error that failed it, if any, and its duration. Can be nil.
+	AfterMigration func(m *Migration, err error, d time.Duration)
+	// BeforeAll is called when a migration or rollback run starts. Can be
+	// nil.
+	BeforeAll func(rollback bool)
+	// AfterAll is called when a run ends, with the error that failed it,
+	// if any, and its duration. Can be nil.
+	AfterAll func(rollback bool, err error, d time.Duration)
+	// LogLevel is the minimum level of the logged messages. Defaults to
+	// the XORMIGRATE_LOG_LEVEL environment variable, or LogInfo. At
+	// LogDebug, statements are echoed as if EchoSQL was set.
+	LogLevel LogLevel
 }
 
 // Migration represents a database migration (a modification to be made on the database).
 type Migration struct {
 	// ID is the migration identifier. Usually a timestamp like "201601021504".
 	ID string `xorm:"VARCHAR(50) notnull pk 'id'"`
+	// Description tells what the migration does.
+	Description string `xorm:"-"`
+	// Tags are free-form labels, e.g. "expand" or "contract".
+	Tags []string `xorm:"-"`
+	// Dialects restricts the migration to the listed databases, named after
+	// xorm's schemas.DBType, e.g. "postgres" or "sqlite3". On other databases
+	// the migration is skipped but still recorded. Empty means all databases.
+	Dialects []string `xorm:"-"`
+	// NoTransaction runs the migration and its rollback outside of the
+	// transaction of Options.UseTransaction, e.g. for CREATE INDEX
+	// CONCURRENTLY on PostgreSQL. It runs on a new session of the engine.
+	NoTransaction bool `xorm:"-"`
+	// LongRunning runs the migration and its rollback in a transaction of
+	// their own, committed right away, instead of the transaction of
+	// Options.UseTransaction, e.g. for a large backfill: it doesn't keep
+	// the run transaction open, nor count against AbortTransactionAfter.
+	LongRunning bool `xorm:"-"`
+	// Repeatable migrations, e.g. creating views, stored procedures or
+	// reference data, are applied again whenever their checksum changes,
+	// see Checksum, after the other migrations of runs applying all of
+	// them. They are never rolled back, except by RollbackMigration, and
+	// their checksum is always recorded. Go ones must set Checksum.
+	Repeatable bool `xorm:"-"`
+	// DependsOn lists the IDs of migrations that must come before this one.
+	DependsOn []string `xorm:"-"`
+	// Idempotent declares that the migration can safely run again after
+	// it failed or was interrupted midway, e.g. because its DDL uses
+	// CreateTableIfNotExists and the like. Such migrations are retried on
+	// transient errors according to Options.Retry.
+	Idempotent bool `xorm:"-"`
+	// RequiresApproval prevents the migration from running unless an
+	// approval token was provided with Approve and accepted by
+	// Options.ApprovalVerifier.
+	RequiresApproval bool `xorm:"-"`
+	// Settings are session settings applied while the migration runs or
+	// is rolled back, and reverted afterwards, see DisableForeignKeyChecks
+	// and LockTimeout. Without Options.UseTransaction, statements may run
+	// on different pooled connections, which makes them unreliable.
+	Settings []Setting `xorm:"-"`
+	// Tables are the tables the migration alters, checked by
+	// Options.LockPreflight. They are inferred from UpSQL if empty.
+	Tables []string `xorm:"-"`
+	// Touches are the tables the migration creates, alters or writes,
+	// reported by MigrationsTouching and PendingOwners. Defaults to Tables,
+	// or to the tables inferred from UpSQL. Go migrations should declare
+	// them.
+	Touches []string `xorm:"-"`
+	// Requires are the migrations of other services that must be applied
+	// before this one runs, checked with Options.Gate, e.g. to roll out a
+	// schema change across services in order.
+	Requires []ServiceMigration `xorm:"-"`
+	// AnalyzeTables are tables whose statistics are refreshed once the run
+	// applying the migration is committed, see RefreshStatistics.
+	AnalyzeTables []string `xorm:"-"`
+	// Group is the logical group of the migration, e.g. a feature, whose
+	// migrations can be rolled back together with RollbackGroup.
+	Group string `xorm:"-"`
+	// Priority reorders the migrations of a run: those with a higher
+	// priority run first, e.g. to create extensions and roles before
+	// anything else, the other ones keeping their order. A migration
+	// still never runs before the migrations it depends on.
+	Priority int `xorm:"-"`
+	// Budget is the expected maximum duration of the migration. When it
+	// is exceeded, a BudgetExceeded event is emitted and the migration is
+	// flagged in the "over_budget" column of the migration table.
+	Budget time.Duration `xorm:"-"`
+	// Checksum identifies the content of the migration. If empty, it is the
+	// SHA-256 of UpSQL, Go migrations having no checksum otherwise.
+	Checksum string `xorm:"-"`
+	// DestructiveRollback declares that the rollback destroys data, e.g. by
+	// dropping a column, which Options.Protection guards against. Rollbacks
+	// whose DownSQL drops objects are considered destructive anyway.
+	DestructiveRollback bool `xorm:"-"`
+	// RollbackOnly declares a migration adopting objects created outside
+	// of xormigrate, e.g. by an older tool: applying it only records it,
+	// Migrate and UpSQL are ignored, so that their teardown goes through
+	// Rollback or DownSQL like the other migrations.
+	RollbackOnly bool `xorm:"-"`
+	// Steps are run in order instead of Migrate or UpSQL. Each completed
+	// step is recorded in a "<TableName>_steps" table, so that a migration
+	// interrupted midway resumes at the step that failed. Without
+	// Options.UseTransaction, steps should make their own changes atomic.
+	Steps []Step `xorm:"-"`
 	// Migrate is a function that will br executed while running this migration.
 	Migrate MigrateFunc `xorm:"-"`
 	// Rollback will be executed on rollback. Can be nil.
 	Rollback RollbackFunc `xorm:"-"`
+	// MigrateContext is executed instead of Migrate when it is nil, with
+	// the context of the run, see MigrateContext.
+	MigrateContext MigrateFuncCtx `xorm:"-"`
+	// RollbackContext is executed instead of Rollback when it is nil,
+	// with the context of the run.
+	RollbackContext RollbackFuncCtx `xorm:"-"`
+	// UpSQL is a SQL script executed instead of Migrate when it is nil.
+	UpSQL string `xorm:"-"`
+	// DownSQL is a SQL script executed instead of Rollback when it is nil.
+	DownSQL string `xorm:"-"`
+	// UpSQLByDialect are variants of UpSQL for some databases, named after
+	// xorm's schemas.DBType, e.g. "postgres". UpSQL is executed on the
+	// other ones. Without UpSQL, nor Dialects, the migration is skipped
+	// on the databases without a variant.
+	UpSQLByDialect map[string]string `xorm:"-"`
+	// DownSQLByDialect are variants of DownSQL for some databases.
+	DownSQLByDialect map[string]string `xorm:"-"`
+	// Params are values the SQL scripts of the migration reference as
+	// {{ param "name" }}, e.g. a batch size tuned per environment with
+	// OverlaySource.
+	Params map[string]string `xorm:"-"`
+	// Skip, if set, is the reason the migration is recorded as skipped
+	// instead of being applied, e.g. on an environment it does not apply
+	// to. Skipped migrations are not rolled back either.
+	Skip string `xorm:"-"`
+	// Overrides lists the changes made to the migration by OverlaySource,
+	// e.g. `fork.yaml: up`, so that plans show them.
+	Overrides []string `xorm:"-"`
+
+	// files are the names of the files of a SQL migration loaded with
+	// LoadFS, checked by NamingConvention.
+	files []string
 }
 
 // Xormigrate represents a collection of all migrations of a database schema.
+//
+// It is safe for concurrent use: as all operations share a single session,
+// they are serialized. Migration functions and listeners must not call the
+// methods of the Xormigrate running them.
 type Xormigrate struct {
-	session    *xorm.Session
+	mu      sync.Mutex
+	session *xorm.Session
+	// engine, if set, provides the session of each operation, see
+	// openSession.
+	engine     *xorm.Engine
+	backend    backend
 	options    *Options
 	migrations []*Migration
 	initSchema InitSchemaFunc
+	// initSchemaAll records all the migrations as applied along with the
+	// schema initialization, see InitSchemaFromSQL.
+	initSchemaAll bool
+	listeners     []Listener
+	values        map[interface{}]interface{}
+	approvals     map[string]string
+	// runID identifies the current run, see RunID, and result collects
+	// its events. ctx is the context of the current call, if any.
+	runID  string
+	result *RunResult
+	ctx    context.Context
+	// applied are the migrations applied by the current run.
+	applied []*Migration
+	// ran caches the IDs of the records, loaded at once by migrationRan
+	// and dropped with the session, see forgetRan.
+	ran map[string]bool
+	// outOfOrder are the IDs of the migrations applied out of order by
+	// the current run, see Options.AllowOutOfOrder.
+	outOfOrder map[string]bool
+	// txStart is when the run transaction started, txWarned whether the
+	// LongTransaction event was emitted for it.
+	txStart  time.Time
+	txWarned bool
+	// detached is set while running outside of the run transaction, see
+	// withoutTransaction.
+	detached bool
+	// rehearsing is set while rehearsing a run, whose transaction is
+	// never committed, see Rehearse.
+	rehearsing bool
+	// release releases the migration lock held by the current run, and
+	// forceLock makes the run take it even without Options.UseLock, see
+	// RunOnce.
+	release   func() error
+	forceLock bool
+	// executed counts the statements of the SQL migrations executed by
+	// the failed attempts resumed by the current run, see
+	// RetryPolicy.ResumeSQL.
+	executed map[string]int
+	// secrets are the values of the secrets resolved by the current run,
+	// as is and as literals, see redact.
+	secrets []string
+	// optionListeners is the number of listeners, first in listeners,
+	// registered from the options, see setOptions.
+	optionListeners int
+	// readyMu guards ready and readyErr, the outcome of the last RunOnce,
+	// see Ready. It is not mu, so that readiness checks don't wait for
+	// the run.
+	readyMu  sync.Mutex
+	ready    bool
+	readyErr error
 }
 
 // ReservedIDError is returned when a migration is using a reserved ID
@@ -57,6 +445,17 @@ func (e *ReservedIDError) Error() string {
 	return fmt.Sprintf(`xormigrate: Reserved migration ID: "%s"`, e.ID)
 }
 
+// DependencyError is returned when a migration depends on a migration that
+// is not defined before it
+type DependencyError struct {
+	ID        string
+	DependsOn string
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf(`xormigrate: Migration "%s" depends on "%s", which is not defined before it`, e.ID, e.DependsOn)
+}
+
 // DuplicatedIDError is returned when more than one migration have the same ID
 type DuplicatedIDError struct {
 	ID string
@@ -66,12 +465,81 @@ func (e *DuplicatedIDError) Error() string {
 	return fmt.Sprintf(`xormigrate: Duplicated migration ID: "%s"`, e.ID)
 }
 
+// MigrationPhase is the step of a migration which failed, see MigrationError.
+type MigrationPhase string
+
+// The phases of a migration.
+const (
+	// PhaseMigrate is the execution of Migrate, UpSQL or Steps.
+	PhaseMigrate MigrationPhase = "migrate"
+	// PhaseRollback is the execution of Rollback or DownSQL.
+	PhaseRollback MigrationPhase = "rollback"
+	// PhaseRecord is the bookkeeping of the migration table once the
+	// migration was applied or rolled back.
+	PhaseRecord MigrationPhase = "record"
+)
+
+// MigrationError is returned when a migration fails to be applied or rolled
+// back. It wraps the error of the Phase which failed.
+type MigrationError struct {
+	ID          string
+	Description string
+	Phase       MigrationPhase
+	Err         error
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("xormigrate: Migration %s%s failed during %s: %v", e.ID, described(e.Description), e.Phase, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// migrationError returns err as a *MigrationError of the phase of m.
+func migrationError(m *Migration, phase MigrationPhase, err error) error {
+	return &MigrationError{ID: m.ID, Description: m.Description, Phase: phase, Err: err}
+}
+
+// UnknownMigrationError is returned when Options.ValidateUnknownMigrations is
+// set and the migration table has records of migrations which are not
+// defined, listed in IDs. It matches ErrUnknownPastMigration with errors.Is.
+type UnknownMigrationError struct {
+	IDs []string
+}
+
+func (e *UnknownMigrationError) Error() string {
+	return fmt.Sprintf("xormigrate: Found migrations in DB that do not exist in code: %s", strings.Join(e.IDs, ", "))
+}
+
+func (e *UnknownMigrationError) Is(target error) bool {
+	return target == ErrUnknownPastMigration
+}
+
+// MissingTableError is returned when Options.AssumeTableExists is set but
+// the migration table can't be queried
+type MissingTableError struct {
+	TableName string
+	Err       error
+}
+
+func (e *MissingTableError) Error() string {
+	return fmt.Sprintf(`xormigrate: Migration table "%s" is missing or not accessible (AssumeTableExists is set): %v`, e.TableName, e.Err)
+}
+
+func (e *MissingTableError) Unwrap() error {
+	return e.Err
+}
+
 var (
 	// DefaultOptions can be used if you don't want to think about options.
 	DefaultOptions = &Options{
 		TableName:                 "migrations",
 		UseTransaction:            false,
 		ValidateUnknownMigrations: false,
+		HostResolver:              os.Hostname,
+		Clock:                     systemClock{},
+		IDGenerator:               defaultIDGenerator{},
 	}
 
 	// ErrRollbackImpossible is returned when trying to rollback a migration
@@ -94,17 +562,109 @@ var (
 
 	// ErrUnknownPastMigration is returned if a migration exists in the DB that doesn't exist in the code
 	ErrUnknownPastMigration = errors.New("xormigrate: Found migration in DB that does not exist in code")
+
+	// ErrReadOnly is returned when trying to migrate or rollback while
+	// Options.ReadOnly is set
+	ErrReadOnly = errors.New("xormigrate: Can't modify the database in read-only mode")
+
+	// ErrNotInitialized is returned by inspection methods when the migration
+	// table does not exist yet
+	ErrNotInitialized = errors.New("xormigrate: Migration table does not exist")
+
+	// ErrNoEngine is returned by the methods generating SQL, such as Script,
+	// when running on a backend without a database engine, e.g. a
+	// FakeBackend
+	ErrNoEngine = errors.New("xormigrate: No database engine")
+
+	// ErrInvalidSignature is returned when signed migration content doesn't
+	// match its signature
+	ErrInvalidSignature = errors.New("xormigrate: Invalid migration signature")
+
+	// ErrEmptySigningKey is returned by HTTPSource when its SigningKey is
+	// set but empty, e.g. read from an unset environment variable, which
+	// would let anyone sign the manifest
+	ErrEmptySigningKey = errors.New("xormigrate: Empty signing key")
+
+	// ErrApprovalRequired is returned, wrapped in an *ApprovalError, when a
+	// migration requiring approval has no approval token or no verifier
+	ErrApprovalRequired = errors.New("xormigrate: Approval token required")
+
+	// ErrNoSecretResolver is returned when resolving a secret in a run
+	// without Options.Secrets
+	ErrNoSecretResolver = errors.New("xormigrate: No secret resolver")
+
+	// ErrUnknownGroup is returned by RollbackGroup when no migration
+	// belongs to the group
+	ErrUnknownGroup = errors.New("xormigrate: No migration in this group")
+
+	// ErrMissingDeps is returned by functions adapted with Typed when no
+	// dependencies of the expected type were attached with SetDeps
+	ErrMissingDeps = errors.New("xormigrate: Missing dependencies for typed migration")
 )
 
-// New returns a new Xormigrate.
+// New returns a new Xormigrate running on session, which remains owned by the
+// caller: it is neither closed nor otherwise managed. See NewFromEngine.
 func New(session *xorm.Session, options *Options, migrations []*Migration) *Xormigrate {
+	return newXormigrate(session, nil, options, migrations)
+}
+
+// NewFromEngine returns a new Xormigrate opening a session of engine for each
+// operation, closed when it is done. Unlike with New, there is no session for
+// the caller to manage.
+func NewFromEngine(engine *xorm.Engine, options *Options, migrations []*Migration) *Xormigrate {
+	return newXormigrate(nil, engine, options, migrations)
+}
+
+func newXormigrate(session *xorm.Session, engine *xorm.Engine, options *Options, migrations []*Migration) *Xormigrate {
+	x := &Xormigrate{
+		session:    session,
+		engine:     engine,
+		migrations: migrations,
+	}
+	x.backend = &sessionBackend{x}
+	x.setOptions(options)
+	return x
+}
+
+// setOptions sets the options, with their defaults, sorts the migrations if
+// Options.SortByID is set, and registers the listeners of Options.Stats and
+// Options.Logger in place of the ones of the previous options.
+func (x *Xormigrate) setOptions(options *Options) {
+	setDefaults(options)
+	if options.SortByID {
+		x.migrations = sortByID(x.migrations)
+	}
+	x.options = options
+	added := x.listeners[x.optionListeners:]
+	x.listeners = nil
+	if options.Stats != nil {
+		x.listeners = append(x.listeners, NewStatsListener(options.Stats))
+	}
+	if options.Logger != nil {
+		x.listeners = append(x.listeners, NewLogListener(x.logger(), options.Quiet))
+	}
+	x.optionListeners = len(x.listeners)
+	x.listeners = append(x.listeners, added...)
+}
+
+func setDefaults(options *Options) {
 	if options.TableName == "" {
 		options.TableName = DefaultOptions.TableName
 	}
-	return &Xormigrate{
-		session:    session,
-		options:    options,
-		migrations: migrations,
+	if options.HostResolver == nil {
+		options.HostResolver = DefaultOptions.HostResolver
+	}
+	if options.Clock == nil {
+		options.Clock = DefaultOptions.Clock
+	}
+	if options.IDGenerator == nil {
+		options.IDGenerator = DefaultOptions.IDGenerator
+	}
+	if options.LogLevel == 0 {
+		options.LogLevel = logLevelFromEnv()
+	}
+	if quiet, _ := strconv.ParseBool(os.Getenv("XORMIGRATE_QUIET")); quiet {
+		options.Quiet = true
 	}
 }
 
@@ -113,11 +673,24 @@ func New(session *xorm.Session, options *Options, migrations []*Migration) *Xorm
 // is being migratinx. In this function you should create all tables and
 // foreign key necessary to your application.
 func (x *Xormigrate) InitSchema(initSchema InitSchemaFunc) {
-	x.initSchema = initSchema
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.initSchema, x.initSchemaAll = initSchema, false
 }
 
 // Migrate executes all migrations that did not run yet.
 func (x *Xormigrate) Migrate() error {
+	_, err := x.MigrateResult()
+	return err
+}
+
+// MigrateResult is like Migrate, also returning the result of the run.
+func (x *Xormigrate) MigrateResult() (*RunResult, error) {
+	return x.withResult(nil, x.migrateAll)
+}
+
+func (x *Xormigrate) migrateAll() error {
 	if !x.hasMigrations() {
 		return ErrNoMigrationDefined
 	}
@@ -126,41 +699,74 @@ func (x *Xormigrate) Migrate() error {
 	if l > 0 {
 		targetMigrationID = x.migrations[l-1].ID
 	}
-	return x.migrate(targetMigrationID)
+	return x.migrate(targetMigrationID, nil)
 }
 
 // MigrateTo executes all migrations that did not run yet up to the migration that matches `migrationID`.
+// The migrations after it are left applied, see Goto to roll them back.
 func (x *Xormigrate) MigrateTo(migrationID string) error {
+	_, err := x.MigrateToResult(migrationID)
+	return err
+}
+
+// MigrateToResult is like MigrateTo, also returning the result of the run.
+func (x *Xormigrate) MigrateToResult(migrationID string) (*RunResult, error) {
+	return x.withResult(nil, func() error {
+		return x.migrateTo(migrationID)
+	})
+}
+
+func (x *Xormigrate) migrateTo(migrationID string) error {
 	if err := x.checkIDExist(migrationID); err != nil {
 		return err
 	}
-	return x.migrate(migrationID)
+	return x.migrate(migrationID, nil)
 }
 
-func (x *Xormigrate) migrate(migrationID string) error {
+// migrate applies the migrations up to migrationID. selectPlan, if not nil,
+// selects the migrations of the run among them, once the migration table
+// exists.
+func (x *Xormigrate) migrate(migrationID string, selectPlan func(plan []*Migration) ([]*Migration, error)) (err error) {
 	if !x.hasMigrations() {
 		return ErrNoMigrationDefined
 	}
-	if err := x.checkReservedID(); err != nil {
+	if err := x.checkWritable(); err != nil {
 		return err
 	}
-	if err := x.checkDuplicatedID(); err != nil {
+	if err := x.validate(); err != nil {
 		return err
 	}
+	if x.options.Rehearse && !x.rehearsing {
+		if err := x.rehearse(migrationID, selectPlan); err != nil {
+			return &RehearsalError{Err: err}
+		}
+	}
+	x.emitRunStarted(false)
+	defer x.emitRunFinished(false, time.Now(), &err)
 
-	x.begin()
-	defer x.rollback()
+	defer x.end()
+	if err := x.begin(); err != nil {
+		return err
+	}
 
 	if err := x.createMigrationTableIfNotExists(); err != nil {
 		return err
 	}
+	if err := x.backfillChecksums(); err != nil {
+		return err
+	}
+	if x.options.VerifyChecksums {
+		if err := x.verifyChecksums(); err != nil {
+			return err
+		}
+	}
 	if x.options.ValidateUnknownMigrations {
-		unknownMigrations, err := x.unknownMigrationsHaveHappened()
+		unknown, err := x.unknownMigrations()
 		if err != nil {
 			return err
 		}
-		if unknownMigrations {
-			return ErrUnknownPastMigration
+		if len(unknown) > 0 {
+			return &UnknownMigrationError{IDs: unknown}
 		}
 	}
 	if x.initSchema != nil {
@@ -169,21 +775,108 @@ func (x *Xormigrate) migrate(migrationID string) error {
 			return err
 		}
 		if canInitializeSchema {
-			if err := x.runInitSchema(); err != nil {
+			if err := x.initSchemaRacing(); err != nil {
 				return err
 			}
-			return x.commit()
+			return x.finish()
 		}
 	}
-	for _, migration := range x.migrations {
+	plan, err := x.runOrder(migrationID)
+	if err != nil {
+		return err
+	}
+	if selectPlan != nil {
+		if plan, err = selectPlan(plan); err != nil {
+			return err
+		}
+	}
+	pending, err := x.pendingIn(plan)
+	if err != nil {
+		return err
+	}
+	if err := x.checkOutOfOrder(pending); err != nil {
+		return err
+	}
+	x.applied = nil
+	next := 0
+	for _, migration := range plan {
+		if err := x.context().Err(); err != nil {
+			return err
+		}
+		if next < len(pending) && pending[next] == migration {
+			x.emitStarting(pending, next)
+			next++
+		}
 		if err := x.runMigration(migration); err != nil {
+			return x.compensate(err)
+		}
+		if err := x.checkTransactionAge(); err != nil {
+			return err
+		}
+	}
+	if selectPlan == nil && (migrationID == "" || migrationID == x.migrations[len(x.migrations)-1].ID) {
+		if err := x.runRepeatables(); err != nil {
 			return err
 		}
+	}
+	if err := x.finish(); err != nil {
+		return err
+	}
+	x.maintain(x.applied)
+	return nil
+}
+
+// runOrder returns the migrations up to migrationID, or all of them if it is
+// empty, except the repeatable ones, sorted by decreasing priority while running dependencies first.
+// It fails with a *DependencyError if dependencies can't be satisfied, which
+// checkDependencies rules out beforehand.
+func (x *Xormigrate) runOrder(migrationID string) ([]*Migration, error) {
+	var candidates []*Migration
+	for _, migration := range x.migrations {
+		if !migration.Repeatable {
+			candidates = append(candidates, migration)
+		}
 		if migrationID != "" && migration.ID == migrationID {
 			break
 		}
 	}
-	return x.commit()
+
+	ordered := make([]*Migration, 0, len(candidates))
+	scheduled := make(map[string]bool, len(candidates))
+	inRun := make(map[string]bool, len(candidates))
+	for _, migration := range candidates {
+		inRun[migration.ID] = true
+	}
+	for len(ordered) < len(candidates) {
+		var next *Migration
+		for _, migration := range candidates {
+			if scheduled[migration.ID] || (next != nil && migration.Priority <= next.Priority) {
+				continue
+			}
+			ready := true
+			for _, id := range migration.DependsOn {
+				if inRun[id] && !scheduled[id] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = migration
+			}
+		}
+		if next == nil {
+			for _, migration := range candidates {
+				for _, id := range migration.DependsOn {
+					if !scheduled[migration.ID] && inRun[id] && !scheduled[id] {
+						return nil, &DependencyError{ID: migration.ID, DependsOn: id}
+					}
+				}
+			}
+		}
+		scheduled[next.ID] = true
+		ordered = append(ordered, next)
+	}
+	return ordered, nil
 }
 
 // There are migrations to apply if either there's a defined
@@ -214,6 +907,26 @@ func (x *Xormigrate) checkDuplicatedID() error {
 	return nil
 }
 
+func (x *Xormigrate) checkDependencies() error {
+	defined := make(map[string]struct{}, len(x.migrations))
+	for _, m := range x.migrations {
+		for _, id := range m.DependsOn {
+			if _, ok := defined[id]; !ok {
+				return &DependencyError{ID: m.ID, DependsOn: id}
+			}
+		}
+		defined[m.ID] = struct{}{}
+	}
+	return nil
+}
+
+func (x *Xormigrate) checkWritable() error {
+	if x.options.ReadOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
 func (x *Xormigrate) checkIDExist(migrationID string) error {
 	for _, migrate := range x.migrations {
 		if migrate.ID == migrationID {
@@ -225,12 +938,29 @@ func (x *Xormigrate) checkIDExist(migrationID string) error {
 
 // RollbackLast undo the last migration
 func (x *Xormigrate) RollbackLast() error {
+	_, err := x.RollbackLastResult()
+	return err
+}
+
+// RollbackLastResult is like RollbackLast, also returning the result of the run.
+func (x *Xormigrate) RollbackLastResult() (*RunResult, error) {
+	return x.withResult(nil, x.rollbackLast)
+}
+
+func (x *Xormigrate) rollbackLast() (err error) {
 	if len(x.migrations) == 0 {
 		return ErrNoMigrationDefined
 	}
+	if err := x.checkWritable(); err != nil {
+		return err
+	}
+	x.emitRunStarted(true)
+	defer x.emitRunFinished(true, time.Now(), &err)
 
-	x.begin()
-	defer x.rollback()
+	defer x.end()
+	if err := x.begin(); err != nil {
+		return err
+	}
 
 	lastRunMigration, err := x.getLastRunMigration()
 	if err != nil {
@@ -239,43 +969,77 @@ func (x *Xormigrate) RollbackLast() error {
 	if err := x.rollbackMigration(lastRunMigration); err != nil {
 		return err
 	}
-	return x.commit()
+	return x.finish()
 }
 
 // RollbackTo undoes migrations up to the given migration that matches the `migrationID`.
 // Migration with the matching `migrationID` is not rolled back.
 func (x *Xormigrate) RollbackTo(migrationID string) error {
+	_, err := x.RollbackToResult(migrationID)
+	return err
+}
+
+// RollbackToResult is like RollbackTo, also returning the result of the run.
+func (x *Xormigrate) RollbackToResult(migrationID string) (*RunResult, error) {
+	return x.withResult(nil, func() error {
+		return x.rollbackTo(migrationID)
+	})
+}
+
+func (x *Xormigrate) rollbackTo(migrationID string) (err error) {
 	if len(x.migrations) == 0 {
 		return ErrNoMigrationDefined
 	}
 	if err := x.checkIDExist(migrationID); err != nil {
 		return err
 	}
+	if err := x.checkWritable(); err != nil {
+		return err
+	}
+	if err := x.checkProtection("RollbackTo"); err != nil {
+		return err
+	}
+	x.emitRunStarted(true)
+	defer x.emitRunFinished(true, time.Now(), &err)
 
-	x.begin()
-	defer x.rollback()
+	defer x.end()
+	if err := x.begin(); err != nil {
+		return err
+	}
 
 	for i := len(x.migrations) - 1; i >= 0; i-- {
 		migration := x.migrations[i]
 		if migration.ID == migrationID {
 			break
 		}
+		if migration.Repeatable {
+			continue
+		}
 		migrationRan, err := x.migrationRan(migration)
 		if err != nil {
 			return err
 		}
 		if migrationRan {
+			if err := x.context().Err(); err != nil {
+				return err
+			}
 			if err := x.rollbackMigration(migration); err != nil {
 				return err
 			}
+			if err := x.checkTransactionAge(); err != nil {
+				return err
+			}
 		}
 	}
-	return x.commit()
+	return x.finish()
 }
 
 func (x *Xormigrate) getLastRunMigration() (*Migration, error) {
 	for i := len(x.migrations) - 1; i >= 0; i-- {
 		migration := x.migrations[i]
+		if migration.Repeatable {
+			continue
+		}
 		migrationRan, err := x.migrationRan(migration)
 		if err != nil {
 			return nil, err
@@ -289,25 +1053,81 @@ func (x *Xormigrate) getLastRunMigration() (*Migration, error) {
 
 // RollbackMigration undo a migration.
 func (x *Xormigrate) RollbackMigration(m *Migration) error {
-	x.begin()
-	defer x.rollback()
+	_, err := x.RollbackMigrationResult(m)
+	return err
+}
+
+// RollbackMigrationResult is like RollbackMigration, also returning the result of the run.
+func (x *Xormigrate) RollbackMigrationResult(m *Migration) (*RunResult, error) {
+	return x.withResult(nil, func() error {
+		return x.rollbackOne(m)
+	})
+}
+
+func (x *Xormigrate) rollbackOne(m *Migration) (err error) {
+	if err := x.checkWritable(); err != nil {
+		return err
+	}
+	x.emitRunStarted(true)
+	defer x.emitRunFinished(true, time.Now(), &err)
+
+	defer x.end()
+	if err := x.begin(); err != nil {
+		return err
+	}
 
 	if err := x.rollbackMigration(m); err != nil {
 		return err
 	}
-	return x.commit()
+	return x.finish()
 }
 
 func (x *Xormigrate) rollbackMigration(m *Migration) error {
-	if m.Rollback == nil {
+	if x.skipReason(m) != "" {
+		// The migration was skipped, only its record has to be removed.
+		return x.deleteMigration(m)
+	}
+	if !m.hasRollback() {
 		return ErrRollbackImpossible
 	}
-	if err := m.Rollback(x.session); err != nil {
+	if err := x.checkRollbackSafety(m); err != nil {
+		return err
+	}
+	if err := x.checkPolicy(m, true); err != nil {
 		return err
 	}
-	if _, err := x.session.Table(x.options.TableName).ID(m.ID).Delete(&Migration{}); err != nil {
+	if m.NoTransaction {
+		return x.withoutTransaction(func() error {
+			return x.revertMigration(m)
+		})
+	}
+	if m.LongRunning {
+		return x.inOwnTransaction(func() error {
+			return x.revertMigration(m)
+		})
+	}
+	return x.revertMigration(m)
+}
+
+func (x *Xormigrate) revertMigration(m *Migration) error {
+	err := x.withSettings(m, func() error {
+		return x.runRollback(m)
+	})
+	if err != nil {
+		return migrationError(m, PhaseRollback, err)
+	}
+	if err := x.deleteMigration(m); err != nil {
+		return migrationError(m, PhaseRecord, err)
+	}
+	return nil
+}
+
+func (x *Xormigrate) deleteMigration(m *Migration) error {
+	if err := x.backend.deleteRecord(m.ID); err != nil {
 		return err
 	}
+	delete(x.ran, m.ID)
+	x.emit(&RolledBack{ID: m.ID})
 	return nil
 }
 
@@ -315,10 +1135,11 @@ func (x *Xormigrate) runInitSchema() error {
 	if err := x.initSchema(x.session); err != nil {
 		return err
 	}
-	if err := x.insertMigration(initSchemaMigrationID); err != nil {
-		return err
+	migrations := []*Migration{{ID: initSchemaMigrationID}}
+	if x.initSchemaAll {
+		migrations = append(migrations, x.migrations...)
 	}
-	return nil
+	return x.insertMigrations(migrations, "")
 }
 
 func (x *Xormigrate) runMigration(migration *Migration) error {
@@ -329,35 +1150,134 @@ func (x *Xormigrate) runMigration(migration *Migration) error {
 	if err != nil {
 		return err
 	}
-	if !migrationRan {
-		if err := migration.Migrate(x.session); err != nil {
-			return err
-		}
+	if migrationRan {
+		return nil
+	}
+	if reason := x.skipReason(migration); reason != "" {
+		return x.skipMigration(migration, reason)
+	}
+	if err := x.checkPolicy(migration, false); err != nil {
+		return err
+	}
+	if err := x.checkApproval(migration); err != nil {
+		return err
+	}
+	if err := x.checkGates(migration); err != nil {
+		return err
+	}
+	if err := x.checkLocks(migration); err != nil {
+		return err
+	}
+	if migration.NoTransaction {
+		return x.withoutTransaction(func() error {
+			return x.applyMigration(migration)
+		})
+	}
+	if migration.LongRunning {
+		return x.inOwnTransaction(func() error {
+			return x.applyMigration(migration)
+		})
+	}
+	return x.applyMigration(migration)
+}
 
-		if err := x.insertMigration(migration.ID); err != nil {
-			return err
+func (x *Xormigrate) applyMigration(migration *Migration) (err error) {
+	if x.options.BeforeMigration != nil {
+		x.options.BeforeMigration(migration)
+	}
+	start := time.Now()
+	if x.options.AfterMigration != nil {
+		defer func() { x.options.AfterMigration(migration, err, time.Since(start)) }()
+	}
+	x.lint(migration)
+	if err := x.migrateWithRetry(migration); err != nil {
+		x.emit(&MigrationFailed{ID: migration.ID, Err: err})
+		return migrationError(migration, PhaseMigrate, err)
+	}
+
+	duration := time.Since(start)
+	if err := x.insertMigration(migration, duration, ""); err != nil {
+		x.emit(&MigrationFailed{ID: migration.ID, Err: err})
+		return migrationError(migration, PhaseRecord, err)
+	}
+	if len(migration.Steps) > 0 {
+		if err := x.backend.deleteSteps(migration.ID); err != nil {
+			return migrationError(migration, PhaseRecord, err)
 		}
 	}
+	x.applied = append(x.applied, migration)
+	x.emit(&MigrationApplied{ID: migration.ID, Description: migration.Description, Duration: duration})
+	if migration.Budget > 0 && duration > migration.Budget {
+		x.emit(&BudgetExceeded{ID: migration.ID, Duration: duration, Budget: migration.Budget})
+	}
 	return nil
 }
 
+func (x *Xormigrate) dialectMatches(migration *Migration) bool {
+	dialects := migration.dialects()
+	if len(dialects) == 0 {
+		return true
+	}
+	dbType := x.backend.dialect()
+	for _, dialect := range dialects {
+		if strings.EqualFold(dialect, dbType) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipReason is the reason recorded for the migration if it is skipped,
+// because of Skip or its Dialects, empty otherwise.
+func (x *Xormigrate) skipReason(migration *Migration) string {
+	if migration.Skip != "" {
+		return migration.Skip
+	}
+	if !x.dialectMatches(migration) {
+		return fmt.Sprintf("dialect %s is not one of %s", x.backend.dialect(), strings.Join(migration.dialects(), ", "))
+	}
+	return ""
+}
+
 func (x *Xormigrate) createMigrationTableIfNotExists() error {
-	b, err := x.session.IsTableExist(x.options.TableName)
+	if x.options.AssumeTableExists {
+		return x.backend.probeTable()
+	}
+	b, err := x.backend.tableExists()
 	if err != nil {
 		return err
 	}
 	if b {
-		return nil
+		err = x.backend.upgradeTable()
+	} else {
+		err = x.createTableRacing()
 	}
-	return x.session.Table(x.options.TableName).Sync2(&Migration{})
+	if err != nil || !x.hasSteps() {
+		return err
+	}
+	return x.createStepTableRacing()
 }
 
+// migrationRan reports whether m was recorded. The IDs of the records are
+// loaded with a single query the first time, rather than one per migration.
 func (x *Xormigrate) migrationRan(m *Migration) (bool, error) {
-	count, err := x.session.
-		Table(x.options.TableName).
-		ID(m.ID).
-		Count(&Migration{})
-	return count > 0, err
+	if x.ran == nil {
+		ids, err := x.backend.recordIDs()
+		if err != nil {
+			return false, err
+		}
+		x.ran = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			x.ran[id] = true
+		}
+	}
+	return x.ran[m.ID], nil
+}
+
+// forgetRan drops the IDs cached by migrationRan, e.g. when the records may
+// have been changed by a rollback.
+func (x *Xormigrate) forgetRan() {
+	x.ran = nil
 }
 
 // The schema can be initialized only if it hasn't been initialized yet
@@ -372,42 +1292,259 @@ func (x *Xormigrate) canInitializeSchema() (bool, error) {
 	}
 
 	// If the ID doesn't exist, we also want the list of migrations to be empty
-	count, err := x.session.
-		Table(x.options.TableName).
-		Count(&Migration{})
+	count, err := x.backend.countRecords(nil)
 	return count == 0, err
 }
 
-func (x *Xormigrate) unknownMigrationsHaveHappened() (bool, error) {
-	m := make([]string, 0, len(x.migrations)+1) //append(x.migrations, &Migration{ID: initSchemaMigrationID})
-	m = append(m, initSchemaMigrationID)
+// unknownMigrations returns the sorted IDs of the records whose migration is
+// not defined.
+func (x *Xormigrate) unknownMigrations() ([]string, error) {
+	ids, err := x.backend.recordIDs()
+	if err != nil {
+		return nil, err
+	}
+	defined := make(map[string]bool, len(x.migrations)+1)
+	defined[initSchemaMigrationID] = true
 	for _, migration := range x.migrations {
-		m = append(m, migration.ID)
+		defined[migration.ID] = true
 	}
-	count, err := x.session.Table(x.options.TableName).NotIn("id", m).Count(&Migration{})
-	return count > 0, err
+	var unknown []string
+	for _, id := range ids {
+		if !defined[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown, nil
 }
 
-func (x *Xormigrate) insertMigration(id string) error {
-	_, err := x.session.Table(x.options.TableName).Insert(&Migration{ID: id})
-	return err
+// skipMigration records that the migration was skipped for the reason.
+func (x *Xormigrate) skipMigration(m *Migration, reason string) error {
+	if err := x.insertMigration(m, 0, reason); err != nil {
+		return err
+	}
+	x.emit(&MigrationSkipped{ID: m.ID, Reason: reason})
+	return nil
+}
+
+// insertMigration records the migration, as skipped if skipReason is set.
+func (x *Xormigrate) insertMigration(m *Migration, duration time.Duration, skipReason string) error {
+	record, err := x.newRecord(m, duration, skipReason)
+	if err != nil {
+		return err
+	}
+	if err := x.backend.insertRecord(record); err != nil {
+		return err
+	}
+	if x.ran != nil {
+		x.ran[m.ID] = true
+	}
+	return nil
+}
+
+// insertMigrations records the migrations at once, with as few statements as
+// possible, as skipped if skipReason is set.
+func (x *Xormigrate) insertMigrations(migrations []*Migration, skipReason string) error {
+	records := make([]migrationRecord, len(migrations))
+	for i, migration := range migrations {
+		record, err := x.newRecord(migration, 0, skipReason)
+		if err != nil {
+			return err
+		}
+		records[i] = *record
+	}
+	if err := x.backend.insertRecords(records); err != nil {
+		return err
+	}
+	if x.ran != nil {
+		for _, migration := range migrations {
+			x.ran[migration.ID] = true
+		}
+	}
+	return nil
+}
+
+// newRecord returns the record of the migration, see insertMigration.
+func (x *Xormigrate) newRecord(m *Migration, duration time.Duration, skipReason string) (*migrationRecord, error) {
+	record := &migrationRecord{ID: m.ID}
+	if x.options.RecordBuildVersion {
+		record.BuildVersion = BuildVersion()
+	}
+	if x.options.RecordHost {
+		host, err := x.options.HostResolver()
+		if err != nil {
+			return nil, err
+		}
+		record.Host = host
+	}
+	if x.options.ApprovalVerifier != nil {
+		record.Approval = x.approvals[m.ID]
+	}
+	if x.options.RecordChecksum || x.hasRepeatables() {
+		record.Checksum = m.checksum()
+	}
+	if x.hasBudgets() {
+		record.OverBudget = m.Budget > 0 && duration > m.Budget
+	}
+	if x.options.RecordRunID {
+		record.RunID = x.runID
+	}
+	if x.options.RecordAppliedAt {
+		now := x.options.Clock.Now()
+		record.AppliedAt = &now
+	}
+	if x.options.RecordDuration {
+		record.DurationMS = duration.Milliseconds()
+	}
+	if x.options.RecordTouches {
+		record.Touches = strings.Join(m.touches(), ",")
+	}
+	if x.options.AllowOutOfOrder {
+		record.OutOfOrder = x.outOfOrder[m.ID]
+	}
+	if x.options.RecordStatus {
+		record.Status, record.SkipReason = statusApplied, skipReason
+		if skipReason != "" {
+			record.Status = statusSkipped
+		}
+	}
+	return record, nil
+}
+
+// openSession opens the session of an operation when x was created with
+// NewFromEngine, and returns the function closing it. The records loaded by
+// the operation are forgotten either way, as other runs may change them.
+func (x *Xormigrate) openSession() (release func()) {
+	if x.session != nil || x.engine == nil {
+		return x.forgetRan
+	}
+	x.session = x.engine.NewSession()
+	return func() {
+		x.session.Close()
+		x.session = nil
+		x.forgetRan()
+	}
 }
 
-func (x *Xormigrate) begin() {
+// begin starts a run, holding the migration lock if Options.UseLock is set,
+// in a transaction if Options.UseTransaction is set, and executes
+// Options.PreRunSQL.
+func (x *Xormigrate) begin() error {
+	sessionRuns.Store(x.session, x)
+	x.bindSession(x.session)
+	if err := x.lock(); err != nil {
+		return err
+	}
+	// The records may have changed while waiting for the lock.
+	x.forgetRan()
 	if x.options.UseTransaction {
-		x.session.Begin()
+		x.backend.begin()
+		x.txStart, x.txWarned = time.Now(), false
 	}
+	return x.execSQL(x.options.PreRunSQL)
+}
+
+// finish ends a successful run, executing Options.PostRunSQL before
+// committing.
+func (x *Xormigrate) finish() error {
+	if err := x.execSQL(x.options.PostRunSQL); err != nil {
+		return err
+	}
+	return x.commit()
 }
 
 func (x *Xormigrate) commit() error {
+	if x.rehearsing {
+		// The run transaction is rolled back by end.
+		return nil
+	}
 	if x.options.UseTransaction {
-		return x.session.Commit()
+		return x.backend.commit()
+	}
+	return nil
+}
+
+// execSQL executes statements on the run session.
+func (x *Xormigrate) execSQL(statements []string) error {
+	for _, statement := range statements {
+		if err := x.backend.exec(statement); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (x *Xormigrate) rollback() {
+// withoutTransaction commits the run transaction, if any, and calls fn with
+// x.session set to a new session, before starting a new transaction.
+// A new session is needed as xorm keeps running some queries against the
+// transaction of a session once it is committed. Options.PreRunSQL and
+// PostRunSQL are executed on the new session too.
+func (x *Xormigrate) withoutTransaction(fn func() error) error {
+	if !x.options.UseTransaction {
+		return fn()
+	}
+	if err := x.commit(); err != nil {
+		return err
+	}
+
+	if x.session == nil {
+		err := fn()
+		x.backend.begin()
+		return err
+	}
+	session := x.session
+	x.session = session.Engine().NewSession()
+	x.detached = true
+	sessionRuns.Store(x.session, x)
+	x.bindSession(x.session)
+	err := x.execSQL(x.options.PreRunSQL)
+	if err == nil {
+		err = fn()
+	}
+	if err == nil {
+		err = x.execSQL(x.options.PostRunSQL)
+	}
+	x.unbindSession(x.session)
+	sessionRuns.Delete(x.session)
+	x.session.Close()
+	x.session = session
+	x.detached = false
+
+	x.backend.begin()
+	x.txStart, x.txWarned = time.Now(), false
+	return err
+}
+
+// inOwnTransaction calls fn like withoutTransaction, but in a transaction of
+// its own, committed if fn succeeds.
+func (x *Xormigrate) inOwnTransaction(fn func() error) error {
+	if !x.options.UseTransaction {
+		return fn()
+	}
+	return x.withoutTransaction(func() error {
+		x.backend.begin()
+		detached := x.detached
+		x.detached = false
+		err := fn()
+		x.detached = detached
+		if err != nil {
+			x.backend.rollback()
+			x.forgetRan()
+			return err
+		}
+		return x.backend.commit()
+	})
+}
+
+// end ends a run, rolling back its transaction unless it was committed.
+func (x *Xormigrate) end() {
 	if x.options.UseTransaction {
-		x.session.Rollback()
+		x.backend.rollback()
 	}
+	x.forgetRan()
+	x.outOfOrder = nil
+	x.secrets = nil
+	x.unlock()
+	x.unbindSession(x.session)
+	sessionRuns.Delete(x.session)
 }
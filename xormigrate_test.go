@@ -1,6 +1,7 @@
 package xormigrate
 
 import (
+	"errors"
 	"os"
 	"testing"
 
@@ -392,3 +393,209 @@ func forEachDatabase(t *testing.T, fn func(database *xorm.Engine)) {
 		fn(db)
 	}
 }
+
+func TestAssumeTableExists(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := New(db.NewSession(), &Options{
+			TableName:         "migration",
+			AssumeTableExists: true,
+		}, migrations)
+
+		err := m.Migrate()
+		var missingTableError *MissingTableError
+		assert.True(t, errors.As(err, &missingTableError))
+		has, _ := db.IsTableExist(&Person{})
+		assert.False(t, has)
+
+		assert.NoError(t, db.Table("migration").Sync2(&Migration{}))
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, int64(2), tableCount(t, db))
+	})
+}
+
+func TestPriority(t *testing.T) {
+	var ran []string
+	stub := func(id string, priority int, dependsOn ...string) *Migration {
+		return &Migration{
+			ID:        id,
+			Priority:  priority,
+			DependsOn: dependsOn,
+			Migrate: func(tx *xorm.Session) error {
+				ran = append(ran, id)
+				return nil
+			},
+		}
+	}
+	m := NewFake(&FakeBackend{}, &Options{}, []*Migration{
+		stub("1", 0),
+		stub("2", 0),
+		stub("3", 10),
+		stub("4", 5, "2"),
+		stub("5", 20),
+	})
+	assert.NoError(t, m.MigrateTo("4"))
+	assert.Equal(t, []string{"3", "1", "2", "4"}, ran)
+
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, []string{"3", "1", "2", "4", "5"}, ran)
+}
+
+func TestPrePostRunSQL(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		assert.NoError(t, db.Sync2(&Book{}))
+		m := New(db.NewSession(), &Options{
+			TableName:      "migration",
+			UseTransaction: true,
+			PreRunSQL:      []string{"INSERT INTO book (name) VALUES ('pre')"},
+			PostRunSQL:     []string{"INSERT INTO book (name) VALUES ('post')"},
+		}, migrations)
+		assert.NoError(t, m.Migrate())
+		assert.NoError(t, m.RollbackLast())
+
+		var names []string
+		assert.NoError(t, db.Table("book").Cols("name").Find(&names))
+		assert.Equal(t, []string{"pre", "post", "pre", "post"}, names)
+	})
+}
+
+func TestNewFromEngine(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		m := NewFromEngine(db, &Options{
+			TableName:      "migration",
+			UseTransaction: true,
+		}, migrations)
+		assert.NoError(t, m.Migrate())
+		assert.Nil(t, m.session)
+		has, err := db.IsTableExist(&Person{})
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.Equal(t, int64(2), tableCount(t, db))
+
+		pending, err := m.Pending()
+		assert.NoError(t, err)
+		assert.Empty(t, pending)
+
+		assert.NoError(t, m.RollbackLast())
+		assert.Nil(t, m.session)
+		assert.Equal(t, int64(1), tableCount(t, db))
+	})
+}
+
+func TestRollbackOnly(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		// Created by an older tool.
+		assert.NoError(t, db.Sync2(&Person{}))
+		_, err := db.Insert(&Person{ID: 1, Name: "legacy"})
+		assert.NoError(t, err)
+
+		m := New(db.NewSession(), &Options{TableName: "migration"}, []*Migration{{
+			ID:           "201608301400",
+			Description:  "Adopt persons",
+			RollbackOnly: true,
+			UpSQL:        "DROP TABLE person;",
+			DownSQL:      "DROP TABLE person;",
+		}})
+		assert.NoError(t, m.Migrate())
+		assert.Equal(t, int64(1), tableCount(t, db))
+		count, err := db.Count(&Person{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+
+		assert.NoError(t, m.RollbackLast())
+		has, err := db.IsTableExist("person")
+		assert.NoError(t, err)
+		assert.False(t, has)
+	})
+}
+
+// listingBackend counts the queries listing the IDs of the records.
+type listingBackend struct {
+	backend
+	lists int
+}
+
+func (b *listingBackend) recordIDs() ([]string, error) {
+	b.lists++
+	return b.backend.recordIDs()
+}
+
+func TestMigrationRanLoadsIDsOnce(t *testing.T) {
+	backend := &listingBackend{backend: &FakeBackend{}}
+	m := NewFake(nil, &Options{}, targetMigrations())
+	m.backend = backend
+
+	assert.NoError(t, m.MigrateTo("201609011200"))
+	assert.Equal(t, 1, backend.lists)
+
+	pending, err := m.Pending()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"201609021200"}, planIDs(pending))
+	assert.Equal(t, 2, backend.lists)
+
+	assert.NoError(t, m.RollbackLast())
+	assert.NoError(t, m.Migrate())
+	applied, err := m.AppliedIDs()
+	assert.NoError(t, err)
+	assert.Len(t, applied, 4)
+	assert.Equal(t, 4, backend.lists)
+}
+
+func TestMigrationRanAfterOtherRun(t *testing.T) {
+	forEachDatabase(t, func(db *xorm.Engine) {
+		options := &Options{TableName: "migration"}
+		first := New(db.NewSession(), options, migrations)
+		second := New(db.NewSession(), options, migrations)
+
+		assert.NoError(t, first.MigrateTo("201608301400"))
+		migrationRan, err := first.MigrationRan("201608301430")
+		assert.NoError(t, err)
+		assert.False(t, migrationRan)
+
+		assert.NoError(t, second.Migrate())
+		migrationRan, err = first.MigrationRan("201608301430")
+		assert.NoError(t, err)
+		assert.True(t, migrationRan)
+		pending, err := first.Pending()
+		assert.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+}
+
+func TestMigrationError(t *testing.T) {
+	errFailed := errors.New("failed")
+	backend := &FakeBackend{}
+	m := NewFake(backend, &Options{}, []*Migration{{
+		ID:          "201608301400",
+		Description: "Create persons",
+		Migrate:     func(tx *xorm.Session) error { return nil },
+		Rollback:    func(tx *xorm.Session) error { return errFailed },
+	}, {
+		ID:      "201608301430",
+		Migrate: func(tx *xorm.Session) error { return errFailed },
+	}})
+
+	err := m.Migrate()
+	var migrationErr *MigrationError
+	if assert.True(t, errors.As(err, &migrationErr)) {
+		assert.Equal(t, &MigrationError{ID: "201608301430", Phase: PhaseMigrate, Err: errFailed}, migrationErr)
+	}
+	assert.ErrorIs(t, err, errFailed)
+
+	err = m.RollbackLast()
+	if assert.True(t, errors.As(err, &migrationErr)) {
+		assert.Equal(t, PhaseRollback, migrationErr.Phase)
+	}
+	assert.EqualError(t, err, "xormigrate: Migration 201608301400 - Create persons failed during rollback: failed")
+
+	backend.Fail = func(op string) error {
+		if op == "insert 201608301430" {
+			return errors.New("disk full")
+		}
+		return nil
+	}
+	m.migrations[1].Migrate = func(tx *xorm.Session) error { return nil }
+	err = m.Migrate()
+	if assert.True(t, errors.As(err, &migrationErr)) {
+		assert.Equal(t, PhaseRecord, migrationErr.Phase)
+	}
+}